@@ -92,6 +92,7 @@ Compile all warnings into a single message, and call fail.
 {{- define "contour.validateValues" -}}
 {{- $messages := list -}}
 {{- $messages := append $messages (include "contour.validateValues.envoy.kind" .) -}}
+{{- $messages := append $messages (include "contour.validateValues.gatewayAPI.channel" .) -}}
 {{- $messages := without $messages "" -}}
 {{- $message := join "\n" $messages -}}
 
@@ -109,6 +110,27 @@ contour: envoy.kind
 {{- end -}}
 {{- end -}}
 
+{{/* Validate values of Contour - must provide a valid Gateway API channel */}}
+{{- define "contour.validateValues.gatewayAPI.channel" -}}
+{{- if and .Values.gatewayAPI.manageCRDs (ne .Values.gatewayAPI.channel "standard") (ne .Values.gatewayAPI.channel "experimental") -}}
+contour: gatewayAPI.channel
+    Invalid gatewayAPI.channel selected. Valid values are "standard" and
+    "experimental". Please set a valid channel (--set gatewayAPI.channel="xxxx")
+{{- end -}}
+{{- end -}}
+
+{{/* Labels of the CRDs managed by the chart, added to the upstream ones by synchronize-crds. */}}
+{{- define "contour.crds.labels" -}}
+{{- include "common.labels.standard" ( dict "customLabels" .Values.commonLabels "context" . ) }}
+{{- end -}}
+
+{{/* Annotations of the CRDs managed by the chart, added to the upstream ones by synchronize-crds. */}}
+{{- define "contour.crds.annotations" -}}
+{{- if .Values.commonAnnotations }}
+{{- include "common.tplvalues.render" ( dict "value" .Values.commonAnnotations "context" . ) }}
+{{- end }}
+{{- end -}}
+
 {{/* Create the name of the IngressClass to use. */}}
 {{- define "contour.ingressClassName" -}}
 {{- $ingressClass := .Values.contour.ingressClass }}
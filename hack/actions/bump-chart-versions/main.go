@@ -13,235 +13,130 @@
 
 //go:build none
 
-// This script determines the latest stable Contour version from the Contour repository
-// it then bumps versions accordingly:
+// This script bumps every chart under charts/ that has an upstream.yaml config file to the latest stable Contour
+// release in versions.yaml: Chart.yaml appVersion, version and kubeVersion, the values.yaml Contour and Envoy image
+// tags, the Artifact Hub annotations and CHANGELOG.md. It never downgrades a chart, and fails before changing any
+// file if a new image tag is not published for all of -platforms.
 //
-// - charts/contour/Chart.yaml appVersion to the latest stable Contour version.
-// - charts/contour/values.yaml Contour and Envoy image tags to match the latest stable versions.
-// - charts/contour/Chart.yaml minor version is incremented by one.
-//
-// If the current chart appVersion is already the latest stable Contour version, no changes are made.
+// - -policy semver (the default) bumps the chart like the Contour patch or minor release, -policy minor always bumps its minor.
+// - -contour-minor only considers patch releases of a Contour minor version, for maintenance release lines.
+// - -contour-version bumps to an exact Contour version, and -channel rc considers pre-releases for the latest one.
+// - -pin-digests sets the image digests next to the tags in values.yaml.
+// - -report writes a JSON report of the bump, keyed by chart name, to a file or stdout.
+// - -backfill-changelog adds the missing CHANGELOG.md sections from git history instead of bumping versions.
 //
 // Usage:
 //
-//	go run hack/actions/bump-chart-versions/main.go
+//	go run hack/actions/bump-chart-versions/main.go [-charts DIR] [-only CHART,...] [-versions URL|FILE|-] [-policy semver|minor] [-contour-minor MAJOR.MINOR|current] [-contour-version VERSION] [-channel stable|rc] [-platforms OS/ARCH,...] [-pin-digests] [-security] [-report FILE|-] [-backfill-changelog]
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"net/http"
 	"os"
-	"strconv"
-	"strings"
-	"time"
 
+	"github.com/projectcontour/helm-charts/hack/actions/internal/bump"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/charts"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/fetch"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/registry"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
 )
 
-var (
-	log = logrus.StandardLogger()
-
-	contourVersionsURL = "https://raw.githubusercontent.com/projectcontour/contour/refs/heads/main/versions.yaml"
-
-	chartPath  = "./charts/contour/Chart.yaml"
-	valuesPath = "./charts/contour/values.yaml"
-)
+var log = logrus.StandardLogger()
 
 func main() {
 	log.SetFormatter(&logrus.TextFormatter{ForceColors: true})
 
-	// Define global HTTP client timeout.
-	http.DefaultClient.Timeout = 2 * time.Minute
-
-	// Read current chart and app versions.
-	currentChartVersion, currentChartAppVersion, err := getCurrentChartVersions(chartPath)
-	if err != nil {
-		log.Fatalf("Failed to get current chart versions: %v", err)
-	}
-	log.Infof("Current chart version: %s, appVersion: %s", currentChartVersion, currentChartAppVersion)
-
-	// Get latest stable Contour and Envoy versions from Github.
-	contourVersion, envoyVersion, err := getLatestStableVersions()
-	if err != nil {
-		log.Fatalf("Failed to get latest stable versions: %v", err)
-	}
-	log.Infof("Latest stable Contour: %s, Envoy: %s", contourVersion, envoyVersion)
-
-	// Compare if versions are the same.
-	if contourVersion == currentChartAppVersion {
-		log.Infof("Contour version %s is already up to date", contourVersion)
-		return
-	}
-
-	// Update Chart.yaml with new minor chart version and appVersion based on latest Contour version info.
-	nextChartVersion, err := nextMinorVersion(currentChartVersion)
+	versions := flag.String("versions", bump.DefaultVersionsURL, "Location of Contour versions.yaml: an http(s) URL, a local file, or - for stdin.")
+	chartsDir := flag.String("charts", charts.DefaultDir, "Directory holding the charts.")
+	only := flag.String("only", "", "Comma-separated names of the charts to bump, empty for all of them.")
+	policyName := flag.String("policy", string(bump.PolicySemver), "Chart version bump policy: semver or minor.")
+	contourMinor := flag.String("contour-minor", "", "Only bump to patch releases of this Contour minor version, such as 1.32, or \"current\".")
+	contourVersion := flag.String("contour-version", "", "Bump to this exact Contour version, such as 1.34.0-rc.1, instead of the latest one.")
+	channelName := flag.String("channel", string(bump.ChannelStable), "Release channel of the latest Contour version: stable or rc.")
+	platformList := flag.String("platforms", bump.DefaultPlatforms, "Comma-separated platforms the new image tags must be published for, empty to skip the check.")
+	pinDigests := flag.Bool("pin-digests", false, "Resolve the new image tags to manifest digests and write them to values.yaml.")
+	registryPlainHTTP := flag.Bool("registry-plain-http", false, "Use plain HTTP to talk to image registries.")
+	security := flag.Bool("security", false, "Mark the Contour and Envoy upgrades as security fixes in the Artifact Hub changes annotation.")
+	reportPath := flag.String("report", "", "Write a JSON report of the bump to this file, or - for stdout.")
+	backfillChangelog := flag.Bool("backfill-changelog", false, "Add missing changelog sections from git history instead of bumping versions.")
+	flag.Parse()
+
+	policy, err := bump.ParsePolicy(*policyName)
 	if err != nil {
-		log.Fatalf("Failed to get next minor version: %v", err)
+		log.Fatalf("Invalid flags: %v", err)
 	}
-	err = setYAMLField(chartPath, "version", nextChartVersion)
+	channel, err := bump.ParseChannel(*channelName)
 	if err != nil {
-		log.Fatalf("Failed to update Contour chart version: %v", err)
+		log.Fatalf("Invalid flags: %v", err)
 	}
-	log.Infof("Updated Contour chart version to %s in %s", nextChartVersion, chartPath)
-
-	err = setYAMLField(chartPath, "appVersion", contourVersion)
+	platforms, err := registry.ParsePlatforms(*platformList)
 	if err != nil {
-		log.Fatalf("Failed to update Contour chart appVersion: %v", err)
+		log.Fatalf("Invalid flags: %v", err)
 	}
-	log.Infof("Updated Contour chart appVersion to %s in %s", contourVersion, chartPath)
-
-	// Update values.yaml with new Contour and Envoy versions.
-	contourImageTag := fmt.Sprintf("v%s", contourVersion)
-	err = setYAMLField(valuesPath, "contour.image.tag", contourImageTag)
+	discovered, err := charts.Discover(*chartsDir, charts.ParseNames(*only)...)
 	if err != nil {
-		log.Fatalf("Failed to update Contour version: %v", err)
-	}
-	log.Infof("Updated Contour image tag to %s in %s", contourImageTag, valuesPath)
-
-	envoyImageTag := fmt.Sprintf("v%s", envoyVersion)
-	err = setYAMLField(valuesPath, "envoy.image.tag", envoyImageTag)
-	if err != nil {
-		log.Fatalf("Failed to update Envoy version: %v", err)
-	}
-	log.Infof("Updated Envoy image tag to %s in %s", envoyImageTag, valuesPath)
-
-	log.Infof("Successfully bumped versions.")
-}
-
-func getLatestStableVersions() (string, string, error) {
-	resp, err := http.Get(contourVersionsURL) //nolint:gosec // G107: URL is constructed from a hardcoded constant
-	if err != nil {
-		return "", "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("failed to fetch versions.yaml: status code %d", resp.StatusCode)
-	}
-
-	type VersionEntry struct {
-		Version      string `yaml:"version"`
-		Supported    string `yaml:"supported"`
-		Dependencies struct {
-			Envoy string `yaml:"envoy"`
-		} `yaml:"dependencies"`
-	}
-	type VersionsYaml struct {
-		Versions []VersionEntry `yaml:"versions"`
-	}
-
-	var versions VersionsYaml
-	if err := yaml.NewDecoder(resp.Body).Decode(&versions); err != nil {
-		return "", "", err
-	}
-
-	for _, entry := range versions.Versions {
-		// Skip "main" version.
-		if entry.Version == "main" {
-			continue
+		log.Fatalf("Failed to discover charts: %v", err)
+	}
+
+	fetch.ConfigureDefaultClient()
+
+	opts := bump.Options{
+		Source:         bump.NewVersionSource(*versions),
+		ContourMinor:   *contourMinor,
+		ContourVersion: *contourVersion,
+		Channel:        channel,
+		Policy:         policy,
+		PinDigests:     *pinDigests,
+		Platforms:      platforms,
+		Registry:       &registry.Client{PlainHTTP: *registryPlainHTTP},
+		Security:       *security,
+	}
+
+	if *backfillChangelog {
+		for _, c := range discovered {
+			if c.ChangelogPath() == "" {
+				log.Infof("Skipping chart %s without changelog", c.Name)
+				continue
+			}
+			if err := bump.BackfillChangelog(context.Background(), c.Bump(opts)); err != nil {
+				log.Fatalf("Failed to backfill %s chart changelog: %v", c.Name, err)
+			}
 		}
-
-		// Pick the first supported version as the latest stable.
-		// Note: assumes versions.yaml is ordered from latest to oldest!
-		if entry.Supported == "true" {
-			contourVersion := strings.TrimPrefix(entry.Version, "v")
-			return contourVersion, entry.Dependencies.Envoy, nil
-		}
-	}
-
-	return "", "", fmt.Errorf("no supported versions found")
-}
-
-// setYAMLField updates a specific field in a YAML file.
-func setYAMLField(filePath, fieldPath, newValue string) error {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to read file %s: %w", filePath, err)
-	}
-
-	var root yaml.Node
-	if err := yaml.Unmarshal(data, &root); err != nil {
-		return fmt.Errorf("failed to unmarshal yaml from %s: %w", filePath, err)
+		return
 	}
 
-	node := &root
-	if node.Kind == yaml.DocumentNode {
-		if len(node.Content) == 0 {
-			return fmt.Errorf("empty document")
+	reports := make(bump.Reports, len(discovered))
+	for _, c := range discovered {
+		log.Infof("Bumping chart %s", c.Name)
+		report, err := bump.Run(context.Background(), c.Bump(opts))
+		if err != nil {
+			log.Fatalf("Failed to bump %s chart versions: %v", c.Name, err)
 		}
-		node = node.Content[0]
-	}
-
-	parts := strings.Split(fieldPath, ".")
-	if err := updateNode(node, parts, newValue); err != nil {
-		return fmt.Errorf("failed to update field %s in %s: %w", fieldPath, filePath, err)
-	}
-
-	f, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filePath, err)
-	}
-	defer f.Close()
-
-	encoder := yaml.NewEncoder(f)
-	encoder.SetIndent(2)
-	if err := encoder.Encode(&root); err != nil {
-		return fmt.Errorf("failed to encode yaml to %s: %w", filePath, err)
+		reports[c.Name] = report
 	}
 
-	return nil
-}
-
-// updateNode recursively updates the YAML node at the specified path.
-func updateNode(node *yaml.Node, path []string, newValue string) error {
-	if len(path) == 0 {
-		node.Value = newValue
-		return nil
-	}
-
-	if node.Kind != yaml.MappingNode {
-		return fmt.Errorf("expected mapping node")
-	}
-
-	for i := 0; i < len(node.Content); i += 2 {
-		if node.Content[i].Value == path[0] {
-			return updateNode(node.Content[i+1], path[1:], newValue)
+	if *reportPath != "" {
+		if err := writeReports(reports, *reportPath); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
 		}
 	}
 
-	return fmt.Errorf("field %s not found", path[0])
+	log.Infof("Successfully bumped versions.")
 }
 
-// getCurrentChartVersions reads the current chart and app version.
-func getCurrentChartVersions(filePath string) (string, string, error) {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to read file %s: %w", filePath, err)
+// writeReports writes the reports to the file at path, or to stdout if path is "-".
+func writeReports(reports bump.Reports, path string) error {
+	if path == "-" {
+		return reports.WriteJSON(os.Stdout)
 	}
 
-	type ChartYaml struct {
-		Version    string `yaml:"version"`
-		AppVersion string `yaml:"appVersion"`
-	}
-
-	var chart ChartYaml
-	if err := yaml.Unmarshal(data, &chart); err != nil {
-		return "", "", fmt.Errorf("failed to unmarshal yaml from %s: %w", filePath, err)
-	}
-
-	return chart.Version, chart.AppVersion, nil
-}
-
-// nextMinorVersion calculates the next minor version given a version string.
-func nextMinorVersion(version string) (string, error) {
-	parts := strings.Split(version, ".")
-	if len(parts) != 3 {
-		return "", fmt.Errorf("invalid version format: %s", version)
-	}
-	minor, err := strconv.Atoi(parts[1])
+	f, err := os.Create(path)
 	if err != nil {
-		return "", fmt.Errorf("invalid minor version: %s", parts[1])
+		return fmt.Errorf("failed to create file %s: %w", path, err)
 	}
-	return fmt.Sprintf("%s.%d.0", parts[0], minor+1), nil
+	defer f.Close()
+
+	return reports.WriteJSON(f)
 }
@@ -0,0 +1,215 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package artifacthub maintains the Artifact Hub annotations of a chart's Chart.yaml.
+// See https://artifacthub.io/docs/topics/annotations/helm/ for the format of each annotation.
+package artifacthub
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/yamledit"
+	"gopkg.in/yaml.v3"
+)
+
+// Annotations maintained in Chart.yaml.
+const (
+	ImagesAnnotation  = "artifacthub.io/images"
+	ChangesAnnotation = "artifacthub.io/changes"
+	CRDsAnnotation    = "artifacthub.io/crds"
+)
+
+// Kinds of changes in the artifacthub.io/changes annotation.
+const (
+	KindChanged  = "changed"
+	KindSecurity = "security"
+)
+
+// Image is an entry of the artifacthub.io/images annotation.
+type Image struct {
+	Name  string `yaml:"name"`
+	Image string `yaml:"image"`
+}
+
+// Change is an entry of the artifacthub.io/changes annotation.
+type Change struct {
+	Kind        string `yaml:"kind"`
+	Description string `yaml:"description"`
+	Links       []Link `yaml:"links,omitempty"`
+}
+
+// Link is a link of a Change.
+type Link struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+// CRD is an entry of the artifacthub.io/crds annotation.
+type CRD struct {
+	Kind        string `yaml:"kind"`
+	Version     string `yaml:"version"`
+	Name        string `yaml:"name"`
+	DisplayName string `yaml:"displayName"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// SetAnnotation encodes value as YAML and sets it as the annotation key in the Chart.yaml at chartPath.
+// It returns false without touching the file if the annotation already has that value.
+func SetAnnotation(chartPath, key string, value any) (bool, error) {
+	data, err := os.ReadFile(chartPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file %s: %w", chartPath, err)
+	}
+
+	updated, err := SetAnnotationData(data, key, value)
+	if err != nil {
+		return false, fmt.Errorf("failed to update %s: %w", chartPath, err)
+	}
+	if bytes.Equal(data, updated) {
+		return false, nil
+	}
+
+	if err := os.WriteFile(chartPath, updated, 0o644); err != nil { //nolint:gosec // G306: chart files are intentionally world-readable
+		return false, fmt.Errorf("failed to write file %s: %w", chartPath, err)
+	}
+
+	return true, nil
+}
+
+// SetAnnotationData is like SetAnnotation, but returns a copy of the Chart.yaml data with the annotation set.
+// The data is returned unchanged if the annotation already has that value.
+func SetAnnotationData(data []byte, key string, value any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(value); err != nil {
+		return nil, fmt.Errorf("failed to encode annotation %s: %w", key, err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode annotation %s: %w", key, err)
+	}
+
+	var chart struct {
+		Annotations map[string]string `yaml:"annotations"`
+	}
+	if err := yaml.Unmarshal(data, &chart); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal yaml: %w", err)
+	}
+	if chart.Annotations[key] == buf.String() {
+		return data, nil
+	}
+
+	return yamledit.SetOrInsertPath(data, []string{"annotations", key}, buf.String())
+}
+
+// crdDocument is the part of a CustomResourceDefinition the artifacthub.io/crds annotation is built from.
+type crdDocument struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Names struct {
+			Kind string `yaml:"kind"`
+		} `yaml:"names"`
+		Versions []struct {
+			Name    string `yaml:"name"`
+			Storage bool   `yaml:"storage"`
+			Schema  struct {
+				OpenAPIV3Schema struct {
+					Description string `yaml:"description"`
+				} `yaml:"openAPIV3Schema"`
+			} `yaml:"schema"`
+		} `yaml:"versions"`
+	} `yaml:"spec"`
+}
+
+// ReadCRDs returns the artifacthub.io/crds entries for the CustomResourceDefinitions in the given chart
+// templates, in file order. Template directives on their own lines, such as the conditionals wrapping
+// the CRD files, are ignored.
+func ReadCRDs(paths ...string) ([]CRD, error) {
+	var crds []CRD
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", p, err)
+		}
+
+		parsed, err := ParseCRDs(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", p, err)
+		}
+		crds = append(crds, parsed...)
+	}
+
+	return crds, nil
+}
+
+// ParseCRDs is like ReadCRDs, but parses the content of a single chart template.
+func ParseCRDs(data []byte) ([]CRD, error) {
+	var crds []CRD
+	dec := yaml.NewDecoder(bytes.NewReader(StripTemplateLines(data)))
+	for {
+		var doc crdDocument
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if doc.Kind != "CustomResourceDefinition" || len(doc.Spec.Versions) == 0 {
+			continue
+		}
+
+		// Describe the version objects are stored as, which is the one users are expected to write.
+		version := doc.Spec.Versions[0]
+		for _, v := range doc.Spec.Versions {
+			if v.Storage {
+				version = v
+			}
+		}
+
+		// Descriptions are hard-wrapped, so use the first paragraph joined into a single line.
+		paragraph, _, _ := strings.Cut(strings.TrimSpace(version.Schema.OpenAPIV3Schema.Description), "\n\n")
+		description := strings.Join(strings.Fields(paragraph), " ")
+		crds = append(crds, CRD{
+			Kind:        doc.Spec.Names.Kind,
+			Version:     version.Name,
+			Name:        doc.Metadata.Name,
+			DisplayName: doc.Spec.Names.Kind,
+			Description: description,
+		})
+	}
+
+	return crds, nil
+}
+
+// StripTemplateLines removes lines that only hold a Helm template directive, such as {{- if ... }}.
+func StripTemplateLines(data []byte) []byte {
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	out := make([]byte, 0, len(data))
+	for _, line := range lines {
+		trimmed := bytes.TrimSpace(line)
+		if bytes.HasPrefix(trimmed, []byte("{{")) && bytes.HasSuffix(trimmed, []byte("}}")) {
+			continue
+		}
+		out = append(out, line...)
+	}
+	return out
+}
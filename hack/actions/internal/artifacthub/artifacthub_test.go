@@ -0,0 +1,152 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifacthub
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAnnotation(t *testing.T) {
+	chartPath := filepath.Join(t.TempDir(), "Chart.yaml")
+	require.NoError(t, os.WriteFile(chartPath, []byte("annotations:\n  category: Infrastructure\n  licenses: Apache-2.0\napiVersion: v2\n"), 0o600))
+
+	images := []Image{
+		{Name: "contour", Image: "ghcr.io/projectcontour/contour:v1.33.10"},
+		{Name: "envoy", Image: "docker.io/envoyproxy/envoy:v1.35.9@sha256:0123"},
+	}
+	changed, err := SetAnnotation(chartPath, ImagesAnnotation, images)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	changes := []Change{{
+		Kind:        KindSecurity,
+		Description: "Envoy upgraded from 1.35.8 to 1.35.9",
+		Links:       []Link{{Name: "Envoy 1.35.9 release notes", URL: "https://github.com/envoyproxy/envoy/releases/tag/v1.35.9"}},
+	}}
+	changed, err = SetAnnotation(chartPath, ChangesAnnotation, changes)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	want := "annotations:\n" +
+		"  artifacthub.io/changes: |\n" +
+		"    - kind: security\n" +
+		"      description: Envoy upgraded from 1.35.8 to 1.35.9\n" +
+		"      links:\n" +
+		"        - name: Envoy 1.35.9 release notes\n" +
+		"          url: https://github.com/envoyproxy/envoy/releases/tag/v1.35.9\n" +
+		"  artifacthub.io/images: |\n" +
+		"    - name: contour\n" +
+		"      image: ghcr.io/projectcontour/contour:v1.33.10\n" +
+		"    - name: envoy\n" +
+		"      image: docker.io/envoyproxy/envoy:v1.35.9@sha256:0123\n" +
+		"  category: Infrastructure\n" +
+		"  licenses: Apache-2.0\n" +
+		"apiVersion: v2\n"
+	data, err := os.ReadFile(chartPath)
+	require.NoError(t, err)
+	assert.Equal(t, want, string(data))
+
+	// Setting the same value again leaves the file as is.
+	changed, err = SetAnnotation(chartPath, ImagesAnnotation, images)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// A new value replaces the existing block.
+	changed, err = SetAnnotation(chartPath, ImagesAnnotation, images[:1])
+	require.NoError(t, err)
+	assert.True(t, changed)
+	data, err = os.ReadFile(chartPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "  artifacthub.io/images: |\n"+
+		"    - name: contour\n"+
+		"      image: ghcr.io/projectcontour/contour:v1.33.10\n"+
+		"  category: Infrastructure\n")
+}
+
+func TestReadCRDs(t *testing.T) {
+	crdPath := filepath.Join(t.TempDir(), "crds.yaml")
+	require.NoError(t, os.WriteFile(crdPath, []byte(`# Conditional: .Values.contour.manageCRDs
+{{- if .Values.contour.manageCRDs }}
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: httpproxies.projectcontour.io
+spec:
+  names:
+    kind: HTTPProxy
+  versions:
+  - name: v1beta1
+    storage: false
+  - name: v1
+    storage: true
+    schema:
+      openAPIV3Schema:
+        description: |-
+          HTTPProxy is an Ingress CRD
+          specification.
+
+          More details.
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: not-a-crd
+{{- end }}
+`), 0o600))
+
+	crds, err := ReadCRDs(crdPath)
+	require.NoError(t, err)
+	assert.Equal(t, []CRD{{
+		Kind:        "HTTPProxy",
+		Version:     "v1",
+		Name:        "httpproxies.projectcontour.io",
+		DisplayName: "HTTPProxy",
+		Description: "HTTPProxy is an Ingress CRD specification.",
+	}}, crds)
+
+	_, err = ReadCRDs(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorContains(t, err, "failed to read file")
+}
+
+// TestReadCRDsChartFiles reads the CRDs the chart ships with.
+func TestReadCRDsChartFiles(t *testing.T) {
+	var paths []string
+	for _, pattern := range []string{"*/*.yaml", "*/*/*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join("../../../../charts/contour/templates/crds", pattern))
+		require.NoError(t, err)
+		paths = append(paths, matches...)
+	}
+	crds, err := ReadCRDs(paths...)
+	require.NoError(t, err)
+	assert.Len(t, crds, len(paths))
+	require.NotEmpty(t, crds)
+	assert.Contains(t, crds, CRD{
+		Kind:        "HTTPProxy",
+		Version:     "v1",
+		Name:        "httpproxies.projectcontour.io",
+		DisplayName: "HTTPProxy",
+		Description: "HTTPProxy is an Ingress CRD specification.",
+	})
+	for _, crd := range crds {
+		assert.NotEmpty(t, crd.Kind)
+		assert.NotEmpty(t, crd.Version)
+		assert.NotEmpty(t, crd.Name)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"fmt"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/artifacthub"
+)
+
+// artifactHubImage returns the artifacthub.io/images entry for the image at field, such as contour.image,
+// in values.yaml. The digest is included when it is pinned.
+func artifactHubImage(valuesPath, name, field string, image imageState) (artifacthub.Image, error) {
+	ref, err := imageReference(valuesPath, field, image.Tag)
+	if err != nil {
+		return artifacthub.Image{}, err
+	}
+
+	s := ref.String()
+	if image.Digest != "" {
+		s += "@" + image.Digest
+	}
+	return artifacthub.Image{Name: name, Image: s}, nil
+}
+
+// artifactHubChanges returns the artifacthub.io/changes entries for the chart release, all of the given kind.
+func (e changelogEntry) artifactHubChanges(kind string) []artifacthub.Change {
+	var changes []artifacthub.Change
+	add := func(component, oldVersion, newVersion, releaseNotesURL string) {
+		if newVersion == oldVersion {
+			return
+		}
+		changes = append(changes, artifacthub.Change{
+			Kind:        kind,
+			Description: upgradeDescription(component, oldVersion, newVersion),
+			Links: []artifacthub.Link{{
+				Name: component + " " + newVersion + " release notes",
+				URL:  fmt.Sprintf(releaseNotesURL, newVersion),
+			}},
+		})
+	}
+	add("Contour", e.OldContour, e.NewContour, contourReleaseNotesURL)
+	add("Envoy", e.OldEnvoy, e.NewEnvoy, envoyReleaseNotesURL)
+
+	if len(changes) == 0 {
+		changes = append(changes, artifacthub.Change{Kind: kind, Description: "No Contour or Envoy version changes"})
+	}
+	return changes
+}
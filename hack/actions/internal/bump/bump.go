@@ -0,0 +1,287 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bump implements the bump-chart-versions tool, which moves the Contour chart
+// to the latest stable Contour release.
+package bump
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/artifacthub"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/registry"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/yamledit"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.StandardLogger()
+
+// ContourMinorCurrent makes Options.ContourMinor track the minor version of the current appVersion.
+const ContourMinorCurrent = "current"
+
+// Options configures a version bump.
+type Options struct {
+	// ChartPath is the path to the chart's Chart.yaml.
+	ChartPath string
+
+	// ValuesPath is the path to the chart's values.yaml.
+	ValuesPath string
+
+	// Images locates the Contour and Envoy images in values.yaml.
+	Images Images
+
+	// Source provides Contour's versions.yaml.
+	Source VersionSource
+
+	// ContourMinor restricts the bump to patch releases of a Contour minor version, such as "1.32",
+	// for maintenance release lines of the chart. "current" tracks the minor version of the
+	// current appVersion. If empty, the latest supported Contour release is used.
+	ContourMinor string
+
+	// ContourVersion is the exact Contour version to bump to, such as "1.34.0-rc.1", instead of the
+	// latest supported one. It must be listed in versions.yaml and cannot be combined with ContourMinor.
+	ContourVersion string
+
+	// Channel decides whether pre-releases are considered for the latest Contour release.
+	// Defaults to ChannelStable.
+	Channel Channel
+
+	// Policy decides how the chart version is bumped. Defaults to PolicySemver.
+	Policy Policy
+
+	// ChangelogPath is the path to the chart's CHANGELOG.md. If empty, the changelog is not updated.
+	ChangelogPath string
+
+	// PinDigests resolves the new image tags to their multi-arch manifest digests
+	// and writes them to image.digest next to the tags.
+	PinDigests bool
+
+	// Platforms lists the platforms, such as linux/amd64 and linux/arm64, that the new image tags must be
+	// published for. The bump fails before changing any file if a tag or platform is missing from the
+	// registry. If empty, the images are not checked unless PinDigests is set.
+	Platforms []registry.Platform
+
+	// Registry is the client used to check images and resolve digests. If nil, a default client is used.
+	Registry *registry.Client
+
+	// Security marks the Contour and Envoy upgrades as security fixes in the artifacthub.io/changes annotation.
+	Security bool
+}
+
+// Run bumps the chart and image versions to the latest stable Contour release, or the release
+// selected by opts, and returns a report of what changed. If the chart is already up to date,
+// no changes are made.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	// Read current chart and app versions.
+	currentChartVersion, currentChartAppVersion, err := getCurrentChartVersions(opts.ChartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current chart versions: %w", err)
+	}
+	log.Infof("Current chart version: %s, appVersion: %s", currentChartVersion, currentChartAppVersion)
+
+	currentKubeVersion, err := getCurrentKubeVersion(opts.ChartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current kubeVersion: %w", err)
+	}
+
+	currentContourImage, err := readImage(opts.ValuesPath, opts.Images.Contour)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current Contour image: %w", err)
+	}
+	currentEnvoyImage, err := readImage(opts.ValuesPath, opts.Images.Envoy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current Envoy image: %w", err)
+	}
+
+	report := newReport(currentChartVersion, currentChartAppVersion, currentKubeVersion, currentContourImage, currentEnvoyImage)
+
+	// Get the target Contour release and its Envoy version.
+	log.Infof("Reading Contour versions from %s", opts.Source)
+	target, err := targetRelease(ctx, opts, currentChartAppVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target versions: %w", err)
+	}
+	contourVersion, envoyVersion := target.Contour, target.Envoy
+	log.Infof("Target Contour: %s, Envoy: %s, tested on Kubernetes: %s", contourVersion, envoyVersion, strings.Join(target.Kubernetes, ", "))
+	report.KubernetesVersions = target.Kubernetes
+
+	// Compare versions, refusing to move the chart to an older Contour.
+	upToDate, err := isUpToDate(currentChartAppVersion, contourVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare versions: %w", err)
+	}
+	if upToDate {
+		log.Infof("Contour version %s is already up to date", currentChartAppVersion)
+		return report, nil
+	}
+
+	policy := opts.Policy
+	if policy == "" {
+		policy = PolicySemver
+	}
+	newChartVersion, err := nextChartVersion(policy, currentChartVersion, currentChartAppVersion, contourVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get next chart version: %w", err)
+	}
+
+	// Check the new images and resolve their digests before touching any file,
+	// so that a missing image or a registry failure leaves the chart as is.
+	contourImage := imageState{Tag: fmt.Sprintf("v%s", contourVersion)}
+	envoyImage := imageState{Tag: fmt.Sprintf("v%s", envoyVersion)}
+	if opts.PinDigests || len(opts.Platforms) > 0 {
+		client := opts.Registry
+		if client == nil {
+			client = &registry.Client{}
+		}
+		if opts.Images.Contour != "" {
+			if contourImage.Digest, err = resolveImage(ctx, client, opts.ValuesPath, opts.Images.Contour, contourImage.Tag, opts.Platforms, opts.PinDigests); err != nil {
+				return nil, err
+			}
+		}
+		if opts.Images.Envoy != "" {
+			if envoyImage.Digest, err = resolveImage(ctx, client, opts.ValuesPath, opts.Images.Envoy, envoyImage.Tag, opts.Platforms, opts.PinDigests); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Read the image references for the Artifact Hub annotations before touching any file as well.
+	var annotationImages []artifacthub.Image
+	if opts.Images.Contour != "" {
+		image, err := artifactHubImage(opts.ValuesPath, "contour", opts.Images.Contour, contourImage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Contour image: %w", err)
+		}
+		annotationImages = append(annotationImages, image)
+	}
+	if opts.Images.Envoy != "" {
+		image, err := artifactHubImage(opts.ValuesPath, "envoy", opts.Images.Envoy, envoyImage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Envoy image: %w", err)
+		}
+		annotationImages = append(annotationImages, image)
+	}
+
+	entry := changelogEntry{
+		ChartVersion: newChartVersion,
+		OldContour:   currentChartAppVersion,
+		NewContour:   contourVersion,
+	}
+	if opts.Images.Envoy != "" {
+		// Charts without an Envoy image leave the Envoy upgrade out of the changelog.
+		entry.OldEnvoy, entry.NewEnvoy = strings.TrimPrefix(currentEnvoyImage.Tag, "v"), envoyVersion
+	}
+
+	// Update Chart.yaml with new chart version and appVersion based on latest Contour version info.
+	if err := setYAMLField(opts.ChartPath, "version", newChartVersion); err != nil {
+		return nil, fmt.Errorf("failed to update Contour chart version: %w", err)
+	}
+	log.Infof("Updated Contour chart version to %s in %s", newChartVersion, opts.ChartPath)
+	report.ChartVersion.New = newChartVersion
+	report.touched(opts.ChartPath)
+
+	if err := setYAMLField(opts.ChartPath, "appVersion", contourVersion); err != nil {
+		return nil, fmt.Errorf("failed to update Contour chart appVersion: %w", err)
+	}
+	log.Infof("Updated Contour chart appVersion to %s in %s", contourVersion, opts.ChartPath)
+	report.AppVersion.New = contourVersion
+
+	// Constrain the Kubernetes versions the chart installs on to the ones the new Contour is tested against.
+	// If versions.yaml has no Kubernetes versions for the release, the current constraint is left as is.
+	if kubeVersion := kubeVersionConstraint(target.Kubernetes); kubeVersion != "" && kubeVersion != currentKubeVersion {
+		if err := yamledit.SetOrInsertFile(opts.ChartPath, "kubeVersion", kubeVersion); err != nil {
+			return nil, fmt.Errorf("failed to update Contour chart kubeVersion: %w", err)
+		}
+		log.Infof("Updated Contour chart kubeVersion to %s in %s", kubeVersion, opts.ChartPath)
+		report.KubeVersion.New = kubeVersion
+	}
+
+	// Update values.yaml with new Contour and Envoy versions.
+	if opts.Images.Contour != "" {
+		if err := updateImage(opts.ValuesPath, opts.Images.Contour, currentContourImage, contourImage); err != nil {
+			return nil, fmt.Errorf("failed to update Contour version: %w", err)
+		}
+		report.ContourTag.New, report.ContourDigest.New = contourImage.Tag, contourImage.Digest
+		report.touched(opts.ValuesPath)
+	}
+	if opts.Images.Envoy != "" {
+		if err := updateImage(opts.ValuesPath, opts.Images.Envoy, currentEnvoyImage, envoyImage); err != nil {
+			return nil, fmt.Errorf("failed to update Envoy version: %w", err)
+		}
+		report.EnvoyTag.New, report.EnvoyDigest.New = envoyImage.Tag, envoyImage.Digest
+		report.touched(opts.ValuesPath)
+	}
+
+	// Update the Artifact Hub annotations with the new images and the changes of the new chart version.
+	if len(annotationImages) > 0 {
+		if _, err := artifacthub.SetAnnotation(opts.ChartPath, artifacthub.ImagesAnnotation, annotationImages); err != nil {
+			return nil, fmt.Errorf("failed to update Artifact Hub images: %w", err)
+		}
+	}
+	kind := artifacthub.KindChanged
+	if opts.Security {
+		kind = artifacthub.KindSecurity
+	}
+	if _, err := artifacthub.SetAnnotation(opts.ChartPath, artifacthub.ChangesAnnotation, entry.artifactHubChanges(kind)); err != nil {
+		return nil, fmt.Errorf("failed to update Artifact Hub changes: %w", err)
+	}
+	log.Infof("Updated Artifact Hub annotations in %s", opts.ChartPath)
+
+	// Add a changelog section for the new chart version.
+	if opts.ChangelogPath != "" {
+		added, err := addChangelogEntries(opts.ChangelogPath, entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update changelog: %w", err)
+		}
+		if len(added) > 0 {
+			log.Infof("Added changelog section for chart version %s to %s", newChartVersion, opts.ChangelogPath)
+			report.touched(opts.ChangelogPath)
+		}
+	}
+
+	return report, nil
+}
+
+// targetRelease returns the Contour release to bump to: the exact version requested by opts,
+// or the latest supported release of opts.Channel, limited to the minor version opts.ContourMinor.
+func targetRelease(ctx context.Context, opts Options, currentAppVersion string) (*release, error) {
+	if opts.ContourVersion != "" {
+		if opts.ContourMinor != "" {
+			return nil, fmt.Errorf("a Contour version and a Contour minor version cannot both be set")
+		}
+		log.Infof("Targeting Contour %s", opts.ContourVersion)
+		return getRelease(ctx, opts.Source, opts.ContourVersion)
+	}
+
+	minor := opts.ContourMinor
+	if minor == ContourMinorCurrent {
+		v, err := semver.NewVersion(currentAppVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid current appVersion %q: %w", currentAppVersion, err)
+		}
+		minor = fmt.Sprintf("%d.%d", v.Major(), v.Minor())
+	}
+	if minor != "" {
+		log.Infof("Tracking maintenance release line Contour %s.x", minor)
+	}
+
+	preReleases := opts.Channel == ChannelRC
+	if preReleases {
+		log.Infof("Including Contour pre-releases")
+	}
+
+	return getLatestRelease(ctx, opts.Source, minor, preReleases)
+}
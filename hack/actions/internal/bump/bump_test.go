@@ -0,0 +1,401 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/registry"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/registry/registrytest"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/yamledit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeChart writes a minimal Chart.yaml and values.yaml into a temporary directory.
+func writeChart(t *testing.T, chartVersion, appVersion string) Options {
+	t.Helper()
+
+	dir := t.TempDir()
+	opts := Options{
+		ChartPath:     filepath.Join(dir, "Chart.yaml"),
+		ValuesPath:    filepath.Join(dir, "values.yaml"),
+		Images:        Images{Contour: "contour.image", Envoy: "envoy.image"},
+		ChangelogPath: filepath.Join(dir, "CHANGELOG.md"),
+	}
+	chart := "annotations:\n  category: Infrastructure\napiVersion: v2\nappVersion: " + appVersion + "\nname: contour\nversion: " + chartVersion + "\n"
+	values := "contour:\n" +
+		"  image:\n" +
+		"    registry: ghcr.io\n" +
+		"    repository: projectcontour/contour\n" +
+		"    tag: v" + appVersion + "\n" +
+		"    digest: \"\"\n" +
+		"envoy:\n" +
+		"  image:\n" +
+		"    registry: docker.io\n" +
+		"    repository: envoyproxy/envoy\n" +
+		"    tag: v1.35.8\n" +
+		"    digest: \"\"\n"
+	require.NoError(t, os.WriteFile(opts.ChartPath, []byte(chart), 0o600))
+	require.NoError(t, os.WriteFile(opts.ValuesPath, []byte(values), 0o600))
+	require.NoError(t, os.WriteFile(opts.ChangelogPath, []byte("# Changelog\n## "+chartVersion+"\n* Previous release\n"), 0o600))
+
+	return opts
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestRun(t *testing.T) {
+	fixture := readFile(t, "testdata/versions.yaml")
+
+	t.Run("bumps chart and images to the latest stable release", func(t *testing.T) {
+		opts := writeChart(t, "0.7.0", "1.33.6")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+
+		report, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, &Report{
+			Changed:       true,
+			ChartVersion:  Change{Old: "0.7.0", New: "0.7.1"},
+			AppVersion:    Change{Old: "1.33.6", New: "1.33.10"},
+			KubeVersion:   Change{Old: "", New: ">=1.31.0-0"},
+			ContourTag:    Change{Old: "v1.33.6", New: "v1.33.10"},
+			ContourDigest: Change{},
+			EnvoyTag:      Change{Old: "v1.35.8", New: "v1.35.9"},
+			EnvoyDigest:   Change{},
+
+			KubernetesVersions: []string{"1.33", "1.32", "1.31"},
+			Files:              []string{opts.ChartPath, opts.ValuesPath, opts.ChangelogPath},
+		}, report)
+
+		assert.Equal(t, "annotations:\n"+
+			"  artifacthub.io/changes: |\n"+
+			"    - kind: changed\n"+
+			"      description: Contour upgraded from 1.33.6 to 1.33.10\n"+
+			"      links:\n"+
+			"        - name: Contour 1.33.10 release notes\n"+
+			"          url: https://github.com/projectcontour/contour/releases/tag/v1.33.10\n"+
+			"    - kind: changed\n"+
+			"      description: Envoy upgraded from 1.35.8 to 1.35.9\n"+
+			"      links:\n"+
+			"        - name: Envoy 1.35.9 release notes\n"+
+			"          url: https://github.com/envoyproxy/envoy/releases/tag/v1.35.9\n"+
+			"  artifacthub.io/images: |\n"+
+			"    - name: contour\n"+
+			"      image: ghcr.io/projectcontour/contour:v1.33.10\n"+
+			"    - name: envoy\n"+
+			"      image: docker.io/envoyproxy/envoy:v1.35.9\n"+
+			"  category: Infrastructure\n"+
+			"apiVersion: v2\n"+
+			"appVersion: 1.33.10\n"+
+			"kubeVersion: \">=1.31.0-0\"\n"+
+			"name: contour\n"+
+			"version: 0.7.1\n", readFile(t, opts.ChartPath))
+
+		values := readFile(t, opts.ValuesPath)
+		assert.Contains(t, values, "tag: v1.33.10")
+		assert.Contains(t, values, "tag: v1.35.9")
+
+		assert.Equal(t, "# Changelog\n"+
+			"## 0.7.1\n"+
+			"* Contour upgraded from 1.33.6 to 1.33.10 ([release notes](https://github.com/projectcontour/contour/releases/tag/v1.33.10))\n"+
+			"* Envoy upgraded from 1.35.8 to 1.35.9 ([release notes](https://github.com/envoyproxy/envoy/releases/tag/v1.35.9))\n"+
+			"\n"+
+			"## 0.7.0\n"+
+			"* Previous release\n", readFile(t, opts.ChangelogPath))
+	})
+
+	t.Run("contour minor release bumps chart minor version", func(t *testing.T) {
+		opts := writeChart(t, "0.6.2", "1.32.2")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+
+		_, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+
+		version, appVersion, err := getCurrentChartVersions(opts.ChartPath)
+		require.NoError(t, err)
+		assert.Equal(t, "0.7.0", version)
+		assert.Equal(t, "1.33.10", appVersion)
+	})
+
+	t.Run("maintenance release line stays on its contour minor", func(t *testing.T) {
+		opts := writeChart(t, "0.6.2", "1.32.1")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		opts.ContourMinor = ContourMinorCurrent
+
+		_, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+
+		version, appVersion, err := getCurrentChartVersions(opts.ChartPath)
+		require.NoError(t, err)
+		assert.Equal(t, "0.6.3", version)
+		assert.Equal(t, "1.32.2", appVersion)
+		assert.Contains(t, readFile(t, opts.ValuesPath), "tag: v1.34.10")
+	})
+
+	t.Run("security release", func(t *testing.T) {
+		opts := writeChart(t, "0.7.0", "1.33.6")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		opts.Security = true
+
+		_, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+
+		chart := readFile(t, opts.ChartPath)
+		assert.Contains(t, chart, "    - kind: security\n      description: Contour upgraded from 1.33.6 to 1.33.10\n")
+		assert.NotContains(t, chart, "kind: changed")
+	})
+
+	t.Run("existing kubeVersion is updated in place", func(t *testing.T) {
+		opts := writeChart(t, "0.6.2", "1.32.2")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		require.NoError(t, yamledit.SetOrInsertFile(opts.ChartPath, "kubeVersion", ">=1.30.0-0"))
+
+		report, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, Change{Old: ">=1.30.0-0", New: ">=1.31.0-0"}, report.KubeVersion)
+
+		kubeVersion, err := getCurrentKubeVersion(opts.ChartPath)
+		require.NoError(t, err)
+		assert.Equal(t, ">=1.31.0-0", kubeVersion)
+	})
+
+	t.Run("release candidate channel gives a chart pre-release", func(t *testing.T) {
+		opts := writeChart(t, "0.7.0", "1.33.6")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		opts.Channel = ChannelRC
+
+		_, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+
+		version, appVersion, err := getCurrentChartVersions(opts.ChartPath)
+		require.NoError(t, err)
+		assert.Equal(t, "0.8.0-rc.1", version)
+		assert.Equal(t, "1.34.0-rc.1", appVersion)
+		values := readFile(t, opts.ValuesPath)
+		assert.Contains(t, values, "tag: v1.34.0-rc.1")
+		assert.Contains(t, values, "tag: v1.36.0")
+		assert.Contains(t, readFile(t, opts.ChangelogPath), "## 0.8.0-rc.1\n")
+	})
+
+	t.Run("exact contour version", func(t *testing.T) {
+		opts := writeChart(t, "0.6.2", "1.32.2")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		opts.ContourVersion = "v1.33.6"
+
+		_, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+
+		version, appVersion, err := getCurrentChartVersions(opts.ChartPath)
+		require.NoError(t, err)
+		assert.Equal(t, "0.7.0", version)
+		assert.Equal(t, "1.33.6", appVersion)
+		assert.Contains(t, readFile(t, opts.ValuesPath), "tag: v1.35.8")
+	})
+
+	t.Run("missing exact contour version leaves the chart untouched", func(t *testing.T) {
+		opts := writeChart(t, "0.7.0", "1.33.6")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		opts.ContourVersion = "1.34.0-rc.9"
+		before := readFile(t, opts.ChartPath)
+
+		_, err := Run(context.Background(), opts)
+		require.ErrorContains(t, err, "contour version 1.34.0-rc.9 not found")
+		assert.Equal(t, before, readFile(t, opts.ChartPath))
+	})
+
+	t.Run("exact contour version and minor are exclusive", func(t *testing.T) {
+		opts := writeChart(t, "0.7.0", "1.33.6")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		opts.ContourVersion = "1.33.10"
+		opts.ContourMinor = "1.33"
+
+		_, err := Run(context.Background(), opts)
+		require.ErrorContains(t, err, "cannot both be set")
+	})
+
+	t.Run("contour major release is left for a human", func(t *testing.T) {
+		opts := writeChart(t, "0.6.2", "0.9.0")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		before := readFile(t, opts.ChartPath)
+
+		_, err := Run(context.Background(), opts)
+		require.ErrorIs(t, err, ErrMajorUpgrade)
+		assert.Equal(t, before, readFile(t, opts.ChartPath))
+	})
+
+	t.Run("digests are pinned next to the tags", func(t *testing.T) {
+		reg := registrytest.New(t)
+		contourDigest := reg.PushIndex("projectcontour/contour", "v1.33.10", "linux/amd64", "linux/arm64")
+		envoyDigest := reg.PushIndex("envoyproxy/envoy", "v1.35.9", "linux/amd64", "linux/arm64")
+
+		opts := writeChart(t, "0.7.0", "1.33.6")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		opts.PinDigests = true
+		opts.Registry = &registry.Client{HTTPClient: reg.Client()}
+		require.NoError(t, setYAMLField(opts.ValuesPath, "contour.image.registry", reg.Host()))
+		require.NoError(t, setYAMLField(opts.ValuesPath, "envoy.image.registry", reg.Host()))
+
+		_, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+
+		values := readFile(t, opts.ValuesPath)
+		assert.Contains(t, values, "    tag: v1.33.10\n    digest: \""+contourDigest+"\"\n")
+		assert.Contains(t, values, "    tag: v1.35.9\n    digest: \""+envoyDigest+"\"\n")
+
+		chart := readFile(t, opts.ChartPath)
+		assert.Contains(t, chart, "image: "+reg.Host()+"/projectcontour/contour:v1.33.10@"+contourDigest+"\n")
+		assert.Contains(t, chart, "image: "+reg.Host()+"/envoyproxy/envoy:v1.35.9@"+envoyDigest+"\n")
+	})
+
+	t.Run("missing image leaves the chart untouched", func(t *testing.T) {
+		reg := registrytest.New(t)
+		reg.PushIndex("projectcontour/contour", "v1.33.10", "linux/amd64", "linux/arm64")
+
+		opts := writeChart(t, "0.7.0", "1.33.6")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		opts.PinDigests = true
+		opts.Registry = &registry.Client{HTTPClient: reg.Client()}
+		require.NoError(t, setYAMLField(opts.ValuesPath, "contour.image.registry", reg.Host()))
+		require.NoError(t, setYAMLField(opts.ValuesPath, "envoy.image.registry", reg.Host()))
+		chart, values := readFile(t, opts.ChartPath), readFile(t, opts.ValuesPath)
+
+		_, err := Run(context.Background(), opts)
+		require.ErrorIs(t, err, registry.ErrNotFound)
+		assert.Equal(t, chart, readFile(t, opts.ChartPath))
+		assert.Equal(t, values, readFile(t, opts.ValuesPath))
+	})
+
+	t.Run("images are verified for the required platforms", func(t *testing.T) {
+		reg := registrytest.New(t)
+		reg.PushIndex("projectcontour/contour", "v1.33.10", "linux/amd64", "linux/arm64")
+		reg.PushImage("envoyproxy/envoy", "v1.35.9", "linux/amd64")
+
+		opts := writeChart(t, "0.7.0", "1.33.6")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		opts.Platforms = []registry.Platform{{OS: "linux", Architecture: "amd64"}}
+		opts.Registry = &registry.Client{HTTPClient: reg.Client()}
+		require.NoError(t, setYAMLField(opts.ValuesPath, "contour.image.registry", reg.Host()))
+		require.NoError(t, setYAMLField(opts.ValuesPath, "envoy.image.registry", reg.Host()))
+
+		report, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+		assert.True(t, report.Changed)
+		assert.False(t, report.ContourDigest.Changed())
+		assert.False(t, report.EnvoyDigest.Changed())
+	})
+
+	t.Run("missing platform leaves the chart untouched", func(t *testing.T) {
+		reg := registrytest.New(t)
+		reg.PushIndex("projectcontour/contour", "v1.33.10", "linux/amd64", "linux/arm64")
+		reg.PushIndex("envoyproxy/envoy", "v1.35.9", "linux/amd64")
+
+		opts := writeChart(t, "0.7.0", "1.33.6")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		opts.Platforms = []registry.Platform{{OS: "linux", Architecture: "amd64"}, {OS: "linux", Architecture: "arm64"}}
+		opts.Registry = &registry.Client{HTTPClient: reg.Client()}
+		require.NoError(t, setYAMLField(opts.ValuesPath, "contour.image.registry", reg.Host()))
+		require.NoError(t, setYAMLField(opts.ValuesPath, "envoy.image.registry", reg.Host()))
+		chart, values, changelog := readFile(t, opts.ChartPath), readFile(t, opts.ValuesPath), readFile(t, opts.ChangelogPath)
+
+		_, err := Run(context.Background(), opts)
+		require.ErrorIs(t, err, registry.ErrPlatformNotFound)
+		assert.ErrorContains(t, err, "failed to verify "+reg.Host()+"/envoyproxy/envoy:v1.35.9: ")
+		assert.ErrorContains(t, err, "not published for linux/arm64")
+		assert.Equal(t, chart, readFile(t, opts.ChartPath))
+		assert.Equal(t, values, readFile(t, opts.ValuesPath))
+		assert.Equal(t, changelog, readFile(t, opts.ChangelogPath))
+	})
+
+	t.Run("chart without images only bumps versions", func(t *testing.T) {
+		opts := writeChart(t, "0.7.0", "1.33.6")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		opts.Images = Images{}
+		values := readFile(t, opts.ValuesPath)
+
+		report, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, []string{opts.ChartPath, opts.ChangelogPath}, report.Files)
+		assert.Equal(t, Change{}, report.ContourTag)
+		assert.Equal(t, Change{}, report.EnvoyTag)
+		assert.Equal(t, values, readFile(t, opts.ValuesPath))
+
+		chart := readFile(t, opts.ChartPath)
+		assert.Contains(t, chart, "appVersion: 1.33.10\n")
+		assert.Contains(t, chart, "description: Contour upgraded from 1.33.6 to 1.33.10\n")
+		assert.NotContains(t, chart, "artifacthub.io/images")
+		assert.NotContains(t, chart, "Envoy")
+		assert.NotContains(t, readFile(t, opts.ChangelogPath), "Envoy")
+	})
+
+	t.Run("stale digest is cleared when not pinning", func(t *testing.T) {
+		opts := writeChart(t, "0.7.0", "1.33.6")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		require.NoError(t, setYAMLField(opts.ValuesPath, "envoy.image.digest", "sha256:0123"))
+
+		_, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+
+		digest, err := yamledit.GetFile(opts.ValuesPath, "envoy.image.digest")
+		require.NoError(t, err)
+		assert.Empty(t, digest)
+	})
+
+	t.Run("up to date chart is left untouched", func(t *testing.T) {
+		opts := writeChart(t, "0.7.0", "1.33.10")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		before := readFile(t, opts.ChartPath)
+
+		report, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, before, readFile(t, opts.ChartPath))
+		assert.False(t, report.Changed)
+		assert.Empty(t, report.Files)
+		assert.False(t, report.AppVersion.Changed())
+		assert.Equal(t, "1.33.10", report.AppVersion.New)
+	})
+
+	t.Run("stable channel keeps a pre-release", func(t *testing.T) {
+		opts := writeChart(t, "0.8.0-rc.1", "1.34.0-rc.1")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		opts.Channel = ChannelStable
+		before := readFile(t, opts.ChartPath)
+
+		report, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, before, readFile(t, opts.ChartPath))
+		assert.False(t, report.Changed)
+		assert.False(t, report.AppVersion.Changed())
+	})
+
+	t.Run("downgrade is refused", func(t *testing.T) {
+		opts := writeChart(t, "0.9.0", "1.34.1")
+		opts.Source = newVersionsServer(t, http.StatusOK, fixture)
+		before := readFile(t, opts.ChartPath)
+
+		_, err := Run(context.Background(), opts)
+		require.ErrorContains(t, err, "refusing to downgrade")
+		assert.Equal(t, before, readFile(t, opts.ChartPath))
+	})
+}
@@ -0,0 +1,254 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/yamledit"
+)
+
+const (
+	contourReleaseNotesURL = "https://github.com/projectcontour/contour/releases/tag/v%s"
+	envoyReleaseNotesURL   = "https://github.com/envoyproxy/envoy/releases/tag/v%s"
+)
+
+// changelogEntry describes the version changes of a single chart release.
+// Old versions are empty when they are not known.
+type changelogEntry struct {
+	ChartVersion string
+	OldContour   string
+	NewContour   string
+	OldEnvoy     string
+	NewEnvoy     string
+}
+
+// markdown renders the entry as a changelog section.
+func (e changelogEntry) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n", e.ChartVersion)
+
+	changed := false
+	if e.NewContour != e.OldContour {
+		b.WriteString(upgradeLine("Contour", e.OldContour, e.NewContour, contourReleaseNotesURL))
+		changed = true
+	}
+	if e.NewEnvoy != e.OldEnvoy {
+		b.WriteString(upgradeLine("Envoy", e.OldEnvoy, e.NewEnvoy, envoyReleaseNotesURL))
+		changed = true
+	}
+	if !changed {
+		b.WriteString("* No Contour or Envoy version changes\n")
+	}
+
+	return b.String()
+}
+
+func upgradeLine(component, oldVersion, newVersion, releaseNotesURL string) string {
+	notes := fmt.Sprintf("([release notes](%s))", fmt.Sprintf(releaseNotesURL, newVersion))
+	return fmt.Sprintf("* %s %s\n", upgradeDescription(component, oldVersion, newVersion), notes)
+}
+
+// upgradeDescription describes the upgrade of a component, such as "Contour upgraded from 1.33.6 to 1.33.10".
+func upgradeDescription(component, oldVersion, newVersion string) string {
+	if oldVersion == "" {
+		return fmt.Sprintf("%s upgraded to %s", component, newVersion)
+	}
+	return fmt.Sprintf("%s upgraded from %s to %s", component, oldVersion, newVersion)
+}
+
+// addChangelogEntries adds sections for the given entries to the changelog file.
+// Sections are kept ordered from newest to oldest chart version, and entries for
+// chart versions that already have a section are skipped.
+func addChangelogEntries(filePath string, entries ...changelogEntry) ([]string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	content := string(data)
+	var added []string
+	for _, e := range entries {
+		var ok bool
+		content, ok = insertSection(content, e.ChartVersion, e.markdown())
+		if ok {
+			added = append(added, e.ChartVersion)
+		}
+	}
+
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil { //nolint:gosec // G306: chart files are intentionally world-readable
+		return nil, fmt.Errorf("failed to write file %s: %w", filePath, err)
+	}
+
+	return added, nil
+}
+
+// insertSection inserts section for version into the changelog content before the first
+// section of a lower version. It returns false if a section for version already exists.
+func insertSection(content, version, section string) (string, bool) {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return content, false
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	insertAt := -1
+	hasSections := false
+	for i, line := range lines {
+		heading, ok := strings.CutPrefix(strings.TrimSpace(line), "## ")
+		if !ok {
+			continue
+		}
+		hasSections = true
+		existing, err := semver.NewVersion(heading)
+		if err != nil {
+			continue
+		}
+		if existing.Equal(v) {
+			return content, false
+		}
+		if existing.LessThan(v) && insertAt < 0 {
+			insertAt = i
+		}
+	}
+
+	// Sections are separated by a blank line.
+	if insertAt >= 0 {
+		return strings.Join(lines[:insertAt], "") + section + "\n" + strings.Join(lines[insertAt:], ""), true
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if hasSections && !strings.HasSuffix(content, "\n\n") {
+		content += "\n"
+	}
+	return content + section, true
+}
+
+// chartRelease is the state of the chart at a commit in git history.
+type chartRelease struct {
+	ChartVersion string
+	Contour      string
+	Envoy        string
+}
+
+// BackfillChangelog adds changelog sections for the chart versions found in the git history
+// of Chart.yaml and values.yaml that do not have a section yet.
+func BackfillChangelog(ctx context.Context, opts Options) error {
+	releases, err := chartHistory(ctx, opts.ChartPath, opts.ValuesPath, opts.Images.Envoy)
+	if err != nil {
+		return fmt.Errorf("failed to read chart history: %w", err)
+	}
+
+	var entries []changelogEntry
+	for i, r := range releases {
+		e := changelogEntry{
+			ChartVersion: r.ChartVersion,
+			NewContour:   r.Contour,
+			NewEnvoy:     r.Envoy,
+		}
+		if i > 0 {
+			e.OldContour = releases[i-1].Contour
+			e.OldEnvoy = releases[i-1].Envoy
+		}
+		entries = append(entries, e)
+	}
+
+	added, err := addChangelogEntries(opts.ChangelogPath, entries...)
+	if err != nil {
+		return err
+	}
+	if len(added) == 0 {
+		log.Infof("Changelog %s is up to date", opts.ChangelogPath)
+		return nil
+	}
+	log.Infof("Added changelog sections for chart versions %s to %s", strings.Join(added, ", "), opts.ChangelogPath)
+
+	return nil
+}
+
+// chartHistory returns the chart releases found in the git history of the chart and values files,
+// ordered from oldest to newest. Commits that do not change the chart version are skipped.
+func chartHistory(ctx context.Context, chartPath, valuesPath, envoyImage string) ([]chartRelease, error) {
+	dir := filepath.Dir(chartPath)
+	chartFile := "./" + filepath.Base(chartPath)
+	valuesFile, err := filepath.Rel(dir, valuesPath)
+	if err != nil {
+		return nil, err
+	}
+	valuesFile = "./" + filepath.ToSlash(valuesFile)
+
+	out, err := git(ctx, dir, "log", "--reverse", "--format=%H", "--", chartFile, valuesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []chartRelease
+	for _, rev := range strings.Fields(string(out)) {
+		chart, err := git(ctx, dir, "show", rev+":"+chartFile)
+		if err != nil {
+			// The chart did not exist yet at this commit.
+			continue
+		}
+		values, err := git(ctx, dir, "show", rev+":"+valuesFile)
+		if err != nil {
+			continue
+		}
+
+		r := chartRelease{}
+		if r.ChartVersion, err = yamledit.Get(chart, "version"); err != nil {
+			continue
+		}
+		if r.Contour, err = yamledit.Get(chart, "appVersion"); err != nil {
+			continue
+		}
+		if envoyImage != "" {
+			if envoyTag, err := yamledit.Get(values, envoyImage+".tag"); err == nil {
+				r.Envoy = strings.TrimPrefix(envoyTag, "v")
+			}
+		}
+
+		if len(releases) > 0 && releases[len(releases)-1].ChartVersion == r.ChartVersion {
+			// Keep the last state of a chart version, it is what was released.
+			releases[len(releases)-1] = r
+			continue
+		}
+		releases = append(releases, r)
+	}
+
+	return releases, nil
+}
+
+// git runs a git command in dir and returns its standard output.
+func git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...) //nolint:gosec // G204: arguments are built by this tool
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,167 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangelogEntryMarkdown(t *testing.T) {
+	tests := map[string]struct {
+		entry changelogEntry
+		want  string
+	}{
+		"contour and envoy upgrade": {
+			entry: changelogEntry{ChartVersion: "0.8.0", OldContour: "1.33.6", NewContour: "1.34.0", OldEnvoy: "1.35.8", NewEnvoy: "1.36.2"},
+			want: "## 0.8.0\n" +
+				"* Contour upgraded from 1.33.6 to 1.34.0 ([release notes](https://github.com/projectcontour/contour/releases/tag/v1.34.0))\n" +
+				"* Envoy upgraded from 1.35.8 to 1.36.2 ([release notes](https://github.com/envoyproxy/envoy/releases/tag/v1.36.2))\n",
+		},
+		"envoy only": {
+			entry: changelogEntry{ChartVersion: "0.7.1", OldContour: "1.33.6", NewContour: "1.33.6", OldEnvoy: "1.35.8", NewEnvoy: "1.35.9"},
+			want: "## 0.7.1\n" +
+				"* Envoy upgraded from 1.35.8 to 1.35.9 ([release notes](https://github.com/envoyproxy/envoy/releases/tag/v1.35.9))\n",
+		},
+		"unknown previous versions": {
+			entry: changelogEntry{ChartVersion: "0.1.0", NewContour: "1.32.0", NewEnvoy: "1.34.1"},
+			want: "## 0.1.0\n" +
+				"* Contour upgraded to 1.32.0 ([release notes](https://github.com/projectcontour/contour/releases/tag/v1.32.0))\n" +
+				"* Envoy upgraded to 1.34.1 ([release notes](https://github.com/envoyproxy/envoy/releases/tag/v1.34.1))\n",
+		},
+		"no version changes": {
+			entry: changelogEntry{ChartVersion: "0.7.2", OldContour: "1.33.6", NewContour: "1.33.6", OldEnvoy: "1.35.8", NewEnvoy: "1.35.8"},
+			want:  "## 0.7.2\n* No Contour or Envoy version changes\n",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.entry.markdown())
+		})
+	}
+}
+
+func TestInsertSection(t *testing.T) {
+	const changelog = "# Changelog\n## 0.2.1\n* a\n\n## 0.1.0\n* b\n"
+
+	tests := map[string]struct {
+		content string
+		version string
+		want    string
+		wantOK  bool
+	}{
+		"newest version goes first": {
+			content: changelog,
+			version: "0.3.0",
+			want:    "# Changelog\n## 0.3.0\n* new\n\n## 0.2.1\n* a\n\n## 0.1.0\n* b\n",
+			wantOK:  true,
+		},
+		"version in between": {
+			content: changelog,
+			version: "0.2.0",
+			want:    "# Changelog\n## 0.2.1\n* a\n\n## 0.2.0\n* new\n\n## 0.1.0\n* b\n",
+			wantOK:  true,
+		},
+		"oldest version goes last": {
+			content: changelog,
+			version: "0.0.1",
+			want:    "# Changelog\n## 0.2.1\n* a\n\n## 0.1.0\n* b\n\n## 0.0.1\n* new\n",
+			wantOK:  true,
+		},
+		"empty changelog": {
+			content: "# Changelog\n",
+			version: "0.1.0",
+			want:    "# Changelog\n## 0.1.0\n* new\n",
+			wantOK:  true,
+		},
+		"existing version is skipped": {
+			content: changelog,
+			version: "0.2.1",
+			want:    changelog,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := insertSection(tc.content, tc.version, "## "+tc.version+"\n* new\n")
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestBackfillChangelog(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	dir := t.TempDir()
+	runGit := func(args ...string) {
+		t.Helper()
+		_, err := git(context.Background(), dir, args...)
+		require.NoError(t, err)
+	}
+	commitChart := func(chartVersion, contour, envoy string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("appVersion: "+contour+"\nversion: "+chartVersion+"\n"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "values.yaml"), []byte("envoy:\n  image:\n    tag: v"+envoy+"\n"), 0o600))
+		runGit("add", ".")
+		runGit("commit", "--quiet", "--message", "chart "+chartVersion)
+	}
+
+	runGit("init", "--quiet")
+	runGit("config", "user.name", "test")
+	runGit("config", "user.email", "test@example.com")
+	runGit("config", "commit.gpgsign", "false")
+
+	commitChart("0.1.0", "1.33.0", "1.35.2")
+	commitChart("0.2.0", "1.33.1", "1.35.2")
+	commitChart("0.2.0", "1.33.1", "1.35.3") // Change before 0.2.0 was released.
+	commitChart("0.3.0", "1.33.2", "1.35.8")
+
+	changelogPath := filepath.Join(dir, "CHANGELOG.md")
+	require.NoError(t, os.WriteFile(changelogPath, []byte("# Changelog\n## 0.1.0\n* Hand-written notes\n"), 0o600))
+
+	opts := Options{
+		ChartPath:     filepath.Join(dir, "Chart.yaml"),
+		ValuesPath:    filepath.Join(dir, "values.yaml"),
+		Images:        Images{Envoy: "envoy.image"},
+		ChangelogPath: changelogPath,
+	}
+	require.NoError(t, BackfillChangelog(context.Background(), opts))
+
+	want := "# Changelog\n" +
+		"## 0.3.0\n" +
+		"* Contour upgraded from 1.33.1 to 1.33.2 ([release notes](https://github.com/projectcontour/contour/releases/tag/v1.33.2))\n" +
+		"* Envoy upgraded from 1.35.3 to 1.35.8 ([release notes](https://github.com/envoyproxy/envoy/releases/tag/v1.35.8))\n" +
+		"\n" +
+		"## 0.2.0\n" +
+		"* Contour upgraded from 1.33.0 to 1.33.1 ([release notes](https://github.com/projectcontour/contour/releases/tag/v1.33.1))\n" +
+		"* Envoy upgraded from 1.35.2 to 1.35.3 ([release notes](https://github.com/envoyproxy/envoy/releases/tag/v1.35.3))\n" +
+		"\n" +
+		"## 0.1.0\n" +
+		"* Hand-written notes\n"
+	assert.Equal(t, want, readFile(t, changelogPath))
+
+	// Running again finds nothing to add.
+	require.NoError(t, BackfillChangelog(context.Background(), opts))
+	assert.Equal(t, want, readFile(t, changelogPath))
+}
@@ -0,0 +1,41 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// getCurrentChartVersions reads the current chart and app version.
+func getCurrentChartVersions(filePath string) (string, string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	type ChartYaml struct {
+		Version    string `yaml:"version"`
+		AppVersion string `yaml:"appVersion"`
+	}
+
+	var chart ChartYaml
+	if err := yaml.Unmarshal(data, &chart); err != nil {
+		return "", "", fmt.Errorf("failed to unmarshal yaml from %s: %w", filePath, err)
+	}
+
+	return chart.Version, chart.AppVersion, nil
+}
@@ -0,0 +1,36 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCurrentChartVersions(t *testing.T) {
+	chartPath := filepath.Join(t.TempDir(), "Chart.yaml")
+	require.NoError(t, os.WriteFile(chartPath, []byte("apiVersion: v2\nappVersion: 1.33.6\nname: contour\nversion: 0.7.0\n"), 0o600))
+
+	version, appVersion, err := getCurrentChartVersions(chartPath)
+	require.NoError(t, err)
+	assert.Equal(t, "0.7.0", version)
+	assert.Equal(t, "1.33.6", appVersion)
+
+	_, _, err = getCurrentChartVersions(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorContains(t, err, "failed to read file")
+}
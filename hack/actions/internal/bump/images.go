@@ -0,0 +1,134 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/registry"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/yamledit"
+)
+
+// DefaultPlatforms are the platforms the Contour and Envoy images must be published for, as a comma-separated list.
+const DefaultPlatforms = "linux/amd64,linux/arm64"
+
+// Images locates the Contour and Envoy images of a chart in its values.yaml, as the path of the mapping
+// holding the image registry, repository, tag and digest, such as contour.image. An empty path means
+// that the chart does not deploy the image.
+type Images struct {
+	Contour string `yaml:"contour"`
+	Envoy   string `yaml:"envoy"`
+}
+
+// imageState is the tag and digest of an image in values.yaml.
+type imageState struct {
+	Tag    string
+	Digest string
+}
+
+// readImage reads the tag and digest of the image at field, such as contour.image, from values.yaml.
+// An empty field yields an empty image.
+func readImage(valuesPath, field string) (imageState, error) {
+	if field == "" {
+		return imageState{}, nil
+	}
+	tag, err := yamledit.GetFile(valuesPath, field+".tag")
+	if err != nil {
+		return imageState{}, err
+	}
+	digest, err := yamledit.GetFile(valuesPath, field+".digest")
+	if err != nil {
+		return imageState{}, err
+	}
+	return imageState{Tag: tag, Digest: digest}, nil
+}
+
+// imageReference reads the registry and repository of the image at field, such as contour.image,
+// from values.yaml and returns a reference to the given tag.
+func imageReference(valuesPath, field, tag string) (registry.Reference, error) {
+	reg, err := yamledit.GetFile(valuesPath, field+".registry")
+	if err != nil {
+		return registry.Reference{}, err
+	}
+	repo, err := yamledit.GetFile(valuesPath, field+".repository")
+	if err != nil {
+		return registry.Reference{}, err
+	}
+
+	return registry.Reference{Registry: reg, Repository: repo, Tag: tag}, nil
+}
+
+// resolveImage checks that the image at field is published with the given tag and for all of the given
+// platforms. If pin is set, it returns the manifest digest of the tag, preferably of a multi-arch index.
+func resolveImage(ctx context.Context, client *registry.Client, valuesPath, field, tag string, platforms []registry.Platform, pin bool) (string, error) {
+	ref, err := imageReference(valuesPath, field, tag)
+	if err != nil {
+		return "", err
+	}
+
+	var m *registry.Manifest
+	if len(platforms) > 0 {
+		if m, err = client.Verify(ctx, ref, platforms); err != nil {
+			return "", fmt.Errorf("failed to verify %s: %w", ref, err)
+		}
+		log.Infof("Verified %s is published for %s", ref, joinPlatforms(platforms))
+	} else {
+		if m, err = client.Manifest(ctx, ref); err != nil {
+			return "", fmt.Errorf("failed to resolve digest of %s: %w", ref, err)
+		}
+	}
+
+	if !pin {
+		return "", nil
+	}
+	if !m.IsIndex() {
+		log.Warnf("Image %s is not a multi-arch image, pinning single manifest digest %s", ref, m.Digest)
+	}
+	log.Infof("Resolved %s to %s", ref, m.Digest)
+
+	return m.Digest, nil
+}
+
+func joinPlatforms(platforms []registry.Platform) string {
+	names := make([]string, 0, len(platforms))
+	for _, p := range platforms {
+		names = append(names, p.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// updateImage sets the tag and digest of the image at field in values.yaml.
+// An empty digest clears a previously pinned one, since the digest would override the new tag.
+func updateImage(valuesPath, field string, current, next imageState) error {
+	if err := setYAMLField(valuesPath, field+".tag", next.Tag); err != nil {
+		return err
+	}
+	log.Infof("Updated %s.tag to %s in %s", field, next.Tag, valuesPath)
+
+	if current.Digest == next.Digest {
+		return nil
+	}
+	if next.Digest == "" {
+		log.Warnf("Clearing %s.digest %s in %s since it would override tag %s", field, current.Digest, valuesPath, next.Tag)
+	}
+
+	if err := setYAMLField(valuesPath, field+".digest", next.Digest); err != nil {
+		return err
+	}
+	log.Infof("Updated %s.digest to %q in %s", field, next.Digest, valuesPath)
+
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// getCurrentKubeVersion reads the current kubeVersion constraint of the chart, which is empty if not set.
+func getCurrentKubeVersion(filePath string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	var chart struct {
+		KubeVersion string `yaml:"kubeVersion"`
+	}
+	if err := yaml.Unmarshal(data, &chart); err != nil {
+		return "", fmt.Errorf("failed to unmarshal yaml from %s: %w", filePath, err)
+	}
+
+	return chart.KubeVersion, nil
+}
+
+// kubernetesVersions returns the Kubernetes minor versions a Contour release is tested against,
+// such as "1.30", newest first and without duplicates.
+func kubernetesVersions(versions []string) ([]string, error) {
+	parsed := make([]*semver.Version, 0, len(versions))
+	for _, s := range versions {
+		v, err := parseMinor(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Kubernetes version: %w", err)
+		}
+		if !slices.ContainsFunc(parsed, v.Equal) {
+			parsed = append(parsed, v)
+		}
+	}
+
+	slices.SortFunc(parsed, func(a, b *semver.Version) int { return b.Compare(a) })
+
+	out := make([]string, 0, len(parsed))
+	for _, v := range parsed {
+		out = append(out, fmt.Sprintf("%d.%d", v.Major(), v.Minor()))
+	}
+	return out, nil
+}
+
+// kubeVersionConstraint returns the Chart.yaml kubeVersion constraint for the given Kubernetes
+// minor versions, as returned by kubernetesVersions.
+//
+// Only a lower bound is set: newer Kubernetes versions than the tested ones usually work, and an
+// upper bound would make helm refuse to install the chart on them. The -0 suffix lets vendor builds
+// such as v1.28.3-eks-1, which are pre-releases in semver terms, satisfy the constraint.
+func kubeVersionConstraint(versions []string) string {
+	if len(versions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(">=%s.0-0", versions[len(versions)-1])
+}
@@ -0,0 +1,128 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Policy decides how the chart version moves when the Contour version changes.
+type Policy string
+
+const (
+	// PolicySemver follows the size of the upstream change: a Contour patch release
+	// gives a chart patch bump and a Contour minor release gives a chart minor bump.
+	// A Contour major release is not bumped automatically and needs a human decision.
+	PolicySemver Policy = "semver"
+
+	// PolicyMinor always bumps the chart minor version.
+	PolicyMinor Policy = "minor"
+)
+
+// ErrMajorUpgrade is returned when Contour moved to a new major version,
+// which needs a chart version decided by a human.
+var ErrMajorUpgrade = errors.New("major Contour upgrade requires a manual chart version bump")
+
+// ParsePolicy returns the Policy with the given name.
+func ParsePolicy(name string) (Policy, error) {
+	switch p := Policy(name); p {
+	case PolicySemver, PolicyMinor:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown version policy %q, must be one of %q or %q", name, PolicySemver, PolicyMinor)
+	}
+}
+
+// nextChartVersion calculates the next chart version for a Contour upgrade from oldAppVersion to newAppVersion.
+// A Contour pre-release gives a chart pre-release, see nextPreReleaseChartVersion.
+func nextChartVersion(policy Policy, chartVersion, oldAppVersion, newAppVersion string) (string, error) {
+	oldApp, err := semver.NewVersion(oldAppVersion)
+	if err != nil {
+		return "", fmt.Errorf("invalid appVersion %q: %w", oldAppVersion, err)
+	}
+	newApp, err := semver.NewVersion(newAppVersion)
+	if err != nil {
+		return "", fmt.Errorf("invalid appVersion %q: %w", newAppVersion, err)
+	}
+
+	if newApp.Prerelease() != "" {
+		return nextPreReleaseChartVersion(policy, chartVersion, oldApp, newApp)
+	}
+
+	if policy == PolicyMinor {
+		return nextMinorVersion(chartVersion)
+	}
+
+	switch {
+	case newApp.Major() != oldApp.Major():
+		return "", fmt.Errorf("%w: Contour %s -> %s", ErrMajorUpgrade, oldAppVersion, newAppVersion)
+	case newApp.Minor() != oldApp.Minor():
+		return nextMinorVersion(chartVersion)
+	default:
+		return nextPatchVersion(chartVersion)
+	}
+}
+
+// nextPreReleaseChartVersion calculates the chart version for an upgrade to a Contour pre-release.
+// It is a pre-release of the chart version the Contour release would get, with the same pre-release
+// identifiers, such as 0.8.0-rc.1 for Contour 1.34.0-rc.1. Further pre-releases of the same Contour
+// release keep the chart version and only change the identifiers, such as 0.8.0-rc.2 for Contour 1.34.0-rc.2.
+func nextPreReleaseChartVersion(policy Policy, chartVersion string, oldApp, newApp *semver.Version) (string, error) {
+	chart, err := semver.StrictNewVersion(chartVersion)
+	if err != nil {
+		return "", fmt.Errorf("invalid version format: %s: %w", chartVersion, err)
+	}
+
+	// Compare releases, as if the pre-releases the chart is currently on were released.
+	chartRelease := semver.New(chart.Major(), chart.Minor(), chart.Patch(), "", "")
+	oldRelease := semver.New(oldApp.Major(), oldApp.Minor(), oldApp.Patch(), "", "")
+	newRelease := semver.New(newApp.Major(), newApp.Minor(), newApp.Patch(), "", "")
+
+	next := chartRelease.String()
+	if chart.Prerelease() == "" || !oldRelease.Equal(newRelease) {
+		if next, err = nextChartVersion(policy, next, oldRelease.String(), newRelease.String()); err != nil {
+			return "", err
+		}
+	}
+
+	return next + "-" + newApp.Prerelease(), nil
+}
+
+// nextMinorVersion calculates the next minor version given a version string.
+// A pre-release of a new minor version, such as 0.8.0-rc.1, is promoted to its release, 0.8.0.
+func nextMinorVersion(version string) (string, error) {
+	v, err := semver.StrictNewVersion(version)
+	if err != nil {
+		return "", fmt.Errorf("invalid version format: %s: %w", version, err)
+	}
+
+	if v.Prerelease() != "" && v.Patch() == 0 {
+		return semver.New(v.Major(), v.Minor(), 0, "", "").String(), nil
+	}
+	return v.IncMinor().String(), nil
+}
+
+// nextPatchVersion calculates the next patch version given a version string.
+// A pre-release, such as 0.7.1-rc.1, is promoted to its release, 0.7.1.
+func nextPatchVersion(version string) (string, error) {
+	v, err := semver.StrictNewVersion(version)
+	if err != nil {
+		return "", fmt.Errorf("invalid version format: %s: %w", version, err)
+	}
+
+	return v.IncPatch().String(), nil
+}
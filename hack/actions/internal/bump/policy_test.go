@@ -0,0 +1,162 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("semver")
+	require.NoError(t, err)
+	assert.Equal(t, PolicySemver, p)
+
+	p, err = ParsePolicy("minor")
+	require.NoError(t, err)
+	assert.Equal(t, PolicyMinor, p)
+
+	_, err = ParsePolicy("major")
+	require.ErrorContains(t, err, `unknown version policy "major"`)
+}
+
+func TestNextChartVersion(t *testing.T) {
+	tests := map[string]struct {
+		policy        Policy
+		chart         string
+		oldApp        string
+		newApp        string
+		want          string
+		wantErr       error
+		wantErrString string
+	}{
+		"contour patch gives chart patch": {
+			policy: PolicySemver, chart: "0.7.0", oldApp: "1.33.5", newApp: "1.33.6", want: "0.7.1",
+		},
+		"contour minor gives chart minor": {
+			policy: PolicySemver, chart: "0.7.3", oldApp: "1.33.6", newApp: "1.34.0", want: "0.8.0",
+		},
+		"contour minor skipping patches gives chart minor": {
+			policy: PolicySemver, chart: "0.7.3", oldApp: "1.32.9", newApp: "1.34.2", want: "0.8.0",
+		},
+		"contour major needs a human": {
+			policy: PolicySemver, chart: "0.7.0", oldApp: "1.33.6", newApp: "2.0.0", wantErr: ErrMajorUpgrade,
+		},
+		"minor policy bumps minor for a contour patch": {
+			policy: PolicyMinor, chart: "0.7.0", oldApp: "1.33.5", newApp: "1.33.6", want: "0.8.0",
+		},
+		"contour release candidate gives chart pre-release": {
+			policy: PolicySemver, chart: "0.7.0", oldApp: "1.33.6", newApp: "1.34.0-rc.1", want: "0.8.0-rc.1",
+		},
+		"next contour release candidate keeps chart release": {
+			policy: PolicySemver, chart: "0.8.0-rc.1", oldApp: "1.34.0-rc.1", newApp: "1.34.0-rc.2", want: "0.8.0-rc.2",
+		},
+		"contour release after its release candidates": {
+			policy: PolicySemver, chart: "0.8.0-rc.2", oldApp: "1.34.0-rc.2", newApp: "1.34.0", want: "0.8.0",
+		},
+		"minor policy contour release after its release candidates": {
+			policy: PolicyMinor, chart: "0.8.0-rc.2", oldApp: "1.34.0-rc.2", newApp: "1.34.0", want: "0.8.0",
+		},
+		"contour patch release candidate gives chart patch pre-release": {
+			policy: PolicySemver, chart: "0.7.0", oldApp: "1.33.6", newApp: "1.33.7-rc.1", want: "0.7.1-rc.1",
+		},
+		"minor policy contour patch release candidate": {
+			policy: PolicyMinor, chart: "0.7.0", oldApp: "1.33.6", newApp: "1.33.7-rc.1", want: "0.8.0-rc.1",
+		},
+		"release candidate of the next contour minor skipping a release": {
+			policy: PolicySemver, chart: "0.8.0-rc.1", oldApp: "1.34.0-rc.1", newApp: "1.35.0-rc.1", want: "0.9.0-rc.1",
+		},
+		"contour major release candidate needs a human": {
+			policy: PolicySemver, chart: "0.7.0", oldApp: "1.33.6", newApp: "2.0.0-rc.1", wantErr: ErrMajorUpgrade,
+		},
+		"invalid appVersion": {
+			policy: PolicySemver, chart: "0.7.0", oldApp: "latest", newApp: "1.33.6", wantErrString: `invalid appVersion "latest"`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := nextChartVersion(tc.policy, tc.chart, tc.oldApp, tc.newApp)
+			switch {
+			case tc.wantErr != nil:
+				require.ErrorIs(t, err, tc.wantErr)
+			case tc.wantErrString != "":
+				require.ErrorContains(t, err, tc.wantErrString)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNextMinorVersion(t *testing.T) {
+	tests := map[string]struct {
+		version       string
+		want          string
+		wantErrString string
+	}{
+		"minor is incremented":               {version: "0.7.0", want: "0.8.0"},
+		"patch is reset":                     {version: "1.2.3", want: "1.3.0"},
+		"two-digit minor":                    {version: "0.9.1", want: "0.10.0"},
+		"pre-release of minor is released":   {version: "0.8.0-rc.1", want: "0.8.0"},
+		"pre-release of patch is skipped":    {version: "0.8.1-rc.1", want: "0.9.0"},
+		"build metadata is dropped":          {version: "0.7.0+build.5", want: "0.8.0"},
+		"pre-release with build metadata":    {version: "0.8.0-rc.2+build.5", want: "0.8.0"},
+		"too few parts":                      {version: "1.2", wantErrString: "invalid version format"},
+		"non-numeric minor":                  {version: "1.x.0", wantErrString: "invalid version format"},
+		"leading v is not a chart version":   {version: "v0.7.0", wantErrString: "invalid version format"},
+		"empty version":                      {version: "", wantErrString: "invalid version format"},
+		"leading zero is not a valid semver": {version: "0.07.0", wantErrString: "invalid version format"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := nextMinorVersion(tc.version)
+			if tc.wantErrString != "" {
+				require.ErrorContains(t, err, tc.wantErrString)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestNextPatchVersion(t *testing.T) {
+	tests := map[string]struct {
+		version       string
+		want          string
+		wantErrString string
+	}{
+		"patch is incremented":      {version: "0.7.0", want: "0.7.1"},
+		"pre-release is released":   {version: "0.7.1-rc.1", want: "0.7.1"},
+		"build metadata is dropped": {version: "0.7.1+build.5", want: "0.7.2"},
+		"invalid version":           {version: "0.7", wantErrString: "invalid version format"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := nextPatchVersion(tc.version)
+			if tc.wantErrString != "" {
+				require.ErrorContains(t, err, tc.wantErrString)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
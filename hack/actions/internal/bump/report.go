@@ -0,0 +1,93 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Change is the value of a version or digest before and after a bump.
+// Old and New are equal when the value did not change.
+type Change struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// Changed reports whether the value changed.
+func (c Change) Changed() bool {
+	return c.Old != c.New
+}
+
+// Report describes the outcome of a bump in a machine-readable form.
+type Report struct {
+	// Changed is true if any file was modified.
+	Changed bool `json:"changed"`
+
+	ChartVersion  Change `json:"chartVersion"`
+	AppVersion    Change `json:"appVersion"`
+	KubeVersion   Change `json:"kubeVersion"`
+	ContourTag    Change `json:"contourTag"`
+	ContourDigest Change `json:"contourDigest"`
+	EnvoyTag      Change `json:"envoyTag"`
+	EnvoyDigest   Change `json:"envoyDigest"`
+
+	// KubernetesVersions lists the Kubernetes minor versions the Contour release is tested against,
+	// newest first. The e2e suite should run against each of them.
+	KubernetesVersions []string `json:"kubernetesVersions"`
+
+	// Files lists the files that were modified.
+	Files []string `json:"files"`
+}
+
+// newReport returns a report for the current state of the chart, with nothing changed yet.
+func newReport(chartVersion, appVersion, kubeVersion string, contour, envoy imageState) *Report {
+	return &Report{
+		ChartVersion:  Change{Old: chartVersion, New: chartVersion},
+		AppVersion:    Change{Old: appVersion, New: appVersion},
+		KubeVersion:   Change{Old: kubeVersion, New: kubeVersion},
+		ContourTag:    Change{Old: contour.Tag, New: contour.Tag},
+		ContourDigest: Change{Old: contour.Digest, New: contour.Digest},
+		EnvoyTag:      Change{Old: envoy.Tag, New: envoy.Tag},
+		EnvoyDigest:   Change{Old: envoy.Digest, New: envoy.Digest},
+
+		KubernetesVersions: []string{},
+		Files:              []string{},
+	}
+}
+
+// touched records that the file was modified.
+func (r *Report) touched(path string) {
+	r.Changed = true
+	for _, f := range r.Files {
+		if f == path {
+			return
+		}
+	}
+	r.Files = append(r.Files, path)
+}
+
+// Reports are the reports of the bumps of several charts, keyed by chart name.
+type Reports map[string]*Report
+
+// WriteJSON writes the reports as indented JSON.
+func (r Reports) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	return nil
+}
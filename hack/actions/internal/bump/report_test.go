@@ -0,0 +1,45 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportsWriteJSON(t *testing.T) {
+	r := newReport("0.7.0", "1.33.6", ">=1.31.0-0", imageState{Tag: "v1.33.6"}, imageState{Tag: "v1.35.8", Digest: "sha256:0123"})
+	r.ChartVersion.New = "0.7.1"
+	r.KubernetesVersions = []string{"1.33", "1.32", "1.31"}
+	r.touched("Chart.yaml")
+	r.touched("Chart.yaml")
+
+	var buf bytes.Buffer
+	require.NoError(t, Reports{"contour": r}.WriteJSON(&buf))
+	assert.JSONEq(t, `{"contour": {
+		"changed": true,
+		"chartVersion": {"old": "0.7.0", "new": "0.7.1"},
+		"appVersion": {"old": "1.33.6", "new": "1.33.6"},
+		"kubeVersion": {"old": ">=1.31.0-0", "new": ">=1.31.0-0"},
+		"contourTag": {"old": "v1.33.6", "new": "v1.33.6"},
+		"contourDigest": {"old": "", "new": ""},
+		"envoyTag": {"old": "v1.35.8", "new": "v1.35.8"},
+		"envoyDigest": {"old": "sha256:0123", "new": "sha256:0123"},
+		"kubernetesVersions": ["1.33", "1.32", "1.31"],
+		"files": ["Chart.yaml"]
+	}}`, buf.String())
+}
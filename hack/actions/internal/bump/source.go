@@ -0,0 +1,109 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/fetch"
+)
+
+// DefaultVersionsURL is the location of the upstream Contour versions.yaml.
+const DefaultVersionsURL = "https://raw.githubusercontent.com/projectcontour/contour/refs/heads/main/versions.yaml"
+
+// VersionSource provides the contents of a Contour versions.yaml file.
+type VersionSource interface {
+	// Open returns a reader for versions.yaml. The caller must close it.
+	Open(ctx context.Context) (io.ReadCloser, error)
+
+	// String describes the source for logging.
+	String() string
+}
+
+// NewVersionSource returns a VersionSource for the given location.
+// Locations starting with http:// or https:// are fetched over HTTP,
+// "-" reads from stdin and anything else is treated as a local file path.
+func NewVersionSource(location string) VersionSource {
+	switch {
+	case strings.HasPrefix(location, "http://"), strings.HasPrefix(location, "https://"):
+		return &URLSource{URL: location}
+	case location == "-":
+		return &ReaderSource{Name: "stdin", Reader: os.Stdin}
+	default:
+		return &FileSource{Path: location}
+	}
+}
+
+// URLSource fetches versions.yaml over HTTP.
+type URLSource struct {
+	URL string
+
+	// Fetcher downloads versions.yaml. If nil, a Fetcher without cache using http.DefaultClient is used.
+	Fetcher *fetch.Fetcher
+}
+
+// Open fetches versions.yaml from the URL.
+func (s *URLSource) Open(ctx context.Context) (io.ReadCloser, error) {
+	fetcher := s.Fetcher
+	if fetcher == nil {
+		fetcher = &fetch.Fetcher{}
+	}
+
+	r, err := fetcher.Open(ctx, s.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch versions.yaml: %w", err)
+	}
+	return r, nil
+}
+
+func (s *URLSource) String() string {
+	return s.URL
+}
+
+// FileSource reads versions.yaml from a local file.
+type FileSource struct {
+	Path string
+}
+
+// Open opens the local file.
+func (s *FileSource) Open(_ context.Context) (io.ReadCloser, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", s.Path, err)
+	}
+	return f, nil
+}
+
+func (s *FileSource) String() string {
+	return s.Path
+}
+
+// ReaderSource reads versions.yaml from an already open reader, such as stdin.
+type ReaderSource struct {
+	Name   string
+	Reader io.Reader
+}
+
+// Open returns the underlying reader. Closing it is a no-op.
+func (s *ReaderSource) Open(_ context.Context) (io.ReadCloser, error) {
+	return io.NopCloser(s.Reader), nil
+}
+
+func (s *ReaderSource) String() string {
+	return s.Name
+}
@@ -0,0 +1,57 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewVersionSource(t *testing.T) {
+	assert.IsType(t, &URLSource{}, NewVersionSource(DefaultVersionsURL))
+	assert.IsType(t, &URLSource{}, NewVersionSource("http://localhost:8080/versions.yaml"))
+	assert.IsType(t, &ReaderSource{}, NewVersionSource("-"))
+	assert.IsType(t, &FileSource{}, NewVersionSource("testdata/versions.yaml"))
+}
+
+func TestFileSource(t *testing.T) {
+	src := NewVersionSource("testdata/versions.yaml")
+
+	latest, err := getLatestRelease(context.Background(), src, "", false)
+	require.NoError(t, err)
+	assert.Equal(t, "1.33.10", latest.Contour)
+	assert.Equal(t, "1.35.9", latest.Envoy)
+
+	_, err = NewVersionSource(filepath.Join(t.TempDir(), "missing.yaml")).Open(context.Background())
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReaderSource(t *testing.T) {
+	src := &ReaderSource{Name: "stdin", Reader: strings.NewReader("versions: []\n")}
+	assert.Equal(t, "stdin", src.String())
+
+	r, err := src.Open(context.Background())
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "versions: []\n", string(data))
+}
@@ -0,0 +1,235 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// versionEntry is a single release in Contour's versions.yaml.
+type versionEntry struct {
+	Version      string `yaml:"version"`
+	Supported    string `yaml:"supported"`
+	Dependencies struct {
+		Envoy      string   `yaml:"envoy"`
+		Kubernetes []string `yaml:"kubernetes"`
+	} `yaml:"dependencies"`
+}
+
+// versionsFile is the top-level structure of Contour's versions.yaml.
+type versionsFile struct {
+	Versions []versionEntry `yaml:"versions"`
+}
+
+// release is a Contour release picked from versions.yaml, with its dependencies.
+type release struct {
+	// Contour is the Contour version, without a leading v.
+	Contour string
+
+	// Envoy is the Envoy version Contour is released with.
+	Envoy string
+
+	// Kubernetes lists the Kubernetes minor versions Contour is tested against, newest first.
+	Kubernetes []string
+}
+
+// readVersions reads and decodes versions.yaml from the given source.
+func readVersions(ctx context.Context, src VersionSource) (*versionsFile, error) {
+	r, err := src.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var versions versionsFile
+	if err := yaml.NewDecoder(r).Decode(&versions); err != nil {
+		return nil, fmt.Errorf("failed to decode versions.yaml from %s: %w", src, err)
+	}
+
+	return &versions, nil
+}
+
+// Channel decides which kinds of Contour releases a bump moves to.
+type Channel string
+
+const (
+	// ChannelStable only moves to Contour releases.
+	ChannelStable Channel = "stable"
+
+	// ChannelRC also moves to Contour pre-releases, such as release candidates,
+	// which give a pre-release chart version such as 0.8.0-rc.1.
+	ChannelRC Channel = "rc"
+)
+
+// ParseChannel returns the Channel with the given name.
+func ParseChannel(name string) (Channel, error) {
+	switch c := Channel(name); c {
+	case ChannelStable, ChannelRC:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown release channel %q, must be one of %q or %q", name, ChannelStable, ChannelRC)
+	}
+}
+
+// getLatestRelease returns the highest supported Contour release and its dependencies.
+// Pre-releases are ignored unless preReleases is set, main is always ignored, and the order
+// of entries in versions.yaml does not matter.
+// If minor is set, such as "1.32", only releases of that minor version are considered.
+func getLatestRelease(ctx context.Context, src VersionSource, minor string, preReleases bool) (*release, error) {
+	var track *semver.Version
+	if minor != "" {
+		var err error
+		if track, err = parseMinor(minor); err != nil {
+			return nil, err
+		}
+	}
+
+	versions, err := readVersions(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *semver.Version
+	var latestEntry versionEntry
+	for _, entry := range versions.Versions {
+		// Skip "main" version.
+		if entry.Version == "main" {
+			continue
+		}
+
+		if entry.Supported != "true" {
+			continue
+		}
+
+		v, err := semver.StrictNewVersion(strings.TrimPrefix(entry.Version, "v"))
+		if err != nil {
+			log.Warnf("Skipping version %q: %v", entry.Version, err)
+			continue
+		}
+
+		// Skip pre-releases such as release candidates.
+		if v.Prerelease() != "" && !preReleases {
+			continue
+		}
+
+		// Skip other minor versions when tracking a maintenance release line.
+		if track != nil && (v.Major() != track.Major() || v.Minor() != track.Minor()) {
+			continue
+		}
+
+		// Pick the highest supported version regardless of the order in versions.yaml.
+		if latest == nil || v.GreaterThan(latest) {
+			latest = v
+			latestEntry = entry
+		}
+	}
+
+	if latest == nil {
+		if track != nil {
+			return nil, fmt.Errorf("no supported versions found for Contour %d.%d", track.Major(), track.Minor())
+		}
+		return nil, fmt.Errorf("no supported versions found")
+	}
+
+	return newRelease(latest, latestEntry)
+}
+
+// getRelease returns the Contour release with the given version, such as "1.34.0-rc.1", and its dependencies.
+// It fails if versions.yaml does not list the version.
+func getRelease(ctx context.Context, src VersionSource, version string) (*release, error) {
+	want, err := semver.StrictNewVersion(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Contour version %q: %w", version, err)
+	}
+
+	versions, err := readVersions(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range versions.Versions {
+		v, err := semver.StrictNewVersion(strings.TrimPrefix(entry.Version, "v"))
+		if err != nil || !v.Equal(want) {
+			continue
+		}
+
+		if entry.Supported != "true" {
+			log.Warnf("Contour %s is not supported according to %s", want, src)
+		}
+		return newRelease(v, entry)
+	}
+
+	return nil, fmt.Errorf("contour version %s not found in %s", want, src)
+}
+
+// newRelease returns the release for the versions.yaml entry of version v.
+func newRelease(v *semver.Version, entry versionEntry) (*release, error) {
+	if entry.Dependencies.Envoy == "" {
+		return nil, fmt.Errorf("no Envoy version found for Contour %s", v)
+	}
+
+	kubernetes, err := kubernetesVersions(entry.Dependencies.Kubernetes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dependencies of %s: %w", entry.Version, err)
+	}
+
+	return &release{
+		Contour:    v.String(),
+		Envoy:      entry.Dependencies.Envoy,
+		Kubernetes: kubernetes,
+	}, nil
+}
+
+// parseMinor parses a minor version such as "1.32", "v1.32" or "1.32.x".
+func parseMinor(minor string) (*semver.Version, error) {
+	s := strings.TrimSuffix(strings.TrimPrefix(minor, "v"), ".x")
+	if strings.Count(s, ".") != 1 {
+		return nil, fmt.Errorf("invalid minor version %q, expected MAJOR.MINOR", minor)
+	}
+
+	v, err := semver.StrictNewVersion(s + ".0")
+	if err != nil {
+		return nil, fmt.Errorf("invalid minor version %q: %w", minor, err)
+	}
+	return v, nil
+}
+
+// isUpToDate reports whether the current appVersion is already the latest version.
+// It returns an error if the latest version is lower than the current one, unless the current one
+// is a pre-release, such as after an rc channel run, which is kept until a newer release.
+func isUpToDate(currentVersion, latestVersion string) (bool, error) {
+	current, err := semver.NewVersion(currentVersion)
+	if err != nil {
+		return false, fmt.Errorf("invalid current version %q: %w", currentVersion, err)
+	}
+	latest, err := semver.NewVersion(latestVersion)
+	if err != nil {
+		return false, fmt.Errorf("invalid latest version %q: %w", latestVersion, err)
+	}
+
+	if latest.LessThan(current) {
+		if current.Prerelease() != "" && latest.Prerelease() == "" {
+			log.Infof("Keeping pre-release appVersion %s, latest release %s is older", currentVersion, latestVersion)
+			return true, nil
+		}
+		return false, fmt.Errorf("refusing to downgrade appVersion from %s to %s", currentVersion, latestVersion)
+	}
+
+	return latest.Equal(current), nil
+}
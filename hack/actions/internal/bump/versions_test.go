@@ -0,0 +1,301 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/fetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newVersionsServer returns a server that stands in for raw.githubusercontent.com
+// and serves the given versions.yaml body.
+func newVersionsServer(t *testing.T, status int, body string) *URLSource {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	return &URLSource{URL: srv.URL + "/versions.yaml", Fetcher: &fetch.Fetcher{Client: srv.Client()}}
+}
+
+func TestGetLatestRelease(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/versions.yaml")
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		body           string
+		status         int
+		minor          string
+		preReleases    bool
+		wantContour    string
+		wantEnvoy      string
+		wantKubernetes []string
+		wantErrString  string
+	}{
+		"unordered versions.yaml picks the highest supported release": {
+			body:           string(fixture),
+			status:         http.StatusOK,
+			wantContour:    "1.33.10",
+			wantEnvoy:      "1.35.9",
+			wantKubernetes: []string{"1.33", "1.32", "1.31"},
+		},
+		"maintenance release line picks the newest patch of the minor": {
+			body:           string(fixture),
+			status:         http.StatusOK,
+			minor:          "1.32",
+			wantContour:    "1.32.2",
+			wantEnvoy:      "1.34.10",
+			wantKubernetes: []string{"1.32", "1.31", "1.30"},
+		},
+		"maintenance release line ignores pre-releases": {
+			body:          string(fixture),
+			status:        http.StatusOK,
+			minor:         "v1.34.x",
+			wantErrString: "no supported versions found for Contour 1.34",
+		},
+		"release candidate channel picks the highest pre-release": {
+			body:        string(fixture),
+			status:      http.StatusOK,
+			preReleases: true,
+			wantContour: "1.34.0-rc.1",
+			wantEnvoy:   "1.36.0",
+		},
+		"release candidate channel picks a release over its pre-releases": {
+			body: `versions:
+- version: v1.34.0-rc.2
+  supported: "true"
+  dependencies:
+    envoy: 1.36.0
+- version: v1.34.0
+  supported: "true"
+  dependencies:
+    envoy: 1.36.1
+`,
+			status:      http.StatusOK,
+			preReleases: true,
+			wantContour: "1.34.0",
+			wantEnvoy:   "1.36.1",
+		},
+		"maintenance release line on the release candidate channel": {
+			body:        string(fixture),
+			status:      http.StatusOK,
+			minor:       "1.34",
+			preReleases: true,
+			wantContour: "1.34.0-rc.1",
+			wantEnvoy:   "1.36.0",
+		},
+		"missing Envoy dependency": {
+			body: `versions:
+- version: v1.33.0
+  supported: "true"
+`,
+			status:        http.StatusOK,
+			wantErrString: "no Envoy version found for Contour 1.33.0",
+		},
+		"maintenance release line of unsupported minor": {
+			body:          string(fixture),
+			status:        http.StatusOK,
+			minor:         "1.35",
+			wantErrString: "no supported versions found for Contour 1.35",
+		},
+		"invalid maintenance release line": {
+			body:          string(fixture),
+			status:        http.StatusOK,
+			minor:         "1.32.2",
+			wantErrString: `invalid minor version "1.32.2"`,
+		},
+		"newest-first versions.yaml": {
+			body: `versions:
+- version: main
+  supported: "false"
+- version: v1.33.1
+  supported: "true"
+  dependencies:
+    envoy: 1.35.2
+- version: v1.33.0
+  supported: "true"
+  dependencies:
+    envoy: 1.35.1
+`,
+			status:      http.StatusOK,
+			wantContour: "1.33.1",
+			wantEnvoy:   "1.35.2",
+		},
+		"Kubernetes versions are sorted and deduplicated": {
+			body: `versions:
+- version: v1.33.0
+  supported: "true"
+  dependencies:
+    envoy: 1.35.1
+    kubernetes: ["1.31", "1.33", "v1.32", "1.33"]
+`,
+			status:         http.StatusOK,
+			wantContour:    "1.33.0",
+			wantEnvoy:      "1.35.1",
+			wantKubernetes: []string{"1.33", "1.32", "1.31"},
+		},
+		"invalid Kubernetes version": {
+			body: `versions:
+- version: v1.33.0
+  supported: "true"
+  dependencies:
+    envoy: 1.35.1
+    kubernetes: ["1.33.1"]
+`,
+			status:        http.StatusOK,
+			wantErrString: `invalid dependencies of v1.33.0: invalid Kubernetes version: invalid minor version "1.33.1"`,
+		},
+		"unparsable versions are skipped": {
+			body: `versions:
+- version: latest
+  supported: "true"
+- version: v1.30.0
+  supported: "true"
+  dependencies:
+    envoy: 1.31.0
+`,
+			status:      http.StatusOK,
+			wantContour: "1.30.0",
+			wantEnvoy:   "1.31.0",
+		},
+		"only pre-releases and main": {
+			body: `versions:
+- version: main
+  supported: "true"
+- version: v1.34.0-rc.1
+  supported: "true"
+`,
+			status:        http.StatusOK,
+			wantErrString: "no supported versions found",
+		},
+		"server error": {
+			status:        http.StatusServiceUnavailable,
+			wantErrString: "status code 503",
+		},
+		"invalid yaml": {
+			body:          "versions: [",
+			status:        http.StatusOK,
+			wantErrString: "failed to decode versions.yaml",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			src := newVersionsServer(t, tc.status, tc.body)
+
+			latest, err := getLatestRelease(context.Background(), src, tc.minor, tc.preReleases)
+			if tc.wantErrString != "" {
+				require.ErrorContains(t, err, tc.wantErrString)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantContour, latest.Contour)
+			assert.Equal(t, tc.wantEnvoy, latest.Envoy)
+			if tc.wantKubernetes == nil {
+				tc.wantKubernetes = []string{}
+			}
+			assert.Equal(t, tc.wantKubernetes, latest.Kubernetes)
+		})
+	}
+}
+
+func TestGetRelease(t *testing.T) {
+	src := NewVersionSource("testdata/versions.yaml")
+
+	tests := map[string]struct {
+		version       string
+		wantContour   string
+		wantEnvoy     string
+		wantErrString string
+	}{
+		"older release":             {version: "1.33.6", wantContour: "1.33.6", wantEnvoy: "1.35.8"},
+		"leading v":                 {version: "v1.32.2", wantContour: "1.32.2", wantEnvoy: "1.34.10"},
+		"release candidate":         {version: "1.34.0-rc.1", wantContour: "1.34.0-rc.1", wantEnvoy: "1.36.0"},
+		"unsupported release":       {version: "1.35.0", wantContour: "1.35.0", wantEnvoy: "1.36.1"},
+		"missing release":           {version: "1.33.7", wantErrString: "contour version 1.33.7 not found in testdata/versions.yaml"},
+		"main is not a version":     {version: "main", wantErrString: `invalid Contour version "main"`},
+		"minor is not a version":    {version: "1.33", wantErrString: `invalid Contour version "1.33"`},
+		"release candidate typo":    {version: "1.34.0-rc1", wantErrString: "contour version 1.34.0-rc1 not found"},
+		"build metadata is ignored": {version: "1.33.6+build.1", wantContour: "1.33.6", wantEnvoy: "1.35.8"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := getRelease(context.Background(), src, tc.version)
+			if tc.wantErrString != "" {
+				require.ErrorContains(t, err, tc.wantErrString)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantContour, got.Contour)
+			assert.Equal(t, tc.wantEnvoy, got.Envoy)
+		})
+	}
+}
+
+func TestParseChannel(t *testing.T) {
+	c, err := ParseChannel("stable")
+	require.NoError(t, err)
+	assert.Equal(t, ChannelStable, c)
+
+	c, err = ParseChannel("rc")
+	require.NoError(t, err)
+	assert.Equal(t, ChannelRC, c)
+
+	_, err = ParseChannel("beta")
+	require.ErrorContains(t, err, `unknown release channel "beta"`)
+}
+
+func TestIsUpToDate(t *testing.T) {
+	tests := map[string]struct {
+		current       string
+		latest        string
+		want          bool
+		wantErrString string
+	}{
+		"same version":             {current: "1.33.6", latest: "1.33.6", want: true},
+		"newer patch":              {current: "1.33.6", latest: "1.33.7", want: false},
+		"newer minor":              {current: "1.33.6", latest: "1.34.0", want: false},
+		"numeric not lexical":      {current: "1.33.9", latest: "1.33.10", want: false},
+		"downgrade is refused":     {current: "1.33.6", latest: "1.32.9", wantErrString: "refusing to downgrade"},
+		"invalid current version":  {current: "foo", latest: "1.33.6", wantErrString: "invalid current version"},
+		"invalid latest version":   {current: "1.33.6", latest: "", wantErrString: "invalid latest version"},
+		"leading v is tolerated":   {current: "v1.33.6", latest: "1.33.6", want: true},
+		"pre-release is not newer": {current: "1.34.0", latest: "1.34.0-rc.1", wantErrString: "refusing to downgrade"},
+		"pre-release is kept":      {current: "1.34.0-rc.1", latest: "1.33.7", want: true},
+		"older pre-release":        {current: "1.34.0-rc.2", latest: "1.34.0-rc.1", wantErrString: "refusing to downgrade"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := isUpToDate(tc.current, tc.latest)
+			if tc.wantErrString != "" {
+				require.ErrorContains(t, err, tc.wantErrString)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
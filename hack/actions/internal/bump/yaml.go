@@ -0,0 +1,24 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"github.com/projectcontour/helm-charts/hack/actions/internal/yamledit"
+)
+
+// setYAMLField updates a specific field in a YAML file.
+// Only the bytes of the field's value are rewritten, the rest of the file is left as is.
+func setYAMLField(filePath, fieldPath, newValue string) error {
+	return yamledit.SetFile(filePath, fieldPath, newValue)
+}
@@ -0,0 +1,153 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bump
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSetYAMLField(t *testing.T) {
+	const values = `contour:
+  image:
+    registry: ghcr.io
+    repository: projectcontour/contour
+    tag: v1.33.6
+envoy:
+  image:
+    tag: v1.35.8
+`
+
+	valuesPath := filepath.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(valuesPath, []byte(values), 0o600))
+
+	require.NoError(t, setYAMLField(valuesPath, "contour.image.tag", "v1.33.7"))
+	require.NoError(t, setYAMLField(valuesPath, "envoy.image.tag", "v1.35.9"))
+
+	data, err := os.ReadFile(valuesPath)
+	require.NoError(t, err)
+
+	var got struct {
+		Contour struct {
+			Image struct {
+				Registry   string `yaml:"registry"`
+				Repository string `yaml:"repository"`
+				Tag        string `yaml:"tag"`
+			} `yaml:"image"`
+		} `yaml:"contour"`
+		Envoy struct {
+			Image struct {
+				Tag string `yaml:"tag"`
+			} `yaml:"image"`
+		} `yaml:"envoy"`
+	}
+	require.NoError(t, yaml.Unmarshal(data, &got))
+	assert.Equal(t, "ghcr.io", got.Contour.Image.Registry)
+	assert.Equal(t, "projectcontour/contour", got.Contour.Image.Repository)
+	assert.Equal(t, "v1.33.7", got.Contour.Image.Tag)
+	assert.Equal(t, "v1.35.9", got.Envoy.Image.Tag)
+}
+
+func TestSetYAMLFieldErrors(t *testing.T) {
+	valuesPath := filepath.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(valuesPath, []byte("contour:\n  image: ghcr.io/projectcontour/contour\n"), 0o600))
+
+	require.ErrorContains(t, setYAMLField(valuesPath, "envoy.image.tag", "v1"), "field envoy not found")
+	require.ErrorContains(t, setYAMLField(valuesPath, "contour.image.tag", "v1"), "expected mapping node")
+	require.ErrorContains(t, setYAMLField(filepath.Join(t.TempDir(), "missing.yaml"), "version", "v1"), "failed to read file")
+}
+
+// TestSetYAMLFieldChartFiles round-trips the real chart files to make sure a version
+// bump only touches the lines of the fields it changes.
+func TestSetYAMLFieldChartFiles(t *testing.T) {
+	tests := map[string]struct {
+		file      string
+		fields    map[string]string
+		wantLines []string
+	}{
+		"Chart.yaml": {
+			file: "../../../../charts/contour/Chart.yaml",
+			fields: map[string]string{
+				"version":    "99.1.0",
+				"appVersion": "99.2.3",
+			},
+			wantLines: []string{"version: 99.1.0", "appVersion: 99.2.3"},
+		},
+		"values.yaml": {
+			file: "../../../../charts/contour/values.yaml",
+			fields: map[string]string{
+				"contour.image.tag":    "v99.2.3",
+				"contour.image.digest": "sha256:0123",
+				"envoy.image.tag":      "v99.4.5",
+			},
+			// Quoting of each field is kept as it was.
+			wantLines: []string{"tag: v99.2.3", `digest: "sha256:0123"`, "tag: v99.4.5"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			original, err := os.ReadFile(tc.file)
+			require.NoError(t, err)
+
+			path := filepath.Join(t.TempDir(), name)
+			require.NoError(t, os.WriteFile(path, original, 0o600))
+
+			// Writing the current values back must not change a single byte.
+			var current map[string]any
+			require.NoError(t, yaml.Unmarshal(original, &current))
+			for field := range tc.fields {
+				require.NoError(t, setYAMLField(path, field, lookupString(t, current, field)))
+			}
+			assert.Equal(t, string(original), readFile(t, path))
+
+			// Writing new values must only change the lines of those fields.
+			for field, value := range tc.fields {
+				require.NoError(t, setYAMLField(path, field, value))
+			}
+			before := strings.Split(string(original), "\n")
+			after := strings.Split(readFile(t, path), "\n")
+			require.Len(t, after, len(before))
+
+			var changed []string
+			for i := range before {
+				if before[i] != after[i] {
+					changed = append(changed, strings.TrimSpace(after[i]))
+				}
+			}
+			assert.ElementsMatch(t, tc.wantLines, changed)
+		})
+	}
+}
+
+// lookupString returns the string at the dot-separated path in the decoded YAML.
+func lookupString(t *testing.T, m map[string]any, path string) string {
+	t.Helper()
+
+	parts := strings.Split(path, ".")
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := m[p].(map[string]any)
+		require.True(t, ok, "field %s not found", p)
+		m = next
+	}
+	s, ok := m[parts[len(parts)-1]].(string)
+	require.True(t, ok, "field %s is not a string", path)
+	return s
+}
@@ -0,0 +1,221 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package charts discovers the charts in the repository that are maintained by the hack tools,
+// and the upstream component, images, CRDs, RBAC rules and configuration each of them tracks.
+package charts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/bump"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/contourconfig"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/rbac"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+var log = logrus.StandardLogger()
+
+// DefaultDir is the directory holding the charts of the repository.
+const DefaultDir = "./charts"
+
+// ConfigFile is the name of the per-chart config file, next to Chart.yaml.
+// Charts without one are not maintained by the hack tools.
+const ConfigFile = "upstream.yaml"
+
+// ComponentContour tracks Contour releases, as listed in Contour's versions.yaml.
+const ComponentContour = "contour"
+
+// Config is the content of a chart's ConfigFile.
+type Config struct {
+	// Component is the upstream component whose releases the chart appVersion follows.
+	Component string `yaml:"component"`
+
+	// Images locates the images of the component in values.yaml.
+	Images bump.Images `yaml:"images"`
+
+	// CRDs lists the CRD files copied from the component source into the chart.
+	CRDs []crds.File `yaml:"crds"`
+
+	// RBAC lists the role manifests of the component source whose rules the chart grants.
+	RBAC []rbac.File `yaml:"rbac"`
+
+	// Configuration maps the configuration file of the component to the chart value it is set in.
+	Configuration *contourconfig.File `yaml:"configuration"`
+}
+
+// Chart is a chart maintained by the hack tools.
+type Chart struct {
+	// Name is the name of the chart directory, such as contour.
+	Name string
+
+	// Dir is the chart directory.
+	Dir string
+
+	// Config is the chart's config file.
+	Config Config
+}
+
+// ChartPath returns the path to the chart's Chart.yaml.
+func (c *Chart) ChartPath() string {
+	return filepath.Join(c.Dir, "Chart.yaml")
+}
+
+// ValuesPath returns the path to the chart's values.yaml.
+func (c *Chart) ValuesPath() string {
+	return filepath.Join(c.Dir, "values.yaml")
+}
+
+// ChangelogPath returns the path to the chart's CHANGELOG.md, or an empty string if the chart has no changelog.
+func (c *Chart) ChangelogPath() string {
+	path := filepath.Join(c.Dir, "CHANGELOG.md")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// Bump returns opts with the paths and images of the chart filled in.
+func (c *Chart) Bump(opts bump.Options) bump.Options {
+	opts.ChartPath = c.ChartPath()
+	opts.ValuesPath = c.ValuesPath()
+	opts.ChangelogPath = c.ChangelogPath()
+	opts.Images = c.Config.Images
+	return opts
+}
+
+// CRDs returns opts with the path and CRD files of the chart filled in.
+func (c *Chart) CRDs(opts crds.Options) crds.Options {
+	opts.ChartPath = c.ChartPath()
+	opts.Files = c.Config.CRDs
+	return opts
+}
+
+// RBAC returns opts with the path and role files of the chart filled in.
+func (c *Chart) RBAC(opts rbac.Options) rbac.Options {
+	opts.ChartPath = c.ChartPath()
+	opts.Files = c.Config.RBAC
+	return opts
+}
+
+// Configuration returns opts with the path and configuration file of the chart filled in.
+func (c *Chart) Configuration(opts contourconfig.Options) contourconfig.Options {
+	opts.ChartPath = c.ChartPath()
+	opts.File = c.Config.Configuration
+	return opts
+}
+
+// Discover returns the charts in dir that have a config file, ordered by name.
+// If names are given, only those charts are returned, and all of them must exist.
+func Discover(dir string, names ...string) ([]*Chart, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read charts directory %s: %w", dir, err)
+	}
+
+	var charts []*Chart
+	for _, e := range entries {
+		if !e.IsDir() || (len(names) > 0 && !slices.Contains(names, e.Name())) {
+			continue
+		}
+
+		chartDir := filepath.Join(dir, e.Name())
+		if _, err := os.Stat(filepath.Join(chartDir, "Chart.yaml")); err != nil {
+			continue
+		}
+
+		c, err := Load(chartDir)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Infof("Skipping chart %s without %s", e.Name(), ConfigFile)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		charts = append(charts, c)
+	}
+
+	for _, name := range names {
+		if !slices.ContainsFunc(charts, func(c *Chart) bool { return c.Name == name }) {
+			return nil, fmt.Errorf("chart %s not found in %s or has no %s", name, dir, ConfigFile)
+		}
+	}
+	if len(charts) == 0 {
+		return nil, fmt.Errorf("no charts with config file %s found in %s", ConfigFile, dir)
+	}
+
+	return charts, nil
+}
+
+// ParseNames parses a comma-separated list of chart names, such as contour,contour-crds.
+// An empty string yields no names.
+func ParseNames(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Load reads the config file of the chart in dir.
+func Load(dir string) (*Chart, error) {
+	path := filepath.Join(dir, ConfigFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+
+	var config Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal yaml from %s: %w", path, err)
+	}
+
+	if config.Component != ComponentContour {
+		return nil, fmt.Errorf("invalid component %q in %s, only %q is supported", config.Component, path, ComponentContour)
+	}
+	for _, c := range config.CRDs {
+		if c.Name == "" || c.Source == "" || c.Destination == "" {
+			return nil, fmt.Errorf("invalid CRDs in %s: name, source and destination are required", path)
+		}
+		if c.Release != "" && c.Release != crds.ReleaseGatewayAPI {
+			return nil, fmt.Errorf("invalid %s CRDs in %s: unknown release %q, only %q is supported", c.Name, path, c.Release, crds.ReleaseGatewayAPI)
+		}
+		if c.Toggles != "" && !c.Split {
+			return nil, fmt.Errorf("invalid %s CRDs in %s: toggles require split", c.Name, path)
+		}
+	}
+
+	for _, r := range config.RBAC {
+		if r.Name == "" || r.Source == "" || r.Destination == "" {
+			return nil, fmt.Errorf("invalid RBAC rules in %s: name, source and destination are required", path)
+		}
+	}
+	if c := config.Configuration; c != nil && (c.Package == "" || c.Type == "" || c.Example == "" || c.Value == "" || c.Schema == "" || c.Reference == "") {
+		return nil, fmt.Errorf("invalid configuration in %s: package, type, example, value, schema and reference are required", path)
+	}
+
+	return &Chart{Name: filepath.Base(dir), Dir: dir, Config: config}, nil
+}
@@ -0,0 +1,295 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package charts
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/bump"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/contourconfig"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds/crdstest"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/fetch"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/rbac"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const testConfig = `component: contour
+images:
+  contour: contour.image
+  envoy: envoy.image
+crds:
+  - name: Contour
+    source: examples/contour/01-crds.yaml
+    destination: templates/crds/contour-crds.yaml
+    conditional: .Values.contour.manageCRDs
+rbac:
+  - name: Contour
+    source: examples/contour/02-role-contour.yaml
+    destination: templates/contour/rbac.yaml
+configuration:
+  package: pkg/config
+  type: Parameters
+  example: examples/contour/01-contour-config.yaml
+  value: configInline
+  schema: values.schema.json
+  reference: config-reference.yaml
+`
+
+// writeCharts writes charts with the given config files into a temporary directory.
+// An empty config writes a chart without a config file.
+func writeCharts(t *testing.T, configs map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, config := range configs {
+		chartDir := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(chartDir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("name: "+name+"\n"), 0o600))
+		if config != "" {
+			require.NoError(t, os.WriteFile(filepath.Join(chartDir, ConfigFile), []byte(config), 0o600))
+		}
+	}
+	return dir
+}
+
+func TestDiscover(t *testing.T) {
+	dir := writeCharts(t, map[string]string{
+		"contour":             testConfig,
+		"contour-crds":        "component: contour\ncrds:\n  - name: Contour\n    source: examples/contour/01-crds.yaml\n    destination: templates/contour-crds.yaml\n",
+		"gateway-provisioner": "component: contour\nimages:\n  contour: image\n",
+		"unmanaged":           "",
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "not-a-chart"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "contour", "CHANGELOG.md"), []byte("# Changelog\n"), 0o600))
+
+	charts, err := Discover(dir)
+	require.NoError(t, err)
+	require.Len(t, charts, 3)
+	assert.Equal(t, "contour", charts[0].Name)
+	assert.Equal(t, "contour-crds", charts[1].Name)
+	assert.Equal(t, "gateway-provisioner", charts[2].Name)
+
+	assert.Equal(t, Config{
+		Component: ComponentContour,
+		Images:    bump.Images{Contour: "contour.image", Envoy: "envoy.image"},
+		CRDs: []crds.File{{
+			Name:        "Contour",
+			Source:      "examples/contour/01-crds.yaml",
+			Destination: "templates/crds/contour-crds.yaml",
+			Conditional: ".Values.contour.manageCRDs",
+		}},
+		RBAC: []rbac.File{{
+			Name:        "Contour",
+			Source:      "examples/contour/02-role-contour.yaml",
+			Destination: "templates/contour/rbac.yaml",
+		}},
+		Configuration: &contourconfig.File{
+			Package:   "pkg/config",
+			Type:      "Parameters",
+			Example:   "examples/contour/01-contour-config.yaml",
+			Value:     "configInline",
+			Schema:    "values.schema.json",
+			Reference: "config-reference.yaml",
+		},
+	}, charts[0].Config)
+	assert.Equal(t, bump.Images{Contour: "image"}, charts[2].Config.Images)
+
+	opts := charts[0].Bump(bump.Options{Policy: bump.PolicyMinor})
+	assert.Equal(t, filepath.Join(dir, "contour", "Chart.yaml"), opts.ChartPath)
+	assert.Equal(t, filepath.Join(dir, "contour", "values.yaml"), opts.ValuesPath)
+	assert.Equal(t, filepath.Join(dir, "contour", "CHANGELOG.md"), opts.ChangelogPath)
+	assert.Equal(t, charts[0].Config.Images, opts.Images)
+	assert.Equal(t, bump.PolicyMinor, opts.Policy)
+
+	// Charts without a changelog skip changelog updates.
+	assert.Empty(t, charts[1].Bump(bump.Options{}).ChangelogPath)
+
+	source := &crds.DirSource{Path: "contour"}
+	crdOpts := charts[1].CRDs(crds.Options{Source: source})
+	assert.Equal(t, filepath.Join(dir, "contour-crds", "Chart.yaml"), crdOpts.ChartPath)
+	assert.Equal(t, charts[1].Config.CRDs, crdOpts.Files)
+	assert.Same(t, source, crdOpts.Source)
+
+	rbacOpts := charts[0].RBAC(rbac.Options{Source: source, Check: true})
+	assert.Equal(t, filepath.Join(dir, "contour", "Chart.yaml"), rbacOpts.ChartPath)
+	assert.Equal(t, charts[0].Config.RBAC, rbacOpts.Files)
+	assert.Same(t, source, rbacOpts.Source)
+	assert.True(t, rbacOpts.Check)
+
+	configOpts := charts[0].Configuration(contourconfig.Options{Source: source})
+	assert.Equal(t, filepath.Join(dir, "contour", "Chart.yaml"), configOpts.ChartPath)
+	assert.Same(t, charts[0].Config.Configuration, configOpts.File)
+	assert.Same(t, source, configOpts.Source)
+
+	// Charts without configuration have nothing to generate.
+	assert.Nil(t, charts[1].Configuration(contourconfig.Options{}).File)
+}
+
+func TestDiscoverNames(t *testing.T) {
+	dir := writeCharts(t, map[string]string{
+		"contour":             testConfig,
+		"gateway-provisioner": "component: contour\n",
+		"unmanaged":           "",
+	})
+
+	charts, err := Discover(dir, "gateway-provisioner")
+	require.NoError(t, err)
+	require.Len(t, charts, 1)
+	assert.Equal(t, "gateway-provisioner", charts[0].Name)
+
+	_, err = Discover(dir, "contour", "unmanaged")
+	require.ErrorContains(t, err, "chart unmanaged not found in "+dir+" or has no upstream.yaml")
+}
+
+func TestDiscoverErrors(t *testing.T) {
+	tests := map[string]struct {
+		configs map[string]string
+		wantErr string
+	}{
+		"no managed charts": {
+			configs: map[string]string{"unmanaged": ""},
+			wantErr: "no charts with config file upstream.yaml found",
+		},
+		"unsupported component": {
+			configs: map[string]string{"contour": "component: envoy\n"},
+			wantErr: `invalid component "envoy"`,
+		},
+		"unknown field": {
+			configs: map[string]string{"contour": "component: contour\nimage: contour.image\n"},
+			wantErr: "field image not found",
+		},
+		"incomplete CRDs": {
+			configs: map[string]string{"contour": "component: contour\ncrds:\n  - name: Contour\n"},
+			wantErr: "name, source and destination are required",
+		},
+		"unknown release": {
+			configs: map[string]string{"contour": "component: contour\ncrds:\n  - name: Contour\n    release: contour\n    source: a.yaml\n    destination: b.yaml\n"},
+			wantErr: `unknown release "contour"`,
+		},
+		"incomplete RBAC rules": {
+			configs: map[string]string{"contour": "component: contour\nrbac:\n  - name: Contour\n    source: a.yaml\n"},
+			wantErr: "invalid RBAC rules in",
+		},
+		"incomplete configuration": {
+			configs: map[string]string{"contour": "component: contour\nconfiguration:\n  package: pkg/config\n  type: Parameters\n"},
+			wantErr: "invalid configuration in",
+		},
+		"toggles without split": {
+			configs: map[string]string{"contour": "component: contour\ncrds:\n  - name: Contour\n    source: a.yaml\n    destination: b.yaml\n    toggles: .Values.crds\n"},
+			wantErr: "invalid Contour CRDs in",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Discover(writeCharts(t, tc.configs))
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestParseNames(t *testing.T) {
+	assert.Equal(t, []string{"contour", "contour-crds"}, ParseNames("contour, contour-crds,"))
+	assert.Empty(t, ParseNames(""))
+}
+
+// TestCheckCRDs runs the CRD synchronization in check mode against the charts of the repository and the
+// committed lockfile, with the upstream files of the chart appVersion taken from the Go module proxy.
+// The Contour source is read from its module, since GitHub source tarballs cannot be reproduced, and
+// the Gateway API bundles are rebuilt from their module and verified against the lockfile.
+func TestCheckCRDs(t *testing.T) {
+	root := filepath.Join("..", "..", "..", "..")
+	lockfilePath := filepath.Join(root, crds.DefaultLockfilePath)
+
+	discovered, err := Discover(filepath.Join(root, DefaultDir))
+	require.NoError(t, err)
+
+	for _, c := range discovered {
+		if len(c.Config.CRDs) == 0 {
+			continue
+		}
+
+		t.Run(c.Name, func(t *testing.T) {
+			data, err := os.ReadFile(c.ChartPath())
+			require.NoError(t, err)
+			var chart struct {
+				AppVersion string `yaml:"appVersion"`
+			}
+			require.NoError(t, yaml.Unmarshal(data, &chart))
+
+			contourDir := crdstest.ModuleDir(t, "github.com/projectcontour/contour", "v"+chart.AppVersion)
+			gatewayAPIVersion, err := crds.GatewayAPIVersion(os.DirFS(contourDir))
+			require.NoError(t, err)
+			gatewayAPIDir := crdstest.ModuleDir(t, "sigs.k8s.io/gateway-api", "v"+gatewayAPIVersion)
+
+			// Contour copies the experimental bundle of the Gateway API release into its examples,
+			// which gives the copyright year of the release and checks the rebuilt bundles.
+			example, err := os.ReadFile(filepath.Join(contourDir, "examples", "gateway", "00-crds.yaml"))
+			require.NoError(t, err)
+			year := regexp.MustCompile(`Copyright (\d{4})`).FindSubmatch(example)
+			require.NotNil(t, year)
+
+			srv := crdstest.NewServer(t, nil)
+			for _, channel := range []string{"standard", "experimental"} {
+				bundle := crdstest.GatewayAPIBundle(t, gatewayAPIDir, channel, string(year[1]))
+				srv.AddGatewayAPIAsset(gatewayAPIVersion, channel+"-install.yaml", bundle)
+				if channel == "experimental" {
+					require.Equal(t, string(example), string(bundle), "rebuilt bundle differs from the release asset")
+				}
+			}
+
+			check := func(c *Chart) (string, error) {
+				var out bytes.Buffer
+				_, err := crds.Run(context.Background(), c.CRDs(crds.Options{
+					Source: &crds.DirSource{Path: contourDir},
+					GatewayAPI: &crds.GatewayAPIRelease{Download: crds.Download{
+						LockfilePath:     lockfilePath,
+						FrozenLockfile:   true,
+						ReadOnlyLockfile: true,
+						Fetcher:          &fetch.Fetcher{Client: srv.Client()},
+					}},
+					Check: true,
+					Out:   &out,
+				}))
+				return out.String(), err
+			}
+
+			diff, err := check(c)
+			require.NoError(t, err, diff)
+			assert.Empty(t, diff)
+
+			// A hand-edited CRD is reported as a diff.
+			dir := t.TempDir()
+			require.NoError(t, os.CopyFS(filepath.Join(dir, c.Name), os.DirFS(c.Dir)))
+			edited, err := Load(filepath.Join(dir, c.Name))
+			require.NoError(t, err)
+			files, err := filepath.Glob(filepath.Join(edited.Dir, c.Config.CRDs[0].Destination, "*.yaml"))
+			require.NoError(t, err)
+			require.NotEmpty(t, files)
+			require.NoError(t, os.WriteFile(files[0], []byte("# edited\n"), 0o600))
+
+			diff, err = check(edited)
+			require.ErrorIs(t, err, crds.ErrDrift)
+			assert.Contains(t, diff, "-# edited")
+		})
+	}
+}
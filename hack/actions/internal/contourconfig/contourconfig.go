@@ -0,0 +1,306 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package contourconfig generates the values schema and the commented reference of the Contour
+// configuration set in the chart values, from the Go types Contour decodes its configuration file into.
+package contourconfig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/render"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+var log = logrus.StandardLogger()
+
+// ErrDrift is returned in check mode when the generated files of the chart differ from the upstream configuration.
+var ErrDrift = errors.New("chart configuration schema is out of date")
+
+// File maps the configuration file of the component to the chart value it is set in.
+type File struct {
+	// Package is the directory of the Go package defining the configuration file in the Contour source,
+	// such as pkg/config.
+	Package string `yaml:"package"`
+
+	// Type is the Go type of the package the configuration file is decoded into, such as Parameters.
+	Type string `yaml:"type"`
+
+	// Example is the path of the example configuration ConfigMap in the Contour source, such as
+	// examples/contour/01-contour-config.yaml. Its keys must all be known, which catches types the
+	// generator misreads.
+	Example string `yaml:"example"`
+
+	// Value is the top-level key of values.yaml the chart renders the configuration file from, such as configInline.
+	// Its default value must only set known keys.
+	Value string `yaml:"value"`
+
+	// Schema is the path of the values schema of the chart, relative to the chart directory, such as values.schema.json.
+	// Only the property of Value is generated, the others are left alone.
+	Schema string `yaml:"schema"`
+
+	// Reference is the path of the generated commented reference of the configuration, relative to the chart directory.
+	Reference string `yaml:"reference"`
+}
+
+// Options configures Run.
+type Options struct {
+	// ChartPath is the path to the chart's Chart.yaml. The generated files are written relative to its directory.
+	ChartPath string
+
+	// File maps the configuration file to the chart. If nil, there is nothing to generate.
+	File *File
+
+	// Source provides the Contour source tree. Defaults to the release tarball of the chart appVersion,
+	// downloaded without verification.
+	Source crds.Source
+
+	// Check compares the generated files with the committed ones instead of writing them. Run writes a
+	// unified diff of the files that differ to Out and returns an error wrapping ErrDrift.
+	Check bool
+
+	// Out receives the diffs in check mode. Defaults to os.Stdout.
+	Out io.Writer
+}
+
+// Run generates the values schema and the reference of the configuration of the chart from the Contour
+// source of the chart's appVersion.
+func Run(ctx context.Context, opts Options) error {
+	if opts.File == nil {
+		log.Infof("No configuration to synchronize in %s", opts.ChartPath)
+		return nil
+	}
+	f := opts.File
+
+	chartDir := filepath.Dir(opts.ChartPath)
+	metadata, err := render.ReadMetadata(chartDir)
+	if err != nil {
+		return fmt.Errorf("failed to get current chart appVersion: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "contour-source-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	source := opts.Source
+	if source == nil {
+		source = &crds.ArchiveSource{}
+	}
+	log.Infof("Reading Contour source from %s", source)
+	tree, err := source.Open(ctx, metadata.AppVersion, tmpDir)
+	if err != nil {
+		return fmt.Errorf("failed to synchronize configuration schema: %w", err)
+	}
+
+	config, err := loadType(tree, f.Package, f.Type)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration type: %w", err)
+	}
+	if err := checkExample(tree, f.Example, config); err != nil {
+		return err
+	}
+	if err := checkDefault(filepath.Join(chartDir, "values.yaml"), f.Value, config); err != nil {
+		return err
+	}
+
+	schemaPath := filepath.Join(chartDir, f.Schema)
+	current, err := readOptional(schemaPath)
+	if err != nil {
+		return err
+	}
+	valueSchema := schema(config, "Contour configuration file, see "+f.Reference+" for the available keys.")
+	valueSchema["type"] = []string{"object", "null"}
+	updatedSchema, err := updateSchema(current, f.Value, valueSchema)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", schemaPath, err)
+	}
+
+	var drifted []string
+	for _, file := range []struct {
+		path string
+		data []byte
+	}{
+		{schemaPath, updatedSchema},
+		{filepath.Join(chartDir, f.Reference), reference(f.Value, config)},
+	} {
+		changed, err := syncFile(opts, file.path, file.data)
+		if err != nil {
+			return err
+		}
+		if changed {
+			drifted = append(drifted, file.path)
+		}
+	}
+
+	if len(drifted) > 0 {
+		return fmt.Errorf("%w: %s", ErrDrift, strings.Join(drifted, ", "))
+	}
+	return nil
+}
+
+// syncFile writes data to p, or diffs it with the committed file in check mode. It reports whether the
+// committed file is out of date in check mode.
+func syncFile(opts Options, p string, data []byte) (bool, error) {
+	current, err := readOptional(p)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case bytes.Equal(current, data):
+		log.Infof("%s is up to date", p)
+		return false, nil
+	case opts.Check:
+		return true, writeDiff(opts.Out, p, current, data)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil { //nolint:gosec // G301: chart directories are intentionally world-readable
+		return false, fmt.Errorf("failed to create directory for %s: %w", p, err)
+	}
+	if err := os.WriteFile(p, data, 0o644); err != nil { //nolint:gosec // G306: chart files are intentionally world-readable
+		return false, fmt.Errorf("failed to write file %s: %w", p, err)
+	}
+	log.Infof("Wrote %s", p)
+	return false, nil
+}
+
+// readOptional reads the file at p, returning no data if it does not exist yet.
+func readOptional(p string) ([]byte, error) {
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", p, err)
+	}
+	return data, nil
+}
+
+// checkExample verifies that the configuration file of the example ConfigMap of the Contour source only sets known keys.
+func checkExample(tree fs.FS, p string, config *node) error {
+	data, err := fs.ReadFile(tree, p)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", p, err)
+	}
+	var configMap struct {
+		Data map[string]string `yaml:"data"`
+	}
+	if err := yaml.Unmarshal(data, &configMap); err != nil {
+		return fmt.Errorf("failed to unmarshal yaml from %s: %w", p, err)
+	}
+	if len(configMap.Data) != 1 {
+		return fmt.Errorf("example ConfigMap %s holds %d files, expected one configuration file", p, len(configMap.Data))
+	}
+
+	for name, content := range configMap.Data {
+		var value any
+		if err := yaml.Unmarshal([]byte(content), &value); err != nil {
+			return fmt.Errorf("failed to unmarshal yaml from %s in %s: %w", name, p, err)
+		}
+		if unknown := unknownKeys(config, value, ""); len(unknown) > 0 {
+			return fmt.Errorf("example configuration %s sets keys missing from the configuration type: %s", p, strings.Join(unknown, ", "))
+		}
+	}
+	return nil
+}
+
+// checkDefault verifies that the default value of key in values.yaml at p only sets known keys.
+func checkDefault(p, key string, config *node) error {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", p, err)
+	}
+	values := map[string]any{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to unmarshal yaml from %s: %w", p, err)
+	}
+	if unknown := unknownKeys(config, values[key], key); len(unknown) > 0 {
+		return fmt.Errorf("default %s in %s sets unknown keys: %s", key, p, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// unknownKeys returns the dotted paths below prefix of the keys of value that n does not have.
+func unknownKeys(n *node, value any, prefix string) []string {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	var unknown []string
+	switch value := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			switch n.Kind {
+			case kindObject:
+				f := n.field(k)
+				if f == nil {
+					unknown = append(unknown, join(k))
+					continue
+				}
+				unknown = append(unknown, unknownKeys(f.Value, value[k], join(k))...)
+			case kindMap:
+				unknown = append(unknown, unknownKeys(n.Elem, value[k], join(k))...)
+			}
+		}
+	case []any:
+		if n.Kind == kindArray {
+			for i, item := range value {
+				unknown = append(unknown, unknownKeys(n.Elem, item, fmt.Sprintf("%s[%d]", prefix, i))...)
+			}
+		}
+	}
+	return unknown
+}
+
+// writeDiff writes a unified diff of the committed and generated file to out.
+func writeDiff(out io.Writer, p string, current, updated []byte) error {
+	if out == nil {
+		out = os.Stdout
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(current)),
+		B:        difflib.SplitLines(string(updated)),
+		FromFile: path.Join("a", filepath.ToSlash(p)),
+		ToFile:   path.Join("b", filepath.ToSlash(p)),
+		Context:  3,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to diff %s: %w", p, err)
+	}
+	if _, err := io.WriteString(out, diff); err != nil {
+		return fmt.Errorf("failed to write diff: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contourconfig
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds/crdstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testExample = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: contour
+data:
+  contour.yaml: |
+    # debug: false
+    log-format: text
+    tls:
+    # minimum TLS version that Contour will negotiate
+    # minimum-protocol-version: "1.2"
+`
+
+var testFile = &File{
+	Package:   "pkg/config",
+	Type:      "Parameters",
+	Example:   "examples/contour/01-contour-config.yaml",
+	Value:     "configInline",
+	Schema:    "values.schema.json",
+	Reference: "config-reference.yaml",
+}
+
+// writeChart writes a chart with the given values and values schema, and returns the path of its Chart.yaml.
+// An empty schema writes a chart without one.
+func writeChart(t *testing.T, values, schema string) string {
+	files := map[string]string{"values.yaml": values}
+	if schema != "" {
+		files["values.schema.json"] = schema
+	}
+	return crdstest.WriteChart(t, files)
+}
+
+// writeSource writes a Contour checkout holding testTree and the example configuration.
+func writeSource(t *testing.T, example string) crds.Source {
+	files := map[string]string{"examples/contour/01-contour-config.yaml": example}
+	for name, f := range testTree {
+		files[name] = string(f.Data)
+	}
+	return &crds.DirSource{Path: crdstest.WriteCheckout(t, files)}
+}
+
+func TestRun(t *testing.T) {
+	chartPath := writeChart(t, "configInline:\n  log-format: json\n", `{"properties": {"replicaCount": {"type": "integer"}}}`)
+	chartDir := filepath.Dir(chartPath)
+
+	require.NoError(t, Run(context.Background(), Options{ChartPath: chartPath, File: testFile, Source: writeSource(t, testExample)}))
+
+	data, err := os.ReadFile(filepath.Join(chartDir, "values.schema.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `    "configInline": {
+      "additionalProperties": false,
+      "description": "Contour configuration file, see config-reference.yaml for the available keys.",
+`)
+	assert.Contains(t, string(data), `"replicaCount": {`, "other properties should be left alone")
+
+	data, err = os.ReadFile(filepath.Join(chartDir, "config-reference.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "configInline:\n  # Enable debug logging.\n  # debug: false\n")
+	assert.Contains(t, string(data), "  # policy:\n    # disabled: false\n")
+}
+
+func TestRunCheck(t *testing.T) {
+	chartPath := writeChart(t, "configInline: {}\n", "")
+	schemaPath := filepath.Join(filepath.Dir(chartPath), "values.schema.json")
+	referencePath := filepath.Join(filepath.Dir(chartPath), "config-reference.yaml")
+	opts := Options{ChartPath: chartPath, File: testFile, Source: writeSource(t, testExample), Check: true}
+
+	t.Run("missing files", func(t *testing.T) {
+		var out bytes.Buffer
+		opts.Out = &out
+		err := Run(context.Background(), opts)
+		require.ErrorIs(t, err, ErrDrift)
+		assert.Equal(t, ErrDrift.Error()+": "+schemaPath+", "+referencePath, err.Error())
+		assert.Contains(t, out.String(), "+++ "+path.Join("b", filepath.ToSlash(schemaPath))+"\n")
+
+		// Nothing was written.
+		_, err = os.Stat(schemaPath)
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	opts.Check = false
+	require.NoError(t, Run(context.Background(), opts))
+	opts.Check = true
+
+	t.Run("up to date", func(t *testing.T) {
+		var out bytes.Buffer
+		opts.Out = &out
+		require.NoError(t, Run(context.Background(), opts))
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("edited reference", func(t *testing.T) {
+		data, err := os.ReadFile(referencePath)
+		require.NoError(t, err)
+		edited := strings.Replace(string(data), "# debug: false", "# debug: true", 1)
+		require.NoError(t, os.WriteFile(referencePath, []byte(edited), 0o600))
+
+		var out bytes.Buffer
+		opts.Out = &out
+		err = Run(context.Background(), opts)
+		require.ErrorIs(t, err, ErrDrift)
+		assert.Equal(t, ErrDrift.Error()+": "+referencePath, err.Error())
+		assert.Contains(t, out.String(), "-  # debug: true\n+  # debug: false\n")
+	})
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := map[string]struct {
+		values  string
+		example string
+		file    *File
+		wantErr string
+	}{
+		"unknown default key": {
+			values:  "configInline:\n  tls:\n    minimum-protocol-versoin: \"1.2\"\n",
+			example: testExample,
+			wantErr: "default configInline in",
+		},
+		"unknown example key": {
+			values:  "configInline: {}\n",
+			example: strings.Replace(testExample, "log-format: text", "log-fromat: text", 1),
+			wantErr: "example configuration examples/contour/01-contour-config.yaml sets keys missing from the configuration type: log-fromat",
+		},
+		"example without configuration file": {
+			values:  "configInline: {}\n",
+			example: "kind: ConfigMap\ndata: {}\n",
+			wantErr: "holds 0 files, expected one configuration file",
+		},
+		"missing type": {
+			values:  "configInline: {}\n",
+			example: testExample,
+			file:    &File{Package: "pkg/config", Type: "Config", Example: testFile.Example, Value: testFile.Value, Schema: testFile.Schema, Reference: testFile.Reference},
+			wantErr: "type Config not found in pkg/config",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			file := tc.file
+			if file == nil {
+				file = testFile
+			}
+			err := Run(context.Background(), Options{ChartPath: writeChart(t, tc.values, ""), File: file, Source: writeSource(t, tc.example)})
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestRunNoFile(t *testing.T) {
+	// Nothing is read from the missing source.
+	require.NoError(t, Run(context.Background(), Options{ChartPath: writeChart(t, "", ""), Source: &crds.DirSource{Path: filepath.Join(t.TempDir(), "contour")}}))
+}
+
+func TestUnknownKeys(t *testing.T) {
+	tests := map[string]struct {
+		value any
+		want  []string
+	}{
+		"known keys": {
+			value: map[string]any{"debug": true, "tls": map[string]any{"cipher-suites": []any{"a"}}, "headers": map[string]any{"x-custom": "1"}},
+		},
+		"null value": {
+			value: nil,
+		},
+		"unknown keys": {
+			value: map[string]any{"debgu": true, "tls": map[string]any{"minimum-protocol-versoin": "1.2"}},
+			want:  []string{"configInline.debgu", "configInline.tls.minimum-protocol-versoin"},
+		},
+		"array items": {
+			value: map[string]any{"tags": []any{map[string]any{"name": "a"}, map[string]any{"nmae": "b"}}},
+			want:  []string{"configInline.tags[1].nmae"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, unknownKeys(testConfig, tc.value, "configInline"))
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contourconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// referenceHeader introduces the reference file.
+const referenceHeader = `# Reference of the Contour configuration set in %s, generated by synchronize-config
+# from the Go types Contour decodes its configuration file into. Do not edit.
+#
+# Every key Contour reads is listed below, commented out, with its documentation.
+# The values schema of the chart rejects the keys that are not listed, such as
+# misspelled ones, when the chart is installed or upgraded.
+`
+
+// reference returns the commented reference of the configuration n set in the value key of the chart.
+func reference(key string, n *node) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, referenceHeader, key)
+	b.WriteString(key + ":\n")
+	for i, f := range n.Fields {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		writeFields(&b, "  ", []*field{f}, false)
+	}
+	return []byte(b.String())
+}
+
+// writeFields writes fields commented out at indent, with their doc comments. If item is set, the
+// fields are the ones of an array item, and the first one starts the item with a dash.
+func writeFields(b *strings.Builder, indent string, fields []*field, item bool) {
+	for i, f := range fields {
+		keyIndent, prefix := indent, ""
+		switch {
+		case item && i == 0:
+			prefix = "- "
+		case item:
+			keyIndent = indent + "  "
+		}
+		childIndent := keyIndent + "  "
+		if item {
+			childIndent = indent + "    "
+		}
+
+		if doc := describe(f); doc != "" {
+			for _, line := range strings.Split(doc, "\n") {
+				b.WriteString(strings.TrimRight(keyIndent+"# "+line, " ") + "\n")
+			}
+		}
+
+		v := f.Value
+		switch {
+		case v.Kind == kindObject && len(v.Fields) > 0:
+			b.WriteString(keyIndent + "# " + prefix + f.Key + ":\n")
+			writeFields(b, childIndent, v.Fields, false)
+		case v.Kind == kindArray && v.Elem.Kind == kindObject && len(v.Elem.Fields) > 0:
+			b.WriteString(keyIndent + "# " + prefix + f.Key + ":\n")
+			writeFields(b, childIndent, v.Elem.Fields, true)
+		default:
+			b.WriteString(keyIndent + "# " + prefix + f.Key + ": " + placeholder(v) + "\n")
+		}
+	}
+}
+
+// placeholder returns an example value of n: its first enum value or its zero value.
+func placeholder(n *node) string {
+	switch {
+	case len(n.Enum) > 0:
+		return strconv.Quote(n.Enum[0])
+	case n.Kind == kindArray:
+		return "[]"
+	case n.Kind == kindMap, n.Kind == kindObject, n.Kind == kindAny:
+		return "{}"
+	case n.Type == "bool":
+		return "false"
+	case n.Type == "string":
+		return `""`
+	default:
+		return "0"
+	}
+}
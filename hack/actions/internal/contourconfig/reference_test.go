@@ -0,0 +1,52 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contourconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReference(t *testing.T) {
+	assert.Equal(t, `# Reference of the Contour configuration set in configInline, generated by synchronize-config
+# from the Go types Contour decodes its configuration file into. Do not edit.
+#
+# Every key Contour reads is listed below, commented out, with its documentation.
+# The values schema of the chart rejects the keys that are not listed, such as
+# misspelled ones, when the chart is installed or upgraded.
+configInline:
+  # Enable debug logging.
+  # debug: false
+
+  # Format of the logs.
+  #
+  # One of: text, json.
+  # log-format: "text"
+
+  # tls:
+    # minimum-protocol-version: ""
+    # CipherSuites to negotiate.
+    # cipher-suites: []
+
+  # headers: {}
+
+  # tags:
+    # Name of the tag.
+    # - name: ""
+      # port: 0
+
+  # timeout: {}
+`, string(reference("configInline", testConfig)))
+}
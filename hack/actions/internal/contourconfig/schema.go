@@ -0,0 +1,94 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contourconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// schemaDraft is the JSON schema draft of new values schemas, the latest one Helm supports.
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// schema returns the JSON schema of the values of n, described by doc.
+//
+// The schema rejects unknown keys and values of the wrong structure, as Contour does when it decodes
+// its configuration file. Scalars are not type-checked, since the chart renders the configuration
+// with tpl before Contour reads it, so that a number may well be set with a template string.
+// Objects and arrays may be null, which Contour decodes as empty.
+func schema(n *node, doc string) map[string]any {
+	s := map[string]any{}
+	switch n.Kind {
+	case kindObject:
+		properties := map[string]any{}
+		for _, f := range n.Fields {
+			properties[f.Key] = schema(f.Value, describe(f))
+		}
+		s["type"] = []string{"object", "null"}
+		s["additionalProperties"] = false
+		s["properties"] = properties
+	case kindMap:
+		s["type"] = []string{"object", "null"}
+		s["additionalProperties"] = schema(n.Elem, "")
+	case kindArray:
+		s["type"] = []string{"array", "null"}
+		s["items"] = schema(n.Elem, "")
+	}
+	if doc != "" {
+		s["description"] = doc
+	}
+	return s
+}
+
+// describe returns the description of a field, with the values of its enum.
+func describe(f *field) string {
+	doc := f.Doc
+	if len(f.Value.Enum) > 0 {
+		doc = strings.TrimSpace(doc + "\n\nOne of: " + strings.Join(f.Value.Enum, ", ") + ".")
+	}
+	return doc
+}
+
+// updateSchema sets the schema of the value key of the chart values schema data, leaving the
+// other properties alone. Empty data starts a new values schema.
+func updateSchema(data []byte, key string, valueSchema map[string]any) ([]byte, error) {
+	values := map[string]any{"$schema": schemaDraft, "type": "object"}
+	if len(bytes.TrimSpace(data)) > 0 {
+		values = map[string]any{}
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal json: %w", err)
+		}
+	}
+
+	properties, ok := values["properties"].(map[string]any)
+	if !ok {
+		if values["properties"] != nil {
+			return nil, fmt.Errorf("properties of the values schema are not an object")
+		}
+		properties = map[string]any{}
+		values["properties"] = properties
+	}
+	properties[key] = valueSchema
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(values); err != nil {
+		return nil, fmt.Errorf("failed to marshal json: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,145 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contourconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConfig is a configuration with a key of every kind.
+var testConfig = &node{Kind: kindObject, Fields: []*field{
+	{Key: "debug", Doc: "Enable debug logging.", Value: &node{Kind: kindScalar, Type: "bool"}},
+	{Key: "log-format", Doc: "Format of the logs.", Value: &node{Kind: kindScalar, Type: "string", Enum: []string{"text", "json"}}},
+	{Key: "tls", Value: &node{Kind: kindObject, Fields: []*field{
+		{Key: "minimum-protocol-version", Value: &node{Kind: kindScalar, Type: "string"}},
+		{Key: "cipher-suites", Doc: "CipherSuites to negotiate.", Value: &node{Kind: kindArray, Elem: &node{Kind: kindScalar, Type: "string"}}},
+	}}},
+	{Key: "headers", Value: &node{Kind: kindMap, Elem: &node{Kind: kindScalar, Type: "string"}}},
+	{Key: "tags", Value: &node{Kind: kindArray, Elem: &node{Kind: kindObject, Fields: []*field{
+		{Key: "name", Doc: "Name of the tag.", Value: &node{Kind: kindScalar, Type: "string"}},
+		{Key: "port", Value: &node{Kind: kindScalar, Type: "uint32"}},
+	}}}},
+	{Key: "timeout", Value: &node{Kind: kindAny}},
+}}
+
+func TestSchema(t *testing.T) {
+	assert.Equal(t, map[string]any{
+		"type":                 []string{"object", "null"},
+		"additionalProperties": false,
+		"description":          "Contour configuration.",
+		"properties": map[string]any{
+			"debug":      map[string]any{"description": "Enable debug logging."},
+			"log-format": map[string]any{"description": "Format of the logs.\n\nOne of: text, json."},
+			"tls": map[string]any{
+				"type":                 []string{"object", "null"},
+				"additionalProperties": false,
+				"properties": map[string]any{
+					"minimum-protocol-version": map[string]any{},
+					"cipher-suites": map[string]any{
+						"type":        []string{"array", "null"},
+						"items":       map[string]any{},
+						"description": "CipherSuites to negotiate.",
+					},
+				},
+			},
+			"headers": map[string]any{
+				"type":                 []string{"object", "null"},
+				"additionalProperties": map[string]any{},
+			},
+			"tags": map[string]any{
+				"type": []string{"array", "null"},
+				"items": map[string]any{
+					"type":                 []string{"object", "null"},
+					"additionalProperties": false,
+					"properties": map[string]any{
+						"name": map[string]any{"description": "Name of the tag."},
+						"port": map[string]any{},
+					},
+				},
+			},
+			"timeout": map[string]any{},
+		},
+	}, schema(testConfig, "Contour configuration."))
+}
+
+func TestUpdateSchema(t *testing.T) {
+	valueSchema := map[string]any{"type": "object", "description": "Contour <configuration> & more."}
+
+	tests := map[string]struct {
+		data string
+		want string
+	}{
+		"new schema": {
+			want: `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "properties": {
+    "configInline": {
+      "description": "Contour <configuration> & more.",
+      "type": "object"
+    }
+  },
+  "type": "object"
+}
+`,
+		},
+		"other properties": {
+			data: `{"type": "object", "properties": {"replicaCount": {"type": "integer"}, "configInline": {"type": "string"}}}`,
+			want: `{
+  "properties": {
+    "configInline": {
+      "description": "Contour <configuration> & more.",
+      "type": "object"
+    },
+    "replicaCount": {
+      "type": "integer"
+    }
+  },
+  "type": "object"
+}
+`,
+		},
+		"no properties": {
+			data: `{"type": "object"}`,
+			want: `{
+  "properties": {
+    "configInline": {
+      "description": "Contour <configuration> & more.",
+      "type": "object"
+    }
+  },
+  "type": "object"
+}
+`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := updateSchema([]byte(tc.data), "configInline", valueSchema)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestUpdateSchemaErrors(t *testing.T) {
+	_, err := updateSchema([]byte("{"), "configInline", nil)
+	require.ErrorContains(t, err, "failed to unmarshal json")
+
+	_, err = updateSchema([]byte(`{"properties": []}`), "configInline", nil)
+	require.EqualError(t, err, "properties of the values schema are not an object")
+}
@@ -0,0 +1,324 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contourconfig
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// contourModule is the module path of the Contour source. Types of its packages are resolved in the
+// source tree, types of other modules accept any value.
+const contourModule = "github.com/projectcontour/contour"
+
+// Kinds of nodes.
+const (
+	kindObject = "object"
+	kindMap    = "map"
+	kindArray  = "array"
+	kindScalar = "scalar"
+	kindAny    = "any"
+)
+
+// node is a value of the configuration file, as decoded by Contour.
+type node struct {
+	// Kind is the kind of the value, one of the kind constants.
+	Kind string
+
+	// Type is the Go type of a scalar, such as bool, string or uint32.
+	Type string
+
+	// Enum lists the values of the constants declared for the named type of a scalar.
+	Enum []string
+
+	// Fields are the keys of an object, in source order.
+	Fields []*field
+
+	// Elem is the value of the items of an array or of the entries of a map.
+	Elem *node
+}
+
+// field is a key of an object.
+type field struct {
+	// Key is the key of the field in the configuration file.
+	Key string
+
+	// Doc is the doc comment of the field, without kubebuilder markers.
+	Doc string
+
+	// Value is the value of the field.
+	Value *node
+}
+
+// field returns the field of an object with key, or nil.
+func (n *node) field(key string) *field {
+	i := slices.IndexFunc(n.Fields, func(f *field) bool { return f.Key == key })
+	if i < 0 {
+		return nil
+	}
+	return n.Fields[i]
+}
+
+// goPackage holds the declarations of a Go package of the Contour source.
+type goPackage struct {
+	types  map[string]*ast.TypeSpec
+	consts map[string][]string
+	// imports maps the names of the imports of each file to their path, by type.
+	imports map[string]map[string]string
+}
+
+// loader reads the Go packages of the Contour source tree.
+type loader struct {
+	tree     fs.FS
+	packages map[string]*goPackage
+	// visiting holds the types being converted, to stop at recursive types.
+	visiting map[string]bool
+}
+
+// loadType returns the configuration file decoded into the named Go type of the package in dir,
+// such as Parameters in pkg/config, following yaml.v3 decoding rules.
+func loadType(tree fs.FS, dir, name string) (*node, error) {
+	l := &loader{tree: tree, packages: map[string]*goPackage{}, visiting: map[string]bool{}}
+	pkg, err := l.load(dir)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := pkg.types[name]; !ok {
+		return nil, fmt.Errorf("type %s not found in %s", name, dir)
+	}
+	return l.named(dir, name)
+}
+
+// load parses the non-test Go files of the package in dir.
+func (l *loader) load(dir string) (*goPackage, error) {
+	if pkg, ok := l.packages[dir]; ok {
+		return pkg, nil
+	}
+
+	entries, err := fs.ReadDir(l.tree, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package %s: %w", dir, err)
+	}
+	pkg := &goPackage{types: map[string]*ast.TypeSpec{}, consts: map[string][]string{}, imports: map[string]map[string]string{}}
+	fset := token.NewFileSet()
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".go" || strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+		p := path.Join(dir, e.Name())
+		src, err := fs.ReadFile(l.tree, p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", p, err)
+		}
+		file, err := parser.ParseFile(fset, p, src, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+		}
+		pkg.add(file)
+	}
+	l.packages[dir] = pkg
+	return pkg, nil
+}
+
+// add collects the type and string constant declarations of file.
+func (pkg *goPackage) add(file *ast.File) {
+	imports := map[string]string{}
+	for _, imp := range file.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		name := path.Base(importPath)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		imports[name] = importPath
+	}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				pkg.types[spec.Name.Name] = spec
+				pkg.imports[spec.Name.Name] = imports
+			case *ast.ValueSpec:
+				ident, ok := spec.Type.(*ast.Ident)
+				if gen.Tok != token.CONST || !ok {
+					continue
+				}
+				for _, value := range spec.Values {
+					if lit, ok := value.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						s, _ := strconv.Unquote(lit.Value)
+						pkg.consts[ident.Name] = append(pkg.consts[ident.Name], s)
+					}
+				}
+			}
+		}
+	}
+}
+
+// named returns the node of the named type of the package in dir.
+func (l *loader) named(dir, name string) (*node, error) {
+	pkg, err := l.load(dir)
+	if err != nil {
+		return nil, err
+	}
+	spec, ok := pkg.types[name]
+	if !ok {
+		// Predeclared types, such as string.
+		return scalar(name), nil
+	}
+
+	key := dir + "." + name
+	if l.visiting[key] {
+		return &node{Kind: kindAny}, nil
+	}
+	l.visiting[key] = true
+	defer delete(l.visiting, key)
+
+	n, err := l.expr(dir, name, spec.Type)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read type %s: %w", name, err)
+	}
+	if n.Kind == kindScalar && len(pkg.consts[name]) > 0 {
+		copied := *n
+		copied.Enum = pkg.consts[name]
+		n = &copied
+	}
+	return n, nil
+}
+
+// expr returns the node of a type expression in the declaration of the type scope of the package in dir.
+func (l *loader) expr(dir, scope string, expr ast.Expr) (*node, error) {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return l.named(dir, expr.Name)
+	case *ast.StarExpr:
+		return l.expr(dir, scope, expr.X)
+	case *ast.ArrayType:
+		elem, err := l.expr(dir, scope, expr.Elt)
+		if err != nil {
+			return nil, err
+		}
+		return &node{Kind: kindArray, Elem: elem}, nil
+	case *ast.MapType:
+		elem, err := l.expr(dir, scope, expr.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &node{Kind: kindMap, Elem: elem}, nil
+	case *ast.StructType:
+		return l.object(dir, scope, expr)
+	case *ast.SelectorExpr:
+		pkgName, ok := expr.X.(*ast.Ident)
+		if !ok {
+			return &node{Kind: kindAny}, nil
+		}
+		importPath := l.packages[dir].imports[scope][pkgName.Name]
+		rel, ok := strings.CutPrefix(importPath, contourModule+"/")
+		if !ok {
+			return &node{Kind: kindAny}, nil
+		}
+		return l.named(rel, expr.Sel.Name)
+	default:
+		return &node{Kind: kindAny}, nil
+	}
+}
+
+// object returns the node of a struct, keyed the way yaml.v3 decodes it: by the name of the yaml tag,
+// or the lowercased field name, with the fields of inlined structs merged in.
+func (l *loader) object(dir, scope string, st *ast.StructType) (*node, error) {
+	n := &node{Kind: kindObject}
+	for _, f := range st.Fields.List {
+		var tag string
+		if f.Tag != nil {
+			unquoted, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(unquoted).Get("yaml")
+		}
+		key, opts, _ := strings.Cut(tag, ",")
+		if key == "-" {
+			continue
+		}
+
+		value, err := l.expr(dir, scope, f.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		if slices.Contains(strings.Split(opts, ","), "inline") {
+			if value.Kind == kindObject {
+				n.Fields = append(n.Fields, value.Fields...)
+			}
+			continue
+		}
+
+		names := f.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{embeddedName(f.Type)}
+		}
+		for _, name := range names {
+			if name == nil || !name.IsExported() {
+				continue
+			}
+			k := key
+			if k == "" {
+				k = strings.ToLower(name.Name)
+			}
+			n.Fields = append(n.Fields, &field{Key: k, Doc: docText(f.Doc), Value: value})
+		}
+	}
+	return n, nil
+}
+
+// embeddedName returns the name of an embedded field of type expr.
+func embeddedName(expr ast.Expr) *ast.Ident {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr
+	case *ast.StarExpr:
+		return embeddedName(expr.X)
+	case *ast.SelectorExpr:
+		return expr.Sel
+	default:
+		return nil
+	}
+}
+
+// scalar returns the node of a predeclared type, such as bool or uint32.
+func scalar(name string) *node {
+	if name == "any" {
+		return &node{Kind: kindAny}
+	}
+	return &node{Kind: kindScalar, Type: name}
+}
+
+// docText returns the text of a doc comment without the kubebuilder markers, such as +optional.
+func docText(doc *ast.CommentGroup) string {
+	var lines []string
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		if !strings.HasPrefix(line, "+") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,160 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contourconfig
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTree is a Contour source tree with a configuration package and an API package it imports.
+var testTree = fstest.MapFS{
+	"pkg/config/parameters.go": {Data: []byte(`package config
+
+import (
+	"time"
+
+	contour_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+)
+
+// LogFormat is the format of the logs.
+type LogFormat string
+
+const (
+	TextLogFormat LogFormat = "text"
+	JSONLogFormat LogFormat = "json"
+)
+
+type Parameters struct {
+	// Enable debug logging.
+	Debug bool
+
+	// Format of the logs.
+	// +optional
+	Format *LogFormat ` + "`yaml:\"log-format,omitempty\"`" + `
+
+	TLS TLSParameters ` + "`yaml:\"tls\"`" + `
+
+	Headers map[string]string ` + "`yaml:\"headers,omitempty\"`" + `
+
+	Tags []Tag ` + "`yaml:\"tags\"`" + `
+
+	Timeout time.Duration ` + "`yaml:\"timeout\"`" + `
+
+	Policy *contour_v1.Policy ` + "`yaml:\"policy\"`" + `
+
+	Ignored string ` + "`yaml:\"-\"`" + `
+
+	unexported string
+}
+
+type TLSParameters struct {
+	ProtocolParameters ` + "`yaml:\",inline\"`" + `
+
+	// CipherSuites to negotiate.
+	CipherSuites []string ` + "`yaml:\"cipher-suites,omitempty\"`" + `
+}
+
+type ProtocolParameters struct {
+	MinimumProtocolVersion string ` + "`yaml:\"minimum-protocol-version\"`" + `
+}
+
+type Tag struct {
+	Name  string ` + "`yaml:\"name\"`" + `
+	Value string ` + "`yaml:\"value\"`" + `
+}
+`)},
+	"pkg/config/parameters_test.go": {Data: []byte("package config\n\ntype Ignored struct{}\n")},
+	"apis/projectcontour/v1/policy.go": {Data: []byte(`package v1
+
+type Policy struct {
+	Disabled bool ` + "`json:\"disabled,omitempty\"`" + `
+	Parent   *Policy ` + "`json:\"parent,omitempty\" yaml:\"parent,omitempty\"`" + `
+}
+`)},
+}
+
+func TestLoadType(t *testing.T) {
+	n, err := loadType(testTree, "pkg/config", "Parameters")
+	require.NoError(t, err)
+
+	var keys []string
+	for _, f := range n.Fields {
+		keys = append(keys, f.Key)
+	}
+	assert.Equal(t, []string{"debug", "log-format", "tls", "headers", "tags", "timeout", "policy"}, keys)
+
+	tests := map[string]struct {
+		key  string
+		want *field
+	}{
+		"field without tag": {
+			key:  "debug",
+			want: &field{Key: "debug", Doc: "Enable debug logging.", Value: &node{Kind: kindScalar, Type: "bool"}},
+		},
+		"pointer to enum without markers": {
+			key:  "log-format",
+			want: &field{Key: "log-format", Doc: "Format of the logs.", Value: &node{Kind: kindScalar, Type: "string", Enum: []string{"text", "json"}}},
+		},
+		"inlined struct": {
+			key: "tls",
+			want: &field{Key: "tls", Value: &node{Kind: kindObject, Fields: []*field{
+				{Key: "minimum-protocol-version", Value: &node{Kind: kindScalar, Type: "string"}},
+				{Key: "cipher-suites", Doc: "CipherSuites to negotiate.", Value: &node{Kind: kindArray, Elem: &node{Kind: kindScalar, Type: "string"}}},
+			}}},
+		},
+		"map": {
+			key:  "headers",
+			want: &field{Key: "headers", Value: &node{Kind: kindMap, Elem: &node{Kind: kindScalar, Type: "string"}}},
+		},
+		"array of structs": {
+			key: "tags",
+			want: &field{Key: "tags", Value: &node{Kind: kindArray, Elem: &node{Kind: kindObject, Fields: []*field{
+				{Key: "name", Value: &node{Kind: kindScalar, Type: "string"}},
+				{Key: "value", Value: &node{Kind: kindScalar, Type: "string"}},
+			}}}},
+		},
+		"type of another module": {
+			key:  "timeout",
+			want: &field{Key: "timeout", Value: &node{Kind: kindAny}},
+		},
+		"recursive type of a Contour package": {
+			key: "policy",
+			want: &field{Key: "policy", Value: &node{Kind: kindObject, Fields: []*field{
+				{Key: "disabled", Value: &node{Kind: kindScalar, Type: "bool"}},
+				{Key: "parent", Value: &node{Kind: kindAny}},
+			}}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, n.field(tc.key))
+		})
+	}
+}
+
+func TestLoadTypeErrors(t *testing.T) {
+	_, err := loadType(testTree, "pkg/config", "Config")
+	require.EqualError(t, err, "type Config not found in pkg/config")
+
+	_, err = loadType(testTree, "pkg/missing", "Parameters")
+	require.ErrorContains(t, err, "failed to read package pkg/missing")
+
+	_, err = loadType(fstest.MapFS{"pkg/config/config.go": {Data: []byte("package config\n\ntype Parameters struct {")}}, "pkg/config", "Parameters")
+	require.ErrorContains(t, err, "failed to parse pkg/config/config.go")
+}
@@ -0,0 +1,372 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package crds implements the synchronize-crds tool, which synchronizes the CRDs in the
+// Contour chart with the ones from the Contour source code of the chart's appVersion.
+package crds
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/artifacthub"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+var log = logrus.StandardLogger()
+
+// File describes a CRD file copied from the Contour source into the chart.
+type File struct {
+	// Name names the CRDs in logs, such as "Gateway API".
+	Name string `yaml:"name"`
+
+	// Source is the path of the file in the Contour source, such as examples/contour/01-crds.yaml,
+	// or the name of the release asset if Release is set.
+	Source string `yaml:"source"`
+
+	// Release is the upstream release Source is an asset of, instead of a path in the Contour source.
+	// Only ReleaseGatewayAPI is supported.
+	Release string `yaml:"release"`
+
+	// Destination is the path of the file relative to the chart directory,
+	// or of the directory the CRDs are written to if Split is set.
+	Destination string `yaml:"destination"`
+
+	// Conditional is the Helm expression the CRDs are rendered under, such as .Values.contour.manageCRDs.
+	// If empty, the CRDs are always rendered.
+	Conditional string `yaml:"conditional"`
+
+	// Split writes each CustomResourceDefinition of the source file to its own template in the Destination
+	// directory, named after the CRD, such as httpproxies.projectcontour.io.yaml. Templates of CRDs that
+	// are no longer in the source file are removed.
+	Split bool `yaml:"split"`
+
+	// Toggles is the values map that turns individual CRDs on and off by plural name, such as
+	// .Values.contour.crds with extensionservices: false. CRDs missing from the map are rendered.
+	// It requires Split and is combined with Conditional.
+	Toggles string `yaml:"toggles"`
+
+	// Keep is the Helm expression under which the CRDs are annotated with helm.sh/resource-policy: keep,
+	// such as .Values.contour.keepCRDs, so that uninstalling the chart does not delete them with all their
+	// resources. If empty, the CRDs are not annotated.
+	Keep string `yaml:"keep"`
+
+	// Labels and Annotations are the names of the chart templates rendering the labels and annotations
+	// added to the metadata of every CRD, such as contour.crds.labels. They are included with the root
+	// context, before the upstream labels and annotations. If empty, nothing is added.
+	Labels      string `yaml:"labels"`
+	Annotations string `yaml:"annotations"`
+}
+
+// Options configures a CRD synchronization.
+type Options struct {
+	// ChartPath is the path to the chart's Chart.yaml. CRDs are written relative to its directory.
+	ChartPath string
+
+	// Files lists the CRD files of the chart. If empty, there is nothing to synchronize.
+	Files []File
+
+	// Source provides the Contour source tree. Defaults to the release tarball of the chart appVersion,
+	// downloaded without verification.
+	Source Source
+
+	// GatewayAPI provides the assets of the Gateway API release for files with Release set to ReleaseGatewayAPI.
+	// Defaults to downloading them without verification.
+	GatewayAPI *GatewayAPIRelease
+
+	// Check compares the chart files with the ones that would be written instead of writing them.
+	// Run writes a unified diff of the files that differ to Out and returns an error wrapping ErrDrift.
+	Check bool
+
+	// Out receives the diffs in check mode. Defaults to os.Stdout.
+	Out io.Writer
+}
+
+// ErrDrift is returned in check mode when the chart files differ from the Contour source.
+var ErrDrift = errors.New("chart files are out of date")
+
+// Run synchronizes the chart CRDs with the Contour source of the chart's appVersion.
+// It returns a report of the semantic changes from the committed to the synchronized CRDs.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if len(opts.Files) == 0 {
+		log.Infof("No CRDs to synchronize in %s", opts.ChartPath)
+		return &Report{}, nil
+	}
+
+	// Read current app version.
+	currentChartAppVersion, err := getCurrentChartAppVersion(opts.ChartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current chart appVersion: %w", err)
+	}
+	log.Infof("Current chart appVersion: %s", currentChartAppVersion)
+
+	tmpDir, err := os.MkdirTemp("", "contour-source-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files, report, err := renderCRDs(ctx, opts, tmpDir, currentChartAppVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to synchronize CRDs: %w", err)
+	}
+
+	if opts.Check {
+		return report, checkFiles(opts.Out, files)
+	}
+	return report, writeFiles(files)
+}
+
+// renderedFile is the expected content of a chart file.
+type renderedFile struct {
+	Path string
+	Data []byte
+
+	// Remove is set for files that should not exist anymore, such as templates of CRDs removed upstream.
+	Remove bool
+}
+
+// renderCRDs returns the expected content of the CRD files and of Chart.yaml without writing them,
+// and the report of the changes to the CRDs.
+func renderCRDs(ctx context.Context, opts Options, tmpDir, currentChartAppVersion string) ([]renderedFile, *Report, error) {
+	source := opts.Source
+	if source == nil {
+		source = &ArchiveSource{}
+	}
+
+	log.Infof("Reading Contour source from %s", source)
+	tree, err := source.Open(ctx, currentChartAppVersion, tmpDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chartDir := filepath.Dir(opts.ChartPath)
+	files := make([]renderedFile, 0, len(opts.Files)+1)
+	var annotation []artifacthub.CRD
+	annotated := map[string]bool{}
+	report := &Report{}
+	for _, c := range opts.Files {
+		data, err := readFile(ctx, opts, c, tree, tmpDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to copy %s CRDs: %w", c.Name, err)
+		}
+		rendered, err := c.render(chartDir, data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to render %s CRDs: %w", c.Name, err)
+		}
+		current, err := c.current(chartDir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		paths := map[string]bool{}
+		var oldCRDs, newCRDs [][]byte
+		for _, f := range rendered {
+			paths[f.Path] = true
+			newCRDs = append(newCRDs, f.Data)
+
+			crds, err := artifacthub.ParseCRDs(unescapeTemplateDelimiters(f.Data))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read %s CRDs: %w", c.Name, err)
+			}
+			// CRDs shipped in several variants, such as Gateway API release channels, are listed once.
+			for _, crd := range crds {
+				if !annotated[crd.Name] {
+					annotated[crd.Name] = true
+					annotation = append(annotation, crd)
+				}
+			}
+		}
+		files = append(files, rendered...)
+
+		for _, f := range current {
+			oldCRDs = append(oldCRDs, f.Data)
+			if !paths[f.Path] {
+				files = append(files, renderedFile{Path: f.Path, Remove: true})
+			}
+		}
+
+		old, err := parseCRDVersions(oldCRDs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse committed %s CRDs: %w", c.Name, err)
+		}
+		updated, err := parseCRDVersions(newCRDs...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s CRDs: %w", c.Name, err)
+		}
+		for _, change := range compareCRDs(old, updated) {
+			change.Set = c.Name
+			report.Changes = append(report.Changes, change)
+		}
+	}
+
+	// List the CRDs in the Artifact Hub annotations.
+	chart, err := os.ReadFile(opts.ChartPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file %s: %w", opts.ChartPath, err)
+	}
+	chart, err = artifacthub.SetAnnotationData(chart, artifacthub.CRDsAnnotation, annotation)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to update Artifact Hub CRDs in %s: %w", opts.ChartPath, err)
+	}
+	files = append(files, renderedFile{Path: opts.ChartPath, Data: chart})
+
+	return files, report, nil
+}
+
+// readFile reads the CRD file from the Contour source tree or from the upstream release it is an asset of.
+func readFile(ctx context.Context, opts Options, c File, tree fs.FS, tmpDir string) ([]byte, error) {
+	switch c.Release {
+	case "":
+		return readCRD(tree, c.Source)
+	case ReleaseGatewayAPI:
+		version, err := GatewayAPIVersion(tree)
+		if err != nil {
+			return nil, err
+		}
+		release := opts.GatewayAPI
+		if release == nil {
+			release = &GatewayAPIRelease{}
+		}
+		return release.open(ctx, version, c.Source, tmpDir)
+	default:
+		return nil, fmt.Errorf("unknown release %q", c.Release)
+	}
+}
+
+// writeFiles writes the files that changed and removes the ones that should not exist anymore.
+func writeFiles(files []renderedFile) error {
+	for _, f := range files {
+		if f.Remove {
+			if err := os.Remove(f.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("failed to remove file %s: %w", f.Path, err)
+			}
+			log.Infof("Removed %s", f.Path)
+			continue
+		}
+
+		current, err := os.ReadFile(f.Path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to read file %s: %w", f.Path, err)
+		}
+		if err == nil && bytes.Equal(current, f.Data) {
+			log.Infof("%s is up to date", f.Path)
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil { //nolint:gosec // G301: chart directories are intentionally world-readable
+			return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(f.Path), err)
+		}
+		if err := os.WriteFile(f.Path, f.Data, 0o644); err != nil { //nolint:gosec // G306: chart files are intentionally world-readable
+			return fmt.Errorf("failed to write destination file %s: %w", f.Path, err)
+		}
+		log.Infof("Wrote %s", f.Path)
+	}
+
+	return nil
+}
+
+// checkFiles writes a unified diff of the files that differ from the committed ones to out,
+// and returns an error wrapping ErrDrift naming them.
+func checkFiles(out io.Writer, files []renderedFile) error {
+	if out == nil {
+		out = os.Stdout
+	}
+
+	var drifted []string
+	for _, f := range files {
+		current, err := os.ReadFile(f.Path)
+		exists := err == nil
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to read file %s: %w", f.Path, err)
+		}
+		switch {
+		case f.Remove && !exists:
+			continue
+		case !f.Remove && exists && bytes.Equal(current, f.Data):
+			continue
+		}
+
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(current)),
+			B:        difflib.SplitLines(string(f.Data)),
+			FromFile: path.Join("a", filepath.ToSlash(f.Path)),
+			ToFile:   path.Join("b", filepath.ToSlash(f.Path)),
+			Context:  3,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to diff %s: %w", f.Path, err)
+		}
+		if _, err := io.WriteString(out, diff); err != nil {
+			return fmt.Errorf("failed to write diff: %w", err)
+		}
+		drifted = append(drifted, f.Path)
+	}
+
+	if len(drifted) > 0 {
+		return fmt.Errorf("%w: %s", ErrDrift, strings.Join(drifted, ", "))
+	}
+	return nil
+}
+
+// getCurrentChartAppVersion reads the current chart and app version.
+func getCurrentChartAppVersion(filePath string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	type ChartYaml struct {
+		AppVersion string `yaml:"appVersion"`
+	}
+
+	var chart ChartYaml
+	if err := yaml.Unmarshal(data, &chart); err != nil {
+		return "", fmt.Errorf("failed to unmarshal yaml from %s: %w", filePath, err)
+	}
+
+	return chart.AppVersion, nil
+}
+
+func readCRD(fsys fs.FS, srcPath string) ([]byte, error) {
+	f, err := fsys.Open(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source file %s: %w", srcPath, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source file %s: %w", srcPath, err)
+	}
+
+	return data, nil
+}
+
+// injectConditional wraps the given data with Helm conditional statement.
+func injectConditional(condition string, data []byte) []byte {
+	if condition == "" {
+		return data
+	}
+	return []byte(fmt.Sprintf("# Conditional: %s\n{{- if %s }}\n%s{{- end }}\n", condition, condition, string(data)))
+}
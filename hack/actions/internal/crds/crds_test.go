@@ -0,0 +1,298 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds/crdstest"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/fetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testContourCRDs = "---\napiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: httpproxies.projectcontour.io\n" +
+		"spec:\n  names:\n    kind: HTTPProxy\n  versions:\n  - name: v1\n    storage: true\n"
+	testGatewayCRDs = "---\napiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: gateways.gateway.networking.k8s.io\n" +
+		"spec:\n  names:\n    kind: Gateway\n  versions:\n  - name: v1\n    storage: true\n"
+)
+
+var testFiles = []File{
+	{
+		Name:        "Contour",
+		Source:      "examples/contour/01-crds.yaml",
+		Destination: "templates/crds/contour-crds.yaml",
+		Conditional: ".Values.contour.manageCRDs",
+	},
+	{
+		Name:        "Gateway API",
+		Source:      "examples/gateway/00-crds.yaml",
+		Destination: "templates/crds/gateway-api-crds.yaml",
+		Conditional: ".Values.gatewayAPI.manageCRDs",
+	},
+}
+
+// writeChart writes a minimal chart with the given appVersion into a temporary directory
+// and returns the path to its Chart.yaml.
+func writeChart(t *testing.T, appVersion string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "templates", "crds"), 0o755))
+	chartPath := filepath.Join(dir, "Chart.yaml")
+	require.NoError(t, os.WriteFile(chartPath, []byte("annotations:\n  category: Infrastructure\nappVersion: "+appVersion+"\nversion: 0.7.0\n"), 0o600))
+
+	return chartPath
+}
+
+// run runs a synchronization that must succeed and returns its report.
+func run(t *testing.T, opts Options) *Report {
+	t.Helper()
+
+	report, err := Run(context.Background(), opts)
+	require.NoError(t, err)
+	return report
+}
+
+func TestRun(t *testing.T) {
+	srv := crdstest.NewServer(t, map[string][]byte{
+		"1.33.6": crdstest.Tarball(t, "1.33.6", map[string]string{
+			"examples/contour/01-crds.yaml": testContourCRDs,
+			"examples/gateway/00-crds.yaml": testGatewayCRDs,
+		}),
+	})
+
+	chartPath := writeChart(t, "1.33.6")
+	report := run(t, Options{ChartPath: chartPath, Files: testFiles, Source: &ArchiveSource{URL: srv.SourceURL()}})
+	assert.Equal(t, []Change{
+		{Set: "Contour", CRD: "httpproxies.projectcontour.io", Description: "added CRD"},
+		{Set: "Gateway API", CRD: "gateways.gateway.networking.k8s.io", Description: "added CRD"},
+	}, report.Changes)
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(chartPath), "templates", "crds", "contour-crds.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Conditional: .Values.contour.manageCRDs\n{{- if .Values.contour.manageCRDs }}\n"+testContourCRDs+"{{- end }}\n", string(data))
+
+	data, err = os.ReadFile(filepath.Join(filepath.Dir(chartPath), "templates", "crds", "gateway-api-crds.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Conditional: .Values.gatewayAPI.manageCRDs\n{{- if .Values.gatewayAPI.manageCRDs }}\n"+testGatewayCRDs+"{{- end }}\n", string(data))
+
+	data, err = os.ReadFile(chartPath)
+	require.NoError(t, err)
+	assert.Equal(t, "annotations:\n"+
+		"  artifacthub.io/crds: |\n"+
+		"    - kind: HTTPProxy\n"+
+		"      version: v1\n"+
+		"      name: httpproxies.projectcontour.io\n"+
+		"      displayName: HTTPProxy\n"+
+		"    - kind: Gateway\n"+
+		"      version: v1\n"+
+		"      name: gateways.gateway.networking.k8s.io\n"+
+		"      displayName: Gateway\n"+
+		"  category: Infrastructure\n"+
+		"appVersion: 1.33.6\n"+
+		"version: 0.7.0\n", string(data))
+}
+
+func TestRunCached(t *testing.T) {
+	srv := crdstest.NewServer(t, map[string][]byte{
+		"1.33.6": crdstest.Tarball(t, "1.33.6", map[string]string{
+			"examples/contour/01-crds.yaml": testContourCRDs,
+			"examples/gateway/00-crds.yaml": testGatewayCRDs,
+		}),
+	})
+	opts := Options{
+		ChartPath: writeChart(t, "1.33.6"),
+		Files:     testFiles,
+		Source:    &ArchiveSource{URL: srv.SourceURL(), Download: Download{Fetcher: &fetch.Fetcher{CacheDir: t.TempDir()}}},
+	}
+
+	run(t, opts)
+	run(t, opts)
+	assert.Equal(t, 1, srv.Downloads())
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(opts.ChartPath), "templates", "crds", "contour-crds.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), testContourCRDs)
+}
+
+func TestRunLockfile(t *testing.T) {
+	tarball := crdstest.Tarball(t, "1.33.6", map[string]string{
+		"examples/contour/01-crds.yaml": testContourCRDs,
+		"examples/gateway/00-crds.yaml": testGatewayCRDs,
+	})
+	sum := sha256.Sum256(tarball)
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+	srv := crdstest.NewServer(t, map[string][]byte{"1.33.6": tarball})
+	url := fmt.Sprintf(srv.SourceURL(), "1.33.6")
+
+	newOptions := func(t *testing.T, lockfile string) (Options, *ArchiveSource) {
+		t.Helper()
+
+		source := &ArchiveSource{
+			URL:      srv.SourceURL(),
+			Download: Download{LockfilePath: filepath.Join(t.TempDir(), "upstream.lock")},
+		}
+		if lockfile != "" {
+			require.NoError(t, os.WriteFile(source.LockfilePath, []byte(lockfile), 0o600))
+		}
+		return Options{ChartPath: writeChart(t, "1.33.6"), Files: testFiles, Source: source}, source
+	}
+
+	t.Run("new tarball is pinned", func(t *testing.T) {
+		opts, source := newOptions(t, "")
+		run(t, opts)
+
+		data, err := os.ReadFile(source.LockfilePath)
+		require.NoError(t, err)
+		assert.Equal(t, lockfileHeader+"sources:\n  "+url+": "+checksum+"\n", string(data))
+
+		// The pinned tarball is accepted, even with a frozen lockfile.
+		source.FrozenLockfile = true
+		run(t, opts)
+	})
+
+	t.Run("changed tarball is rejected", func(t *testing.T) {
+		opts, source := newOptions(t, "sources:\n  "+url+": sha256:0000\n")
+		_, err := Run(context.Background(), opts)
+		require.ErrorContains(t, err, "checksum mismatch for "+url+": "+source.LockfilePath+" pins sha256:0000, downloaded "+checksum)
+
+		// No CRD was written.
+		_, err = os.Stat(filepath.Join(filepath.Dir(opts.ChartPath), "templates", "crds", "contour-crds.yaml"))
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("read-only lockfile is not written", func(t *testing.T) {
+		opts, source := newOptions(t, "")
+		source.ReadOnlyLockfile = true
+		run(t, opts)
+
+		_, err := os.Stat(source.LockfilePath)
+		require.ErrorIs(t, err, fs.ErrNotExist)
+
+		// Pinned tarballs are still verified.
+		require.NoError(t, os.WriteFile(source.LockfilePath, []byte("sources:\n  "+url+": sha256:0000\n"), 0o600))
+		_, err = Run(context.Background(), opts)
+		require.ErrorContains(t, err, "checksum mismatch")
+	})
+
+	t.Run("frozen lockfile rejects new tarballs", func(t *testing.T) {
+		opts, source := newOptions(t, "sources: {}\n")
+		source.FrozenLockfile = true
+		_, err := Run(context.Background(), opts)
+		require.ErrorContains(t, err, "checksum of "+url+" not found in "+source.LockfilePath)
+	})
+}
+
+func TestRunCheck(t *testing.T) {
+	srv := crdstest.NewServer(t, map[string][]byte{
+		"1.33.6": crdstest.Tarball(t, "1.33.6", map[string]string{
+			"examples/contour/01-crds.yaml": testContourCRDs,
+			"examples/gateway/00-crds.yaml": testGatewayCRDs,
+		}),
+	})
+	chartPath := writeChart(t, "1.33.6")
+	contourPath := filepath.Join(filepath.Dir(chartPath), "templates", "crds", "contour-crds.yaml")
+	opts := Options{ChartPath: chartPath, Files: testFiles, Source: &ArchiveSource{URL: srv.SourceURL()}, Check: true}
+
+	t.Run("missing files", func(t *testing.T) {
+		var out bytes.Buffer
+		opts.Out = &out
+		_, err := Run(context.Background(), opts)
+		require.ErrorIs(t, err, ErrDrift)
+		assert.ErrorContains(t, err, contourPath)
+		assert.Contains(t, out.String(), "+++ "+path.Join("b", filepath.ToSlash(chartPath))+"\n")
+		assert.Contains(t, out.String(), "+  artifacthub.io/crds: |\n")
+
+		// Nothing was written.
+		_, err = os.Stat(contourPath)
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	opts.Check = false
+	run(t, opts)
+	opts.Check = true
+
+	t.Run("up to date", func(t *testing.T) {
+		var out bytes.Buffer
+		opts.Out = &out
+		run(t, opts)
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("edited file", func(t *testing.T) {
+		data, err := os.ReadFile(contourPath)
+		require.NoError(t, err)
+		edited := strings.Replace(string(data), "kind: HTTPProxy", "kind: HTTPProxies", 1)
+		require.NoError(t, os.WriteFile(contourPath, []byte(edited), 0o600))
+
+		var out bytes.Buffer
+		opts.Out = &out
+		_, err = Run(context.Background(), opts)
+		require.ErrorIs(t, err, ErrDrift)
+		assert.Equal(t, ErrDrift.Error()+": "+contourPath, err.Error())
+		assert.Equal(t, "--- "+path.Join("a", filepath.ToSlash(contourPath))+"\n"+
+			"+++ "+path.Join("b", filepath.ToSlash(contourPath))+"\n"+
+			"@@ -7,7 +7,7 @@\n"+
+			"   name: httpproxies.projectcontour.io\n"+
+			" spec:\n"+
+			"   names:\n"+
+			"-    kind: HTTPProxies\n"+
+			"+    kind: HTTPProxy\n"+
+			"   versions:\n"+
+			"   - name: v1\n"+
+			"     storage: true\n", out.String())
+
+		// The edited file was left alone.
+		data, err = os.ReadFile(contourPath)
+		require.NoError(t, err)
+		assert.Equal(t, edited, string(data))
+	})
+}
+
+func TestRunMissingRelease(t *testing.T) {
+	srv := crdstest.NewServer(t, map[string][]byte{})
+
+	_, err := Run(context.Background(), Options{ChartPath: writeChart(t, "1.33.6"), Files: testFiles, Source: &ArchiveSource{URL: srv.SourceURL()}})
+	require.ErrorContains(t, err, "status code 404")
+}
+
+func TestRunNoFiles(t *testing.T) {
+	chartPath := writeChart(t, "1.33.6")
+	before, err := os.ReadFile(chartPath)
+	require.NoError(t, err)
+
+	// Nothing is downloaded from the unreachable source.
+	run(t, Options{ChartPath: chartPath, Source: &ArchiveSource{URL: "http://127.0.0.1:0/%s"}})
+
+	after, err := os.ReadFile(chartPath)
+	require.NoError(t, err)
+	assert.Equal(t, string(before), string(after))
+}
+
+func TestInjectConditional(t *testing.T) {
+	assert.Equal(t, "# Conditional: .Values.crds\n{{- if .Values.crds }}\nkind: A\n{{- end }}\n", string(injectConditional(".Values.crds", []byte("kind: A\n"))))
+	assert.Equal(t, "kind: A\n", string(injectConditional("", []byte("kind: A\n"))))
+}
@@ -0,0 +1,198 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package crdstest provides the fixtures of the hack tool tests: charts and Contour checkouts, Contour source
+// tarballs and Gateway API release assets served offline, and the upstream files of a release rebuilt from the
+// Go module proxy.
+package crdstest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Tarball returns a gzipped tarball laid out like a GitHub source archive of Contour,
+// with the given files below the contour-<version>/ directory.
+func Tarball(t *testing.T, version string, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		hdr := &tar.Header{
+			Name:     "contour-" + version + "/" + name,
+			Mode:     0o644,
+			Size:     int64(len(files[name])),
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// Server serves Contour source tarballs the way GitHub serves release archives.
+type Server struct {
+	*httptest.Server
+
+	tarballs map[string][]byte
+
+	mu        sync.Mutex
+	assets    map[string][]byte
+	downloads int
+}
+
+// NewServer starts a server that serves a tarball for each version in tarballs,
+// which is also served as the tarball of the git ref of the same name.
+// It is shut down when the test ends.
+func NewServer(t *testing.T, tarballs map[string][]byte) *Server {
+	t.Helper()
+
+	s := &Server{tarballs: tarballs}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Downloads returns the number of files served in full, not counting revalidated cached ones.
+func (s *Server) Downloads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.downloads
+}
+
+// SourceURL returns the tarball URL format string, to be formatted with the Contour version.
+func (s *Server) SourceURL() string {
+	return s.URL + "/archive/refs/tags/v%s.tar.gz"
+}
+
+// AddGatewayAPIAsset serves data as the asset of the Gateway API release of the given version, such as standard-install.yaml.
+func (s *Server) AddGatewayAPIAsset(version, asset string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.assets == nil {
+		s.assets = map[string][]byte{}
+	}
+	s.assets["/gateway-api/releases/download/v"+version+"/"+asset] = data
+}
+
+// GatewayAPIURL returns the Gateway API release asset URL format string, to be formatted with the version and asset name.
+func (s *Server) GatewayAPIURL() string {
+	return s.URL + "/gateway-api/releases/download/v%s/%s"
+}
+
+// Client returns a client that sends the requests for the GitHub URLs of the Contour source tarballs and
+// the Gateway API release assets, such as crds.DefaultSourceURL, to the server. Downloads are then verified
+// against the lockfile entries of the GitHub URLs.
+func (s *Server) Client() *http.Client {
+	target, err := url.Parse(s.URL)
+	if err != nil {
+		panic(err)
+	}
+
+	return &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		r = r.Clone(r.Context())
+		if r.URL.Host == "github.com" {
+			r.URL.Path = strings.TrimPrefix(r.URL.Path, "/projectcontour/contour")
+			r.URL.Path = strings.TrimPrefix(r.URL.Path, "/kubernetes-sigs")
+		}
+		r.URL.Scheme, r.URL.Host, r.Host = target.Scheme, target.Host, target.Host
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// RefURL returns the tarball URL format string of git refs, to be formatted with the ref.
+func (s *Server) RefURL() string {
+	return s.URL + "/archive/%s.tar.gz"
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	data, ok := s.lookup(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	s.mu.Lock()
+	s.downloads++
+	s.mu.Unlock()
+
+	if strings.HasSuffix(r.URL.Path, ".tar.gz") {
+		w.Header().Set("Content-Type", "application/x-gzip")
+	}
+	_, _ = w.Write(data)
+}
+
+func (s *Server) lookup(path string) ([]byte, bool) {
+	s.mu.Lock()
+	data, ok := s.assets[path]
+	s.mu.Unlock()
+	if ok {
+		return data, true
+	}
+
+	version, ok := strings.CutPrefix(path, "/archive/refs/tags/v")
+	if !ok {
+		version, ok = strings.CutPrefix(path, "/archive/")
+	}
+	if !ok {
+		return nil, false
+	}
+	data, ok = s.tarballs[strings.TrimSuffix(version, ".tar.gz")]
+	return data, ok
+}
@@ -0,0 +1,56 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crdstest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// WriteChart writes a chart named test, at version 1.0.0 and Contour appVersion 1.33.6, holding the given
+// files next to its Chart.yaml, such as values.yaml and templates/rbac.yaml. It returns the path of its Chart.yaml.
+func WriteChart(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+	writeFiles(t, dir, map[string]string{"Chart.yaml": "apiVersion: v2\nname: test\nversion: 1.0.0\nappVersion: 1.33.6\n"})
+	return filepath.Join(dir, "Chart.yaml")
+}
+
+// WriteCheckout writes a local Contour checkout holding the given files, such as
+// examples/contour/02-role-contour.yaml, and returns its directory.
+func WriteCheckout(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+	return dir
+}
+
+// writeFiles writes files, keyed by slash-separated path, below dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
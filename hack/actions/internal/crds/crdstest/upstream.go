@@ -0,0 +1,78 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crdstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ModuleDir returns the directory of a module version in the Go module cache, such as the Contour
+// source of a release, downloading it through the Go module proxy if needed. The test is skipped if
+// the module cannot be downloaded.
+func ModuleDir(t *testing.T, module, version string) string {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("go", "mod", "download", "-json", module+"@"+version)
+	cmd.Dir = t.TempDir()
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Skipf("Failed to download module %s@%s: %v: %s", module, version, err, stderr.String())
+	}
+
+	var info struct {
+		Dir string
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	return info.Dir
+}
+
+// GatewayAPIBundle returns the install bundle of a Gateway API release channel, such as standard,
+// built from the release source in dir the way its hack/build-install-yaml.sh does, with the given
+// copyright year.
+func GatewayAPIBundle(t *testing.T, dir, channel, year string) []byte {
+	t.Helper()
+
+	boilerplate, err := os.ReadFile(filepath.Join(dir, "hack", "boilerplate", "boilerplate.sh.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(strings.ReplaceAll(string(boilerplate), "YEAR", year))
+	buf.WriteString("#\n# Gateway API " + strings.ToUpper(channel[:1]) + channel[1:] + " channel install\n#\n")
+
+	files, err := filepath.Glob(filepath.Join(dir, "config", "crd", channel, "gateway*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.WriteString("---\n#\n# " + path.Join("config", "crd", channel, filepath.Base(file)) + "\n#\n")
+		buf.Write(data)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,89 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// Template delimiters in upstream content are replaced by actions printing them. The delimiters are
+// quoted with backticks rather than double quotes, so that they stay valid in double-quoted YAML strings.
+var (
+	escapedLeftDelim  = []byte("{{ `{{` }}")
+	escapedRightDelim = []byte("{{ `}}` }}")
+)
+
+// escapeTemplateDelimiters escapes the Helm template delimiters {{ and }} in upstream content, such as
+// CEL validation messages or descriptions, so that the chart template renders it as is. It returns the
+// number of delimiters escaped.
+func escapeTemplateDelimiters(data []byte) ([]byte, int) {
+	if !bytes.Contains(data, []byte("{{")) && !bytes.Contains(data, []byte("}}")) {
+		return data, 0
+	}
+
+	out := make([]byte, 0, len(data))
+	n := 0
+	for i := 0; i < len(data); i++ {
+		if i+1 < len(data) && data[i] == data[i+1] && (data[i] == '{' || data[i] == '}') {
+			if data[i] == '{' {
+				out = append(out, escapedLeftDelim...)
+			} else {
+				out = append(out, escapedRightDelim...)
+			}
+			n++
+			i++
+			continue
+		}
+		out = append(out, data[i])
+	}
+	return out, n
+}
+
+// unescapeTemplateDelimiters reverts escapeTemplateDelimiters, to read the upstream content of a template.
+func unescapeTemplateDelimiters(data []byte) []byte {
+	data = bytes.ReplaceAll(data, escapedLeftDelim, []byte("{{"))
+	return bytes.ReplaceAll(data, escapedRightDelim, []byte("}}"))
+}
+
+// verifyEscaped renders escaped content as Helm would, with text/template and the Sprig functions,
+// and checks that it renders to the upstream content byte for byte.
+func verifyEscaped(escaped, upstream []byte) error {
+	tmpl, err := template.New("crds").Funcs(sprig.TxtFuncMap()).Option("missingkey=zero").Parse(string(escaped))
+	if err != nil {
+		return fmt.Errorf("failed to parse escaped CRDs as a template: %w", err)
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, nil); err != nil {
+		return fmt.Errorf("failed to render escaped CRDs: %w", err)
+	}
+	if !bytes.Equal(rendered.Bytes(), upstream) {
+		return fmt.Errorf("escaped CRDs render differently from upstream at byte %d", mismatchOffset(rendered.Bytes(), upstream))
+	}
+	return nil
+}
+
+// mismatchOffset returns the offset of the first byte that differs between a and b.
+func mismatchOffset(a, b []byte) int {
+	n := min(len(a), len(b))
+	for i := range n {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
@@ -0,0 +1,111 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds/crdstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestEscapeTemplateDelimiters(t *testing.T) {
+	tests := map[string]struct {
+		data  string
+		want  string
+		count int
+	}{
+		"no delimiters": {
+			data: "description: a {single} brace\n",
+			want: "description: a {single} brace\n",
+		},
+		"template expression": {
+			data:  "message: must not contain {{ .Values }}\n",
+			want:  "message: must not contain {{ `{{` }} .Values {{ `}}` }}\n",
+			count: 2,
+		},
+		"double-quoted string": {
+			data:  "message: \"use {{ and }} for placeholders\"\n",
+			want:  "message: \"use {{ `{{` }} and {{ `}}` }} for placeholders\"\n",
+			count: 2,
+		},
+		"trim markers": {
+			data:  "description: |\n  {{- if x -}}\n",
+			want:  "description: |\n  {{ `{{` }}- if x -{{ `}}` }}\n",
+			count: 2,
+		},
+		"odd braces": {
+			data:  "rule: self.matches('^{{{[a-z]}}}$')\n",
+			want:  "rule: self.matches('^{{ `{{` }}{[a-z]{{ `}}` }}}$')\n",
+			count: 2,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			escaped, n := escapeTemplateDelimiters([]byte(tc.data))
+			assert.Equal(t, tc.want, string(escaped))
+			assert.Equal(t, tc.count, n)
+
+			// The escaped content renders to the upstream content and still reads as the same YAML.
+			require.NoError(t, verifyEscaped(escaped, []byte(tc.data)))
+			assert.Equal(t, tc.data, string(unescapeTemplateDelimiters(escaped)))
+			var want, got map[string]any
+			require.NoError(t, yaml.Unmarshal([]byte(tc.data), &want))
+			require.NoError(t, yaml.Unmarshal(unescapeTemplateDelimiters(escaped), &got))
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestVerifyEscaped(t *testing.T) {
+	require.NoError(t, verifyEscaped([]byte("{{ `{{` }} x | upper {{ `}}` }}"), []byte("{{ x | upper }}")))
+
+	err := verifyEscaped([]byte("a {{ .x }}"), []byte("a {{ .x }}"))
+	require.ErrorContains(t, err, "escaped CRDs render differently from upstream at byte 2")
+
+	err = verifyEscaped([]byte("a {{ x"), []byte("a {{ x"))
+	require.ErrorContains(t, err, "failed to parse escaped CRDs as a template")
+}
+
+func TestRunEscapesTemplateDelimiters(t *testing.T) {
+	crd := "---\napiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: httpproxies.projectcontour.io\n" +
+		"spec:\n  names:\n    kind: HTTPProxy\n    plural: httpproxies\n  versions:\n  - name: v1\n    storage: true\n" +
+		"    schema:\n      openAPIV3Schema:\n        description: \"HTTPProxy routes {{ host }} requests.\"\n"
+	srv := crdstest.NewServer(t, map[string][]byte{
+		"1.33.6": crdstest.Tarball(t, "1.33.6", map[string]string{"examples/contour/01-crds.yaml": crd}),
+	})
+	chartPath := writeChart(t, "1.33.6")
+	files := []File{{Name: "Contour", Source: "examples/contour/01-crds.yaml", Destination: "templates/crds/contour", Split: true}}
+
+	report := run(t, Options{ChartPath: chartPath, Files: files, Source: &ArchiveSource{URL: srv.SourceURL()}})
+	assert.Equal(t, []Change{{Set: "Contour", CRD: "httpproxies.projectcontour.io", Description: "added CRD"}}, report.Changes)
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(chartPath), "templates", "crds", "contour", "httpproxies.projectcontour.io.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "description: \"HTTPProxy routes {{ `{{` }} host {{ `}}` }} requests.\"\n")
+
+	// The escaped templates read as the upstream CRDs.
+	report = run(t, Options{ChartPath: chartPath, Files: files, Source: &ArchiveSource{URL: srv.SourceURL()}, Check: true})
+	assert.Empty(t, report.Changes)
+
+	// The Artifact Hub annotation lists the upstream description.
+	chart, err := os.ReadFile(chartPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(chart), "      description: HTTPProxy routes {{ host }} requests.\n")
+}
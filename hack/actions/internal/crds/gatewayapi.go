@@ -0,0 +1,119 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// ReleaseGatewayAPI is the File.Release of the CRD bundles of the Gateway API release the Contour source depends on.
+const ReleaseGatewayAPI = "gateway-api"
+
+// DefaultGatewayAPIURL is the location of the Gateway API release assets, formatted with the Gateway API version and the asset name.
+const DefaultGatewayAPIURL = "https://github.com/kubernetes-sigs/gateway-api/releases/download/v%s/%s"
+
+// GatewayAPIRelease provides the CRD bundles of a Gateway API release, such as standard-install.yaml.
+type GatewayAPIRelease struct {
+	// URL is the location of the release assets, formatted with the Gateway API version and the asset name.
+	// Defaults to DefaultGatewayAPIURL.
+	URL string
+
+	// Dir is a local directory holding the release assets, for offline runs. If set, nothing is downloaded
+	// and the Gateway API version is ignored.
+	Dir string
+
+	// Download verifies and caches the release assets.
+	Download
+}
+
+// open returns the content of the release asset of the given Gateway API version.
+func (r *GatewayAPIRelease) open(ctx context.Context, version, asset, tmpDir string) ([]byte, error) {
+	if r.Dir != "" {
+		data, err := os.ReadFile(filepath.Join(r.Dir, asset))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Gateway API release asset: %w", err)
+		}
+		return data, nil
+	}
+
+	url := DefaultGatewayAPIURL
+	if r.URL != "" {
+		url = r.URL
+	}
+	url = fmt.Sprintf(url, version, asset)
+
+	downloadPath := filepath.Join(tmpDir, "gateway-api-"+version+"-"+asset)
+	log.Infof("Downloading Gateway API release asset %s to %s", url, downloadPath)
+	if err := r.get(ctx, url, downloadPath, true); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(downloadPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", downloadPath, err)
+	}
+	return data, nil
+}
+
+// gatewayAPIModule is the module path of Gateway API.
+const gatewayAPIModule = "sigs.k8s.io/gateway-api"
+
+// GatewayAPIVersion returns the version of the Gateway API module required by the go.mod
+// of the Contour source, such as 1.3.0, after its replace directives. Versions that are not
+// release tags, such as pseudo-versions, are rejected since they have no release assets.
+func GatewayAPIVersion(tree fs.FS) (string, error) {
+	data, err := fs.ReadFile(tree, "go.mod")
+	if err != nil {
+		return "", fmt.Errorf("failed to read Contour go.mod: %w", err)
+	}
+	f, err := modfile.Parse("go.mod", data, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Contour go.mod: %w", err)
+	}
+
+	var version string
+	for _, r := range f.Require {
+		if r.Mod.Path == gatewayAPIModule {
+			version = r.Mod.Version
+		}
+	}
+	if version == "" {
+		return "", errors.New(gatewayAPIModule + " not found in Contour go.mod")
+	}
+
+	for _, r := range f.Replace {
+		if r.Old.Path != gatewayAPIModule || (r.Old.Version != "" && r.Old.Version != version) {
+			continue
+		}
+		if r.New.Path != gatewayAPIModule {
+			return "", fmt.Errorf("%s is replaced with %s in Contour go.mod, use -gateway-api-dir to provide its CRD bundles", gatewayAPIModule, r.New.Path)
+		}
+		version = r.New.Version
+	}
+
+	if !semver.IsValid(version) || module.IsPseudoVersion(version) || semver.Build(version) != "" {
+		return "", fmt.Errorf("%s %s in Contour go.mod is not a release tag, use -gateway-api-dir to provide its CRD bundles", gatewayAPIModule, version)
+	}
+	return strings.TrimPrefix(version, "v"), nil
+}
@@ -0,0 +1,200 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds/crdstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testContourGoMod = "module github.com/projectcontour/contour\n\ngo 1.24\n\nrequire (\n" +
+	"\tgithub.com/stretchr/testify v1.10.0\n\tsigs.k8s.io/gateway-api v1.3.0\n)\n"
+
+var testGatewayAPIFiles = []File{
+	{
+		Name:        "Gateway API standard channel",
+		Release:     ReleaseGatewayAPI,
+		Source:      "standard-install.yaml",
+		Destination: "templates/crds/gateway-api/standard",
+		Conditional: `and .Values.gatewayAPI.manageCRDs (eq .Values.gatewayAPI.channel "standard")`,
+		Split:       true,
+		Toggles:     ".Values.gatewayAPI.crds",
+	},
+	{
+		Name:        "Gateway API experimental channel",
+		Release:     ReleaseGatewayAPI,
+		Source:      "experimental-install.yaml",
+		Destination: "templates/crds/gateway-api/experimental",
+		Conditional: `and .Values.gatewayAPI.manageCRDs (eq .Values.gatewayAPI.channel "experimental")`,
+		Split:       true,
+		Toggles:     ".Values.gatewayAPI.crds",
+	},
+}
+
+const testTCPRouteCRDs = "---\napiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: tcproutes.gateway.networking.k8s.io\n" +
+	"spec:\n  names:\n    kind: TCPRoute\n    plural: tcproutes\n  versions:\n  - name: v1alpha2\n    storage: true\n"
+
+func TestRunGatewayAPI(t *testing.T) {
+	gatewayCRDs := "---\napiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: gateways.gateway.networking.k8s.io\n" +
+		"spec:\n  names:\n    kind: Gateway\n    plural: gateways\n  versions:\n  - name: v1\n    storage: true\n"
+	srv := crdstest.NewServer(t, map[string][]byte{
+		"1.33.6": crdstest.Tarball(t, "1.33.6", map[string]string{"go.mod": testContourGoMod}),
+	})
+	srv.AddGatewayAPIAsset("1.3.0", "standard-install.yaml", []byte(gatewayCRDs))
+	srv.AddGatewayAPIAsset("1.3.0", "experimental-install.yaml", []byte(gatewayCRDs+testTCPRouteCRDs))
+
+	chartPath := writeChart(t, "1.33.6")
+	lockfilePath := filepath.Join(t.TempDir(), "upstream.lock")
+	report := run(t, Options{
+		ChartPath:  chartPath,
+		Files:      testGatewayAPIFiles,
+		Source:     &ArchiveSource{URL: srv.SourceURL()},
+		GatewayAPI: &GatewayAPIRelease{URL: srv.GatewayAPIURL(), Download: Download{LockfilePath: lockfilePath}},
+	})
+
+	chartDir := filepath.Dir(chartPath)
+	data, err := os.ReadFile(filepath.Join(chartDir, "templates/crds/gateway-api/standard/gateways.gateway.networking.k8s.io.yaml"))
+	require.NoError(t, err)
+	condition := `and (and .Values.gatewayAPI.manageCRDs (eq .Values.gatewayAPI.channel "standard")) (dig "gateways" true (.Values.gatewayAPI.crds | default dict))`
+	assert.Equal(t, "# Conditional: "+condition+"\n{{- if "+condition+" }}\n"+gatewayCRDs+"{{- end }}\n", string(data))
+	_, err = os.Stat(filepath.Join(chartDir, "templates/crds/gateway-api/experimental/tcproutes.gateway.networking.k8s.io.yaml"))
+	require.NoError(t, err)
+
+	// Both release assets are pinned.
+	lockfile, err := readLockfile(lockfilePath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		srv.URL + "/gateway-api/releases/download/v1.3.0/standard-install.yaml",
+		srv.URL + "/gateway-api/releases/download/v1.3.0/experimental-install.yaml",
+	}, keys(lockfile.Sources))
+
+	// Gateways are listed once in the Artifact Hub annotation.
+	data, err = os.ReadFile(chartPath)
+	require.NoError(t, err)
+	assert.Equal(t, "annotations:\n"+
+		"  artifacthub.io/crds: |\n"+
+		"    - kind: Gateway\n"+
+		"      version: v1\n"+
+		"      name: gateways.gateway.networking.k8s.io\n"+
+		"      displayName: Gateway\n"+
+		"    - kind: TCPRoute\n"+
+		"      version: v1alpha2\n"+
+		"      name: tcproutes.gateway.networking.k8s.io\n"+
+		"      displayName: TCPRoute\n"+
+		"  category: Infrastructure\n"+
+		"appVersion: 1.33.6\n"+
+		"version: 0.7.0\n", string(data))
+
+	assert.Equal(t, []Change{
+		{Set: "Gateway API standard channel", CRD: "gateways.gateway.networking.k8s.io", Description: "added CRD"},
+		{Set: "Gateway API experimental channel", CRD: "gateways.gateway.networking.k8s.io", Description: "added CRD"},
+		{Set: "Gateway API experimental channel", CRD: "tcproutes.gateway.networking.k8s.io", Description: "added CRD"},
+	}, report.Changes)
+}
+
+func TestRunGatewayAPIDir(t *testing.T) {
+	checkout := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(checkout, "go.mod"), []byte(testContourGoMod), 0o600))
+	assets := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(assets, "standard-install.yaml"), []byte(testTCPRouteCRDs), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(assets, "experimental-install.yaml"), []byte(testTCPRouteCRDs), 0o600))
+
+	chartPath := writeChart(t, "1.33.6")
+	run(t, Options{
+		ChartPath:  chartPath,
+		Files:      testGatewayAPIFiles,
+		Source:     &DirSource{Path: checkout},
+		GatewayAPI: &GatewayAPIRelease{Dir: assets},
+	})
+
+	_, err := os.Stat(filepath.Join(filepath.Dir(chartPath), "templates/crds/gateway-api/standard/tcproutes.gateway.networking.k8s.io.yaml"))
+	require.NoError(t, err)
+}
+
+func TestGatewayAPIVersion(t *testing.T) {
+	const module = "module github.com/projectcontour/contour\n\n"
+	tests := map[string]struct {
+		goMod         string
+		want          string
+		wantErrString string
+	}{
+		"require block":  {goMod: testContourGoMod, want: "1.3.0"},
+		"single require": {goMod: module + "require sigs.k8s.io/gateway-api v1.3.0\n", want: "1.3.0"},
+		"indirect":       {goMod: module + "require (\n\tsigs.k8s.io/gateway-api v1.3.0 // indirect\n)\n", want: "1.3.0"},
+		"pre-release":    {goMod: module + "require sigs.k8s.io/gateway-api v1.4.0-rc.1\n", want: "1.4.0-rc.1"},
+		"replace": {
+			goMod: module + "require sigs.k8s.io/gateway-api v1.3.0\n\nreplace sigs.k8s.io/gateway-api => sigs.k8s.io/gateway-api v1.3.1\n",
+			want:  "1.3.1",
+		},
+		"replace of the required version": {
+			goMod: module + "require sigs.k8s.io/gateway-api v1.3.0\n\nreplace sigs.k8s.io/gateway-api v1.3.0 => sigs.k8s.io/gateway-api v1.3.1\n",
+			want:  "1.3.1",
+		},
+		"replace of another version": {
+			goMod: module + "require sigs.k8s.io/gateway-api v1.3.0\n\nreplace sigs.k8s.io/gateway-api v1.2.0 => sigs.k8s.io/gateway-api v1.2.1\n",
+			want:  "1.3.0",
+		},
+		"replace with a fork": {
+			goMod:         module + "require sigs.k8s.io/gateway-api v1.3.0\n\nreplace sigs.k8s.io/gateway-api => github.com/example/gateway-api v1.3.0\n",
+			wantErrString: "sigs.k8s.io/gateway-api is replaced with github.com/example/gateway-api in Contour go.mod",
+		},
+		"replace with a directory": {
+			goMod:         module + "require sigs.k8s.io/gateway-api v1.3.0\n\nreplace sigs.k8s.io/gateway-api => ../gateway-api\n",
+			wantErrString: "sigs.k8s.io/gateway-api is replaced with ../gateway-api in Contour go.mod",
+		},
+		"pseudo-version": {
+			goMod:         module + "require sigs.k8s.io/gateway-api v1.3.1-0.20250612133521-7e5a9b1f0d2c\n",
+			wantErrString: "sigs.k8s.io/gateway-api v1.3.1-0.20250612133521-7e5a9b1f0d2c in Contour go.mod is not a release tag",
+		},
+		"replace with a pseudo-version": {
+			goMod:         module + "require sigs.k8s.io/gateway-api v1.3.0\n\nreplace sigs.k8s.io/gateway-api => sigs.k8s.io/gateway-api v0.0.0-20250612133521-7e5a9b1f0d2c\n",
+			wantErrString: "is not a release tag",
+		},
+		"missing": {
+			goMod:         module,
+			wantErrString: "sigs.k8s.io/gateway-api not found in Contour go.mod",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(tc.goMod), 0o600))
+
+			version, err := GatewayAPIVersion(os.DirFS(dir))
+			if tc.wantErrString != "" {
+				require.ErrorContains(t, err, tc.wantErrString)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, version)
+		})
+	}
+}
+
+func TestRunGatewayAPIMissingGoMod(t *testing.T) {
+	_, err := Run(context.Background(), Options{
+		ChartPath:  writeChart(t, "1.33.6"),
+		Files:      testGatewayAPIFiles,
+		Source:     &DirSource{Path: t.TempDir()},
+		GatewayAPI: &GatewayAPIRelease{Dir: t.TempDir()},
+	})
+	require.ErrorContains(t, err, "failed to read Contour go.mod")
+}
@@ -0,0 +1,105 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLockfilePath is the lockfile pinning the checksums of the upstream files, relative to the repository root.
+const DefaultLockfilePath = "./hack/actions/upstream.lock"
+
+const lockfileHeader = `# SHA-256 checksums of the upstream files the chart CRDs are copied from, keyed by URL.
+# synchronize-crds adds the checksum of a file it has not seen yet, and fails if a known file changed.
+`
+
+// lockfile pins the checksums of upstream files.
+type lockfile struct {
+	Sources map[string]string `yaml:"sources"`
+}
+
+// readLockfile reads the lockfile at path. A missing lockfile is empty.
+func readLockfile(path string) (*lockfile, error) {
+	lock := &lockfile{Sources: map[string]string{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal yaml from %s: %w", path, err)
+	}
+	if lock.Sources == nil {
+		lock.Sources = map[string]string{}
+	}
+	return lock, nil
+}
+
+// write writes the lockfile to path, with the sources sorted by URL.
+func (l *lockfile) write(path string) error {
+	var buf bytes.Buffer
+	buf.WriteString(lockfileHeader)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(l); err != nil {
+		return fmt.Errorf("failed to marshal lockfile: %w", err)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil { //nolint:gosec // G306: repository files are intentionally world-readable
+		return fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+	return nil
+}
+
+// verifyChecksum compares the checksum of the file downloaded from url with the one pinned in the
+// lockfile at path. A file that is not pinned yet is rejected if frozen is set, added to the lockfile
+// if pin is set, and accepted as is otherwise.
+func verifyChecksum(path, url, checksum string, frozen, pin bool) error {
+	lock, err := readLockfile(path)
+	if err != nil {
+		return err
+	}
+
+	pinned, ok := lock.Sources[url]
+	switch {
+	case ok && pinned == checksum:
+		log.Infof("Verified %s checksum %s", url, checksum)
+		return nil
+	case ok:
+		return fmt.Errorf("checksum mismatch for %s: %s pins %s, downloaded %s", url, path, pinned, checksum)
+	case frozen:
+		return fmt.Errorf("checksum of %s not found in %s", url, path)
+	case !pin:
+		log.Warnf("Checksum %s of %s is not pinned in %s", checksum, url, path)
+		return nil
+	}
+
+	lock.Sources[url] = checksum
+	if err := lock.write(path); err != nil {
+		return err
+	}
+	log.Infof("Pinned %s checksum %s in %s", url, checksum, path)
+
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommittedLockfile(t *testing.T) {
+	// DefaultLockfilePath is relative to the repository root.
+	path := filepath.Join("..", "..", "..", "..", DefaultLockfilePath)
+	committed, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	lock, err := readLockfile(path)
+	require.NoError(t, err)
+	require.NotEmpty(t, lock.Sources)
+	for url, checksum := range lock.Sources {
+		assert.Regexp(t, `^https://`, url)
+		assert.Regexp(t, `^sha256:[0-9a-f]{64}$`, checksum, url)
+	}
+
+	// The committed lockfile is formatted the way synchronize-crds writes it.
+	written := filepath.Join(t.TempDir(), "upstream.lock")
+	require.NoError(t, lock.write(written))
+	data, err := os.ReadFile(written)
+	require.NoError(t, err)
+	assert.Equal(t, string(committed), string(data))
+
+	// Check mode verifies the pinned files, accepts new ones with a warning, and never writes the lockfile.
+	check := Download{LockfilePath: path, ReadOnlyLockfile: true}
+	for url, checksum := range lock.Sources {
+		require.NoError(t, check.verify(url, checksum))
+		require.ErrorContains(t, check.verify(url, "sha256:0000"), "checksum mismatch for "+url)
+	}
+	require.NoError(t, check.verify("https://example.com/new.tar.gz", "sha256:0000"))
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(committed), string(data))
+
+	// With -frozen-lockfile, new files are rejected.
+	check.FrozenLockfile = true
+	require.ErrorContains(t, check.verify("https://example.com/new.tar.gz", "sha256:0000"), "not found in "+path)
+}
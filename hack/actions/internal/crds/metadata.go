@@ -0,0 +1,173 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// injection is a block of template lines inserted before a line of the source file.
+type injection struct {
+	line  int
+	lines []string
+}
+
+// injectMetadata adds the keep policy, labels and annotations of the config to the metadata of every
+// CustomResourceDefinition in data. The upstream labels and annotations are kept byte for byte after the
+// injected template lines, so that the CRDs still read as upstream YAML once the template lines are stripped.
+func (f File) injectMetadata(data []byte) ([]byte, error) {
+	if f.Keep == "" && f.Labels == "" && f.Annotations == "" {
+		return data, nil
+	}
+
+	var injections []injection
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal yaml: %w", err)
+		}
+		docInjections, err := f.metadataInjections(&doc)
+		if err != nil {
+			return nil, err
+		}
+		injections = append(injections, docInjections...)
+	}
+
+	// Insert the blocks from the bottom up, so that the line numbers of the next ones stay valid.
+	// Blocks inserted at the same line keep their order.
+	sort.SliceStable(injections, func(i, j int) bool { return injections[i].line < injections[j].line })
+	lines := strings.SplitAfter(string(data), "\n")
+	for i := len(injections) - 1; i >= 0; i-- {
+		in := injections[i]
+		block := make([]string, 0, len(in.lines))
+		for _, l := range in.lines {
+			block = append(block, l+"\n")
+		}
+		lines = append(lines[:in.line], append(block, lines[in.line:]...)...)
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// metadataInjections returns the template lines to insert into the metadata of a CustomResourceDefinition
+// document, or none for other documents.
+func (f File) metadataInjections(doc *yaml.Node) ([]injection, error) {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if kind := mappingValue(root, "kind"); kind == nil || kind.Value != "CustomResourceDefinition" {
+		return nil, nil
+	}
+	metadata := mappingValue(root, "metadata")
+	if metadata == nil || metadata.Kind != yaml.MappingNode || metadata.Style&yaml.FlowStyle != 0 || len(metadata.Content) == 0 {
+		return nil, errors.New("cannot inject metadata into CustomResourceDefinition without block metadata")
+	}
+	name := ""
+	if n := mappingValue(metadata, "name"); n != nil {
+		name = n.Value
+	}
+	indent := metadata.Content[0].Column - 1
+
+	var injections []injection
+	addBlock := func(key string, block func(indent int) []string) error {
+		// Upstream entries are kept after the injected ones.
+		for i := 0; i < len(metadata.Content); i += 2 {
+			if metadata.Content[i].Value != key {
+				continue
+			}
+			value := metadata.Content[i+1]
+			if value.Kind != yaml.MappingNode || value.Style&yaml.FlowStyle != 0 || len(value.Content) == 0 {
+				return fmt.Errorf("cannot inject %s into CustomResourceDefinition %s: %s is not a block mapping", key, name, key)
+			}
+			injections = append(injections, injection{
+				line:  metadata.Content[i].Line,
+				lines: block(value.Content[0].Column - 1),
+			})
+			return nil
+		}
+		// A missing key is added before the first key that sorts after it, as upstream metadata keys are sorted.
+		line := metadata.Content[0].Line - 1
+		for i := 0; i < len(metadata.Content); i += 2 {
+			if metadata.Content[i].Value > key {
+				line = metadata.Content[i].Line - 1
+				break
+			}
+		}
+		injections = append(injections, injection{
+			line:  line,
+			lines: append([]string{strings.Repeat(" ", indent) + key + ":"}, block(indent+2)...),
+		})
+		return nil
+	}
+
+	if f.Keep != "" || f.Annotations != "" {
+		err := addBlock("annotations", func(indent int) []string {
+			var lines []string
+			if f.Keep != "" {
+				pad := strings.Repeat(" ", indent)
+				lines = append(lines,
+					fmt.Sprintf("%s{{- if %s }}", pad, f.Keep),
+					pad+"helm.sh/resource-policy: keep",
+					pad+"{{- end }}",
+				)
+			}
+			return append(lines, includeLines(f.Annotations, indent)...)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if f.Labels != "" {
+		if err := addBlock("labels", func(indent int) []string { return includeLines(f.Labels, indent) }); err != nil {
+			return nil, err
+		}
+	}
+	return injections, nil
+}
+
+// includeLines returns the template lines including the named template, which renders a YAML mapping,
+// at the given indentation. Nothing is rendered if the template renders nothing.
+func includeLines(name string, indent int) []string {
+	if name == "" {
+		return nil
+	}
+	pad := strings.Repeat(" ", indent)
+	return []string{
+		fmt.Sprintf("%s{{- with include %q $ }}", pad, name),
+		fmt.Sprintf("%s{{- . | nindent %d }}", pad, indent),
+		pad + "{{- end }}",
+	}
+}
+
+// mappingValue returns the value of key in a mapping node, or nil if it is missing.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/artifacthub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjectMetadata(t *testing.T) {
+	metadata := File{Keep: ".Values.contour.keepCRDs", Labels: "contour.crds.labels", Annotations: "contour.crds.annotations"}
+
+	tests := map[string]struct {
+		file    File
+		data    string
+		want    string
+		wantErr string
+	}{
+		"nothing to inject": {
+			data: testHTTPProxyCRDs,
+			want: testHTTPProxyCRDs,
+		},
+		"upstream annotations and labels": {
+			file: metadata,
+			data: "---\nkind: CustomResourceDefinition\nmetadata:\n  annotations:\n    controller-gen.kubebuilder.io/version: v0.18.0\n" +
+				"  labels:\n    gateway.networking.k8s.io/policy: Direct\n  name: httpproxies.projectcontour.io\n",
+			want: "---\nkind: CustomResourceDefinition\nmetadata:\n  annotations:\n" +
+				"    {{- if .Values.contour.keepCRDs }}\n" +
+				"    helm.sh/resource-policy: keep\n" +
+				"    {{- end }}\n" +
+				"    {{- with include \"contour.crds.annotations\" $ }}\n" +
+				"    {{- . | nindent 4 }}\n" +
+				"    {{- end }}\n" +
+				"    controller-gen.kubebuilder.io/version: v0.18.0\n" +
+				"  labels:\n" +
+				"    {{- with include \"contour.crds.labels\" $ }}\n" +
+				"    {{- . | nindent 4 }}\n" +
+				"    {{- end }}\n" +
+				"    gateway.networking.k8s.io/policy: Direct\n" +
+				"  name: httpproxies.projectcontour.io\n",
+		},
+		"missing annotations and labels": {
+			file: metadata,
+			data: "---\nkind: CustomResourceDefinition\nmetadata:\n  name: httpproxies.projectcontour.io\nspec: {}\n",
+			want: "---\nkind: CustomResourceDefinition\nmetadata:\n" +
+				"  annotations:\n" +
+				"    {{- if .Values.contour.keepCRDs }}\n" +
+				"    helm.sh/resource-policy: keep\n" +
+				"    {{- end }}\n" +
+				"    {{- with include \"contour.crds.annotations\" $ }}\n" +
+				"    {{- . | nindent 4 }}\n" +
+				"    {{- end }}\n" +
+				"  labels:\n" +
+				"    {{- with include \"contour.crds.labels\" $ }}\n" +
+				"    {{- . | nindent 4 }}\n" +
+				"    {{- end }}\n" +
+				"  name: httpproxies.projectcontour.io\nspec: {}\n",
+		},
+		"missing labels": {
+			file: File{Labels: "contour.crds.labels"},
+			data: "---\nkind: CustomResourceDefinition\nmetadata:\n  annotations:\n    a: b\n  creationTimestamp: null\n  name: gateways.gateway.networking.k8s.io\n",
+			want: "---\nkind: CustomResourceDefinition\nmetadata:\n  annotations:\n    a: b\n  creationTimestamp: null\n" +
+				"  labels:\n    {{- with include \"contour.crds.labels\" $ }}\n    {{- . | nindent 4 }}\n    {{- end }}\n" +
+				"  name: gateways.gateway.networking.k8s.io\n",
+		},
+		"keep only": {
+			file: File{Keep: ".Values.contour.keepCRDs"},
+			data: "# License header.\n---\nkind: CustomResourceDefinition\nmetadata:\n    name: httpproxies.projectcontour.io\n",
+			want: "# License header.\n---\nkind: CustomResourceDefinition\nmetadata:\n" +
+				"    annotations:\n" +
+				"      {{- if .Values.contour.keepCRDs }}\n" +
+				"      helm.sh/resource-policy: keep\n" +
+				"      {{- end }}\n" +
+				"    name: httpproxies.projectcontour.io\n",
+		},
+		"every CRD of a multi-document file": {
+			file: File{Labels: "contour.crds.labels"},
+			data: "---\nkind: CustomResourceDefinition\nmetadata:\n  name: a\n---\nkind: Namespace\nmetadata:\n  name: b\n" +
+				"---\nkind: CustomResourceDefinition\nmetadata:\n  name: c\n",
+			want: "---\nkind: CustomResourceDefinition\nmetadata:\n" +
+				"  labels:\n    {{- with include \"contour.crds.labels\" $ }}\n    {{- . | nindent 4 }}\n    {{- end }}\n" +
+				"  name: a\n---\nkind: Namespace\nmetadata:\n  name: b\n" +
+				"---\nkind: CustomResourceDefinition\nmetadata:\n" +
+				"  labels:\n    {{- with include \"contour.crds.labels\" $ }}\n    {{- . | nindent 4 }}\n    {{- end }}\n" +
+				"  name: c\n",
+		},
+		"flow annotations": {
+			file:    metadata,
+			data:    "---\nkind: CustomResourceDefinition\nmetadata:\n  annotations: {}\n  name: httpproxies.projectcontour.io\n",
+			wantErr: "cannot inject annotations into CustomResourceDefinition httpproxies.projectcontour.io: annotations is not a block mapping",
+		},
+		"flow metadata": {
+			file:    metadata,
+			data:    "---\nkind: CustomResourceDefinition\nmetadata: {name: httpproxies.projectcontour.io}\n",
+			wantErr: "cannot inject metadata into CustomResourceDefinition without block metadata",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.file.injectMetadata([]byte(tc.data))
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(got))
+		})
+	}
+}
+
+// TestInjectMetadataParses checks that the CRDs still parse once the injected template lines are stripped,
+// as the report and the Artifact Hub annotation read them.
+func TestInjectMetadataParses(t *testing.T) {
+	f := File{Keep: ".Values.contour.keepCRDs", Labels: "contour.crds.labels", Annotations: "contour.crds.annotations"}
+	data, err := f.injectMetadata([]byte(testExtensionServiceCRDs + testHTTPProxyCRDs))
+	require.NoError(t, err)
+
+	crds, err := artifacthub.ParseCRDs(artifacthub.StripTemplateLines(data))
+	require.NoError(t, err)
+	assert.Len(t, crds, 2)
+
+	versions, err := parseCRDVersions(data)
+	require.NoError(t, err)
+	want, err := parseCRDVersions([]byte(testExtensionServiceCRDs + testHTTPProxyCRDs))
+	require.NoError(t, err)
+	assert.Equal(t, want, versions)
+}
@@ -0,0 +1,291 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/artifacthub"
+	"gopkg.in/yaml.v3"
+)
+
+// Change is a semantic change of a CustomResourceDefinition between the committed and the synchronized CRDs.
+type Change struct {
+	// Set is the name of the CRD file the change is in, such as "Gateway API standard channel",
+	// since a CRD can be shipped in several variants.
+	Set string
+
+	// CRD is the name of the CustomResourceDefinition, such as httpproxies.projectcontour.io.
+	CRD string
+
+	// Version is the API version the change applies to, empty for changes of the whole CRD.
+	Version string
+
+	// Description describes the change, such as "removed field spec.virtualhost.tls".
+	Description string
+
+	// Breaking is true for changes that can break existing resources or clients:
+	// removed CRDs, versions and fields, versions no longer served, and storage version changes.
+	Breaking bool
+}
+
+func (c Change) String() string {
+	s := c.CRD
+	if c.Version != "" {
+		s += " " + c.Version
+	}
+	s += ": " + c.Description
+	if c.Breaking {
+		s += " (breaking)"
+	}
+	return s
+}
+
+// Report lists the semantic changes of the chart CRDs, so that a synchronization that rewrites thousands
+// of lines can be reviewed.
+type Report struct {
+	Changes []Change
+}
+
+// Breaking reports whether any change is breaking.
+func (r *Report) Breaking() bool {
+	for _, c := range r.Changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the changes one per line below the name of their set, or a note that there are none.
+func (r *Report) WriteText(w io.Writer) error {
+	if len(r.Changes) == 0 {
+		_, err := io.WriteString(w, "No CRD changes.\n")
+		return err
+	}
+	for i, c := range r.Changes {
+		if i == 0 || c.Set != r.Changes[i-1].Set {
+			if _, err := fmt.Fprintf(w, "%s:\n", c.Set); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "  %s\n", c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// crdSchema is the part of a CustomResourceDefinition the report is built from.
+type crdSchema struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Versions []struct {
+			Name    string `yaml:"name"`
+			Served  bool   `yaml:"served"`
+			Storage bool   `yaml:"storage"`
+			Schema  struct {
+				OpenAPIV3Schema map[string]any `yaml:"openAPIV3Schema"`
+			} `yaml:"schema"`
+		} `yaml:"versions"`
+	} `yaml:"spec"`
+}
+
+// crdVersion is an API version of a CRD, with its schema flattened into fields.
+type crdVersion struct {
+	Served  bool
+	Storage bool
+
+	// Fields maps the path of every field of the schema, such as spec.routes[].conditions, to whether it is required.
+	Fields map[string]bool
+}
+
+// parseCRDVersions returns the versions of the CustomResourceDefinitions in the given chart templates, by CRD name.
+func parseCRDVersions(files ...[]byte) (map[string]map[string]crdVersion, error) {
+	crds := map[string]map[string]crdVersion{}
+	for _, data := range files {
+		dec := yaml.NewDecoder(bytes.NewReader(unescapeTemplateDelimiters(artifacthub.StripTemplateLines(data))))
+		for {
+			var doc crdSchema
+			err := dec.Decode(&doc)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if doc.Kind != "CustomResourceDefinition" {
+				continue
+			}
+
+			versions := map[string]crdVersion{}
+			for _, v := range doc.Spec.Versions {
+				fields := map[string]bool{}
+				schemaFields(v.Schema.OpenAPIV3Schema, "", fields)
+				versions[v.Name] = crdVersion{Served: v.Served, Storage: v.Storage, Fields: fields}
+			}
+			crds[doc.Metadata.Name] = versions
+		}
+	}
+	return crds, nil
+}
+
+// schemaFields adds the fields of the OpenAPI schema below path to fields. Array items are
+// written as path[] and values of maps as path.*.
+func schemaFields(schema map[string]any, path string, fields map[string]bool) {
+	required := map[string]bool{}
+	if list, ok := schema["required"].([]any); ok {
+		for _, name := range list {
+			if name, ok := name.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		for name, property := range properties {
+			field := name
+			if path != "" {
+				field = path + "." + name
+			}
+			fields[field] = required[name]
+			if property, ok := property.(map[string]any); ok {
+				schemaFields(property, field, fields)
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		schemaFields(items, path+"[]", fields)
+	}
+	if values, ok := schema["additionalProperties"].(map[string]any); ok {
+		schemaFields(values, path+".*", fields)
+	}
+}
+
+// compareCRDs returns the semantic changes from the old to the new CRDs, sorted by CRD, version and description.
+func compareCRDs(old, updated map[string]map[string]crdVersion) []Change {
+	var changes []Change
+	for name := range old {
+		if _, ok := updated[name]; !ok {
+			changes = append(changes, Change{CRD: name, Description: "removed CRD", Breaking: true})
+		}
+	}
+	for name, versions := range updated {
+		oldVersions, ok := old[name]
+		if !ok {
+			changes = append(changes, Change{CRD: name, Description: "added CRD"})
+			continue
+		}
+		changes = append(changes, compareVersions(name, oldVersions, versions)...)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.CRD != b.CRD {
+			return a.CRD < b.CRD
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Description < b.Description
+	})
+	return changes
+}
+
+func compareVersions(crd string, old, updated map[string]crdVersion) []Change {
+	var changes []Change
+	if oldStorage, storage := storageVersion(old), storageVersion(updated); oldStorage != storage {
+		changes = append(changes, Change{
+			CRD:         crd,
+			Description: fmt.Sprintf("changed storage version from %s to %s", oldStorage, storage),
+			Breaking:    true,
+		})
+	}
+
+	for name := range old {
+		if _, ok := updated[name]; !ok {
+			changes = append(changes, Change{CRD: crd, Version: name, Description: "removed version", Breaking: true})
+		}
+	}
+	for name, v := range updated {
+		oldVersion, ok := old[name]
+		switch {
+		case !ok:
+			changes = append(changes, Change{CRD: crd, Version: name, Description: "added version"})
+			continue
+		case oldVersion.Served && !v.Served:
+			changes = append(changes, Change{CRD: crd, Version: name, Description: "no longer served", Breaking: true})
+		case !oldVersion.Served && v.Served:
+			changes = append(changes, Change{CRD: crd, Version: name, Description: "now served"})
+		}
+
+		for field, required := range v.Fields {
+			oldRequired, existed := oldVersion.Fields[field]
+			// Required fields of new optional objects do not affect existing resources.
+			if required && !oldRequired && (existed || fieldExists(oldVersion.Fields, parentField(field))) {
+				changes = append(changes, Change{CRD: crd, Version: name, Description: "new required field " + field})
+			}
+		}
+		for field := range oldVersion.Fields {
+			// Report only the outermost removed field, not every field below it.
+			if _, ok := v.Fields[field]; !ok && fieldExists(v.Fields, parentField(field)) {
+				changes = append(changes, Change{CRD: crd, Version: name, Description: "removed field " + field, Breaking: true})
+			}
+		}
+	}
+	return changes
+}
+
+// storageVersion returns the name of the version flagged as storage version, or "none".
+func storageVersion(versions map[string]crdVersion) string {
+	for name, v := range versions {
+		if v.Storage {
+			return name
+		}
+	}
+	return "none"
+}
+
+// parentField returns the path of the object or array holding field, empty for top-level fields.
+func parentField(field string) string {
+	i := strings.LastIndexByte(field, '.')
+	if i < 0 {
+		return ""
+	}
+	parent := field[:i]
+	for {
+		trimmed := strings.TrimSuffix(strings.TrimSuffix(parent, "[]"), ".*")
+		if trimmed == parent {
+			return parent
+		}
+		parent = trimmed
+	}
+}
+
+// fieldExists reports whether field is in fields. The top-level object always exists.
+func fieldExists(fields map[string]bool, field string) bool {
+	if field == "" {
+		return true
+	}
+	_, ok := fields[field]
+	return ok
+}
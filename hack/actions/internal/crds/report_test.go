@@ -0,0 +1,199 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testReportOld = `# Conditional: .Values.contour.manageCRDs
+{{- if .Values.contour.manageCRDs }}
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: httpproxies.projectcontour.io
+spec:
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              virtualhost:
+                type: object
+                required: [fqdn]
+                properties:
+                  fqdn:
+                    type: string
+                  tls:
+                    type: object
+                    properties:
+                      secretName:
+                        type: string
+              routes:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    services:
+                      type: array
+                      items:
+                        type: object
+                        properties:
+                          name:
+                            type: string
+                          port:
+                            type: integer
+  - name: v1beta1
+    served: true
+    storage: false
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: tlscertificatedelegations.projectcontour.io
+spec:
+  versions:
+  - name: v1
+    served: true
+    storage: true
+{{- end }}
+`
+
+const testReportNew = `---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: httpproxies.projectcontour.io
+spec:
+  versions:
+  - name: v1
+    served: true
+    storage: false
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              virtualhost:
+                type: object
+                required: [fqdn]
+                properties:
+                  fqdn:
+                    type: string
+              routes:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    services:
+                      type: array
+                      items:
+                        type: object
+                        required: [name, port]
+                        properties:
+                          name:
+                            type: string
+                          port:
+                            type: integer
+                    timeouts:
+                      type: object
+                      required: [response]
+                      properties:
+                        response:
+                          type: string
+  - name: v1beta1
+    served: false
+    storage: false
+  - name: v2
+    served: true
+    storage: true
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: extensionservices.projectcontour.io
+spec:
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+`
+
+func TestCompareCRDs(t *testing.T) {
+	old, err := parseCRDVersions([]byte(testReportOld))
+	require.NoError(t, err)
+	updated, err := parseCRDVersions([]byte(testReportNew))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Change{
+		{CRD: "extensionservices.projectcontour.io", Description: "added CRD"},
+		{CRD: "httpproxies.projectcontour.io", Description: "changed storage version from v1 to v2", Breaking: true},
+		{CRD: "httpproxies.projectcontour.io", Version: "v1", Description: "new required field spec.routes[].services[].name"},
+		{CRD: "httpproxies.projectcontour.io", Version: "v1", Description: "new required field spec.routes[].services[].port"},
+		{CRD: "httpproxies.projectcontour.io", Version: "v1", Description: "removed field spec.virtualhost.tls", Breaking: true},
+		{CRD: "httpproxies.projectcontour.io", Version: "v1beta1", Description: "no longer served", Breaking: true},
+		{CRD: "httpproxies.projectcontour.io", Version: "v2", Description: "added version"},
+		{CRD: "tlscertificatedelegations.projectcontour.io", Description: "removed CRD", Breaking: true},
+	}, compareCRDs(old, updated))
+
+	assert.Empty(t, compareCRDs(old, old))
+}
+
+func TestParentField(t *testing.T) {
+	tests := map[string]string{
+		"spec":                          "",
+		"spec.virtualhost.fqdn":         "spec.virtualhost",
+		"spec.routes[].services":        "spec.routes",
+		"spec.routes[].services[].name": "spec.routes[].services",
+		"spec.labels.*.value":           "spec.labels",
+	}
+
+	for field, expected := range tests {
+		assert.Equal(t, expected, parentField(field), field)
+	}
+}
+
+func TestReportWriteText(t *testing.T) {
+	report := &Report{Changes: []Change{
+		{Set: "Contour", CRD: "extensionservices.projectcontour.io", Description: "added CRD"},
+		{Set: "Contour", CRD: "httpproxies.projectcontour.io", Version: "v1", Description: "removed field spec.virtualhost.tls", Breaking: true},
+		{Set: "Gateway API experimental channel", CRD: "tcproutes.gateway.networking.k8s.io", Description: "removed CRD", Breaking: true},
+	}}
+	assert.True(t, report.Breaking())
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteText(&buf))
+	assert.Equal(t, "Contour:\n"+
+		"  extensionservices.projectcontour.io: added CRD\n"+
+		"  httpproxies.projectcontour.io v1: removed field spec.virtualhost.tls (breaking)\n"+
+		"Gateway API experimental channel:\n"+
+		"  tcproutes.gateway.networking.k8s.io: removed CRD (breaking)\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, (&Report{}).WriteText(&buf))
+	assert.Equal(t, "No CRD changes.\n", buf.String())
+}
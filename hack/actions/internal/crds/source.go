@@ -0,0 +1,249 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/mholt/archives"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/fetch"
+)
+
+// DefaultSourceURL is the location of the Contour source tarball, formatted with the Contour version.
+const DefaultSourceURL = "https://github.com/projectcontour/contour/archive/refs/tags/v%s.tar.gz"
+
+// DefaultRefURL is the location of the Contour source tarball of a git branch or commit, formatted with the ref.
+const DefaultRefURL = "https://github.com/projectcontour/contour/archive/%s.tar.gz"
+
+// Source provides the Contour source tree the CRDs are copied from.
+type Source interface {
+	// Open returns the root of the Contour source tree for the given Contour version.
+	// tmpDir is a scratch directory that is removed once the CRDs are synchronized.
+	Open(ctx context.Context, version, tmpDir string) (fs.FS, error)
+
+	// String describes the source for logging.
+	String() string
+}
+
+// NewSource returns the Source of the command-line options of the hack tools: the local checkout
+// in dir, the local tarball at tarball, or else the tarball of ref, or of the chart appVersion release
+// if ref is empty, downloaded with download. At most one of ref, dir and tarball can be set.
+func NewSource(ref, dir, tarball string, download Download) (Source, error) {
+	set := 0
+	for _, v := range []string{ref, dir, tarball} {
+		if v != "" {
+			set++
+		}
+	}
+
+	switch {
+	case set > 1:
+		return nil, errors.New("only one of -source-ref, -source-dir and -source-tarball can be set")
+	case dir != "":
+		return &DirSource{Path: dir}, nil
+	case tarball != "":
+		return &TarballSource{Path: tarball}, nil
+	default:
+		return &ArchiveSource{Ref: ref, Download: download}, nil
+	}
+}
+
+// ArchiveSource downloads the Contour source tarball of the release tag of the chart appVersion,
+// or of a git branch or commit, such as GitHub serves them.
+type ArchiveSource struct {
+	// Ref is the git branch or commit to download instead of the release tag.
+	Ref string
+
+	// URL is the location of the tarball, formatted with the Contour version, or with Ref if it is set.
+	// Defaults to DefaultSourceURL, or DefaultRefURL if Ref is set.
+	URL string
+
+	// Download verifies and caches the tarball. Tarballs of branches are not verified,
+	// since they change with every commit.
+	Download
+}
+
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Open downloads and verifies the tarball.
+func (s *ArchiveSource) Open(ctx context.Context, version, tmpDir string) (fs.FS, error) {
+	url := s.url(version)
+
+	downloadPath := filepath.Join(tmpDir, "contour.tar.gz")
+	log.Infof("Downloading Contour source tarball %s to %s", url, downloadPath)
+	verify := true
+	if s.Ref != "" && !commitPattern.MatchString(s.Ref) {
+		log.Warnf("Not verifying the checksum of %s, ref %s is not a commit", url, s.Ref)
+		verify = false
+	}
+	if err := s.get(ctx, url, downloadPath, verify); err != nil {
+		return nil, err
+	}
+
+	return openTarball(ctx, downloadPath)
+}
+
+func (s *ArchiveSource) url(version string) string {
+	if s.Ref != "" {
+		if s.URL == "" {
+			return fmt.Sprintf(DefaultRefURL, s.Ref)
+		}
+		return fmt.Sprintf(s.URL, s.Ref)
+	}
+	if s.URL == "" {
+		return fmt.Sprintf(DefaultSourceURL, version)
+	}
+	return fmt.Sprintf(s.URL, version)
+}
+
+func (s *ArchiveSource) String() string {
+	if s.Ref != "" {
+		return "Contour ref " + s.Ref
+	}
+	return "Contour release tarball"
+}
+
+// TarballSource reads a local Contour source tarball, such as one downloaded from GitHub beforehand.
+// The chart appVersion is ignored.
+type TarballSource struct {
+	Path string
+}
+
+// Open opens the tarball.
+func (s *TarballSource) Open(ctx context.Context, _, _ string) (fs.FS, error) {
+	return openTarball(ctx, s.Path)
+}
+
+func (s *TarballSource) String() string {
+	return s.Path
+}
+
+// DirSource reads a local Contour checkout. The chart appVersion is ignored.
+type DirSource struct {
+	Path string
+}
+
+// Open returns the checkout directory.
+func (s *DirSource) Open(_ context.Context, _, _ string) (fs.FS, error) {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Contour checkout: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("failed to open Contour checkout: %s is not a directory", s.Path)
+	}
+	return os.DirFS(s.Path), nil
+}
+
+func (s *DirSource) String() string {
+	return s.Path
+}
+
+// Download configures how upstream files are downloaded and verified.
+type Download struct {
+	// LockfilePath is the path to the lockfile pinning the SHA-256 checksums of the upstream files.
+	// A downloaded file whose checksum differs from the pinned one is rejected before any CRD is
+	// written. If empty, downloads are not verified.
+	LockfilePath string
+
+	// FrozenLockfile rejects files that are not pinned in the lockfile instead of pinning them.
+	FrozenLockfile bool
+
+	// ReadOnlyLockfile accepts files that are not pinned in the lockfile with a warning instead of
+	// pinning them, for runs that must not write anything. Pinned files are still verified.
+	ReadOnlyLockfile bool
+
+	// Fetcher downloads the files. If nil, a Fetcher without cache using http.DefaultClient is used.
+	Fetcher *fetch.Fetcher
+}
+
+// get downloads url to destPath and, if verify is set, verifies it against the lockfile.
+func (d *Download) get(ctx context.Context, url, destPath string, verify bool) error {
+	checksum, err := downloadFile(ctx, d.Fetcher, url, destPath)
+	if err != nil {
+		return fmt.Errorf("failed to download release: %w", err)
+	}
+
+	if verify {
+		if err := d.verify(url, checksum); err != nil {
+			return fmt.Errorf("failed to verify release: %w", err)
+		}
+	}
+	return nil
+}
+
+// verify verifies the checksum of the file downloaded from url against the lockfile, if any.
+func (d *Download) verify(url, checksum string) error {
+	if d.LockfilePath == "" {
+		return nil
+	}
+	return verifyChecksum(d.LockfilePath, url, checksum, d.FrozenLockfile, !d.ReadOnlyLockfile)
+}
+
+// openTarball opens a source tarball. GitHub nests the source tree in a single top-level directory
+// named after the repository and ref, such as contour-1.33.6, which becomes the root.
+func openTarball(ctx context.Context, path string) (fs.FS, error) {
+	fsys, err := archives.FileSystem(ctx, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive %s: %w", path, err)
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", path, err)
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return fs.Sub(fsys, entries[0].Name())
+	}
+	return fsys, nil
+}
+
+// downloadFile streams a file from the given URL to the specified destination path,
+// and returns its SHA-256 checksum as computed while downloading.
+func downloadFile(ctx context.Context, fetcher *fetch.Fetcher, sourceURL, destPath string) (string, error) {
+	if fetcher == nil {
+		fetcher = &fetch.Fetcher{}
+	}
+
+	r, err := fetcher.Open(ctx, sourceURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", sourceURL, err)
+	}
+	defer r.Close()
+
+	f, err := os.Create(destPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file %s: %w", destPath, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+		return "", fmt.Errorf("failed to write file %s: %w", destPath, err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write file %s: %w", destPath, err)
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,122 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds/crdstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSources(t *testing.T) {
+	files := map[string]string{
+		"examples/contour/01-crds.yaml": testContourCRDs,
+		"examples/gateway/00-crds.yaml": testGatewayCRDs,
+	}
+	commit := "0123456789abcdef0123456789abcdef01234567"
+	srv := crdstest.NewServer(t, map[string][]byte{
+		"main": crdstest.Tarball(t, "main", files),
+		commit: crdstest.Tarball(t, commit, files),
+	})
+
+	checkout := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(checkout, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	tarball := filepath.Join(t.TempDir(), "contour.tar.gz")
+	require.NoError(t, os.WriteFile(tarball, crdstest.Tarball(t, "1.34.0-rc.1", files), 0o600))
+
+	lockfilePath := filepath.Join(t.TempDir(), "upstream.lock")
+
+	tests := map[string]Source{
+		"local checkout": &DirSource{Path: checkout},
+		"local tarball":  &TarballSource{Path: tarball},
+		"branch":         &ArchiveSource{Ref: "main", URL: srv.RefURL(), Download: Download{LockfilePath: lockfilePath, FrozenLockfile: true}},
+		"commit":         &ArchiveSource{Ref: commit, URL: srv.RefURL(), Download: Download{LockfilePath: lockfilePath}},
+	}
+
+	for name, source := range tests {
+		t.Run(name, func(t *testing.T) {
+			// The chart appVersion is ignored, no release tarball exists for it.
+			chartPath := writeChart(t, "1.33.6")
+			run(t, Options{ChartPath: chartPath, Files: testFiles, Source: source})
+
+			data, err := os.ReadFile(filepath.Join(filepath.Dir(chartPath), "templates", "crds", "gateway-api-crds.yaml"))
+			require.NoError(t, err)
+			assert.Equal(t, "# Conditional: .Values.gatewayAPI.manageCRDs\n{{- if .Values.gatewayAPI.manageCRDs }}\n"+testGatewayCRDs+"{{- end }}\n", string(data))
+		})
+	}
+
+	// Only the commit tarball is pinned, branches move.
+	lockfile, err := readLockfile(lockfilePath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{srv.URL + "/archive/" + commit + ".tar.gz"}, keys(lockfile.Sources))
+}
+
+func TestDirSourceMissing(t *testing.T) {
+	_, err := (&DirSource{Path: filepath.Join(t.TempDir(), "contour")}).Open(context.Background(), "1.33.6", t.TempDir())
+	require.ErrorContains(t, err, "failed to open Contour checkout")
+}
+
+func keys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestNewSource(t *testing.T) {
+	download := Download{LockfilePath: "upstream.lock"}
+	tests := map[string]struct {
+		ref, dir, tarball string
+		want              Source
+		wantErrString     string
+	}{
+		"release":  {want: &ArchiveSource{Download: download}},
+		"ref":      {ref: "main", want: &ArchiveSource{Ref: "main", Download: download}},
+		"checkout": {dir: "contour", want: &DirSource{Path: "contour"}},
+		"tarball":  {tarball: "contour.tar.gz", want: &TarballSource{Path: "contour.tar.gz"}},
+		"ref and checkout": {
+			ref:           "main",
+			dir:           "contour",
+			wantErrString: "only one of -source-ref, -source-dir and -source-tarball can be set",
+		},
+		"checkout and tarball": {
+			dir:           "contour",
+			tarball:       "contour.tar.gz",
+			wantErrString: "only one of -source-ref, -source-dir and -source-tarball can be set",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			source, err := NewSource(tc.ref, tc.dir, tc.tarball, download)
+			if tc.wantErrString != "" {
+				require.ErrorContains(t, err, tc.wantErrString)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, source)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// crdTemplate is a document split from a multi-document source file, usually a CustomResourceDefinition.
+type crdTemplate struct {
+	// Name is the name of the template file without extension, such as httpproxies.projectcontour.io
+	// for a CRD, or the lowercase kind and name of other resources, such as validatingadmissionpolicy-safe-upgrades.
+	Name string
+
+	// Plural is the plural resource name of a CRD, such as httpproxies, which keys its toggle.
+	// It is empty for other resources, which are not toggled individually.
+	Plural string
+
+	// Data is the document, starting with its --- separator.
+	Data []byte
+}
+
+// splitCRDs splits a multi-document file into its resources, keeping each document byte for byte.
+// Comments before the first document, such as a license header, are kept in every template.
+func splitCRDs(data []byte) ([]crdTemplate, error) {
+	var preamble []byte
+	var docs [][]byte
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if string(bytes.TrimRight(line, " \r\n")) == "---" {
+			docs = append(docs, []byte("---\n"))
+			continue
+		}
+		if len(docs) == 0 {
+			preamble = append(preamble, line...)
+			continue
+		}
+		docs[len(docs)-1] = append(docs[len(docs)-1], line...)
+	}
+
+	var crds []crdTemplate
+	for _, doc := range docs {
+		var crd struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name string `yaml:"name"`
+			} `yaml:"metadata"`
+			Spec struct {
+				Names struct {
+					Plural string `yaml:"plural"`
+				} `yaml:"names"`
+			} `yaml:"spec"`
+		}
+		if err := yaml.Unmarshal(doc, &crd); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal yaml: %w", err)
+		}
+		template := crdTemplate{
+			Name:   crd.Metadata.Name,
+			Plural: crd.Spec.Names.Plural,
+			Data:   append(append([]byte{}, preamble...), doc...),
+		}
+		switch {
+		case crd.Kind == "":
+			// Only comments.
+			continue
+		case crd.Metadata.Name == "":
+			return nil, fmt.Errorf("cannot split %s without name", crd.Kind)
+		case crd.Kind != "CustomResourceDefinition":
+			// Resources shipped with the CRDs, such as the Gateway API ValidatingAdmissionPolicy.
+			template.Name = strings.ToLower(crd.Kind) + "-" + crd.Metadata.Name
+			template.Plural = ""
+		case crd.Spec.Names.Plural == "":
+			return nil, fmt.Errorf("cannot split CustomResourceDefinition %s without plural", crd.Metadata.Name)
+		}
+		crds = append(crds, template)
+	}
+	return crds, nil
+}
+
+// toggleConditional returns the condition of a split CRD, which combines the file conditional
+// with the toggle of the CRD in the toggles map. Other resources only get the file conditional.
+func toggleConditional(conditional, toggles, plural string) string {
+	if toggles == "" || plural == "" {
+		return conditional
+	}
+	toggle := fmt.Sprintf("(dig %q true (%s | default dict))", plural, toggles)
+	if conditional == "" {
+		return toggle
+	}
+	if strings.ContainsAny(conditional, " \t") {
+		conditional = "(" + conditional + ")"
+	}
+	return fmt.Sprintf("and %s %s", conditional, toggle)
+}
+
+// render returns the chart templates of the CRD file read from the source. Template delimiters in the
+// source are escaped, so that the templates render the upstream content as is.
+func (f File) render(chartDir string, data []byte) ([]renderedFile, error) {
+	escaped, n := escapeTemplateDelimiters(data)
+	if err := verifyEscaped(escaped, data); err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		log.Infof("Escaped %d Helm template delimiters in %s CRDs", n, f.Name)
+	}
+	data = escaped
+
+	destPath := filepath.Join(chartDir, f.Destination)
+	if !f.Split {
+		data, err := f.injectMetadata(data)
+		if err != nil {
+			return nil, err
+		}
+		return []renderedFile{{Path: destPath, Data: injectConditional(f.Conditional, data)}}, nil
+	}
+
+	crds, err := splitCRDs(data)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]renderedFile, 0, len(crds))
+	for _, crd := range crds {
+		data, err := f.injectMetadata(crd.Data)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, renderedFile{
+			Path: filepath.Join(destPath, crd.Name+".yaml"),
+			Data: injectConditional(toggleConditional(f.Conditional, f.Toggles, crd.Plural), data),
+		})
+	}
+	return files, nil
+}
+
+// current returns the chart templates of the CRD file as committed. Missing templates are skipped.
+func (f File) current(chartDir string) ([]renderedFile, error) {
+	destPath := filepath.Join(chartDir, f.Destination)
+	paths := []string{destPath}
+	if f.Split {
+		entries, err := os.ReadDir(destPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read directory %s: %w", destPath, err)
+		}
+		paths = paths[:0]
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasSuffix(e.Name(), ".yaml") {
+				paths = append(paths, filepath.Join(destPath, e.Name()))
+			}
+		}
+	}
+
+	var files []renderedFile
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+		files = append(files, renderedFile{Path: path, Data: data})
+	}
+	return files, nil
+}
@@ -0,0 +1,110 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds/crdstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testExtensionServiceCRDs = "---\napiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: extensionservices.projectcontour.io\n" +
+	"spec:\n  names:\n    kind: ExtensionService\n    plural: extensionservices\n  versions:\n  - name: v1alpha1\n    storage: true\n"
+
+const testHTTPProxyCRDs = "---\napiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: httpproxies.projectcontour.io\n" +
+	"spec:\n  names:\n    kind: HTTPProxy\n    plural: httpproxies\n  versions:\n  - name: v1\n    storage: true\n"
+
+func TestSplitCRDs(t *testing.T) {
+	crds, err := splitCRDs([]byte("# License header.\n" + testExtensionServiceCRDs + "---\n# Only a comment.\n" + testHTTPProxyCRDs))
+	require.NoError(t, err)
+	assert.Equal(t, []crdTemplate{
+		{Name: "extensionservices.projectcontour.io", Plural: "extensionservices", Data: []byte("# License header.\n" + testExtensionServiceCRDs)},
+		{Name: "httpproxies.projectcontour.io", Plural: "httpproxies", Data: []byte("# License header.\n" + testHTTPProxyCRDs)},
+	}, crds)
+
+	// Other resources are split without a toggle.
+	policy := "---\napiVersion: admissionregistration.k8s.io/v1\nkind: ValidatingAdmissionPolicy\nmetadata:\n  name: safe-upgrades.gateway.networking.k8s.io\n"
+	crds, err = splitCRDs([]byte(policy))
+	require.NoError(t, err)
+	assert.Equal(t, []crdTemplate{{Name: "validatingadmissionpolicy-safe-upgrades.gateway.networking.k8s.io", Data: []byte(policy)}}, crds)
+
+	_, err = splitCRDs([]byte("---\napiVersion: v1\nkind: Namespace\n"))
+	require.ErrorContains(t, err, "cannot split Namespace without name")
+}
+
+func TestToggleConditional(t *testing.T) {
+	assert.Equal(t, `and .Values.contour.manageCRDs (dig "httpproxies" true (.Values.contour.crds | default dict))`,
+		toggleConditional(".Values.contour.manageCRDs", ".Values.contour.crds", "httpproxies"))
+	assert.Equal(t, `(dig "httpproxies" true (.Values.contour.crds | default dict))`, toggleConditional("", ".Values.contour.crds", "httpproxies"))
+	assert.Equal(t, ".Values.contour.manageCRDs", toggleConditional(".Values.contour.manageCRDs", "", "httpproxies"))
+	assert.Equal(t, ".Values.contour.manageCRDs", toggleConditional(".Values.contour.manageCRDs", ".Values.contour.crds", ""))
+	assert.Equal(t, `and (eq .Values.gatewayAPI.channel "standard") (dig "gateways" true (.Values.gatewayAPI.crds | default dict))`,
+		toggleConditional(`eq .Values.gatewayAPI.channel "standard"`, ".Values.gatewayAPI.crds", "gateways"))
+}
+
+func TestRunSplit(t *testing.T) {
+	srv := crdstest.NewServer(t, map[string][]byte{
+		"1.33.6": crdstest.Tarball(t, "1.33.6", map[string]string{
+			"examples/contour/01-crds.yaml": testExtensionServiceCRDs + testHTTPProxyCRDs,
+		}),
+	})
+	chartPath := writeChart(t, "1.33.6")
+	crdsDir := filepath.Join(filepath.Dir(chartPath), "templates", "crds", "contour")
+	files := []File{{
+		Name:        "Contour",
+		Source:      "examples/contour/01-crds.yaml",
+		Destination: "templates/crds/contour",
+		Conditional: ".Values.contour.manageCRDs",
+		Split:       true,
+		Toggles:     ".Values.contour.crds",
+	}}
+
+	// A CRD that is no longer in the source is removed.
+	require.NoError(t, os.MkdirAll(crdsDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(crdsDir, "tlscertificatedelegations.projectcontour.io.yaml"), []byte(testContourCRDs), 0o600))
+
+	run(t, Options{ChartPath: chartPath, Files: files, Source: &ArchiveSource{URL: srv.SourceURL()}})
+
+	entries, err := os.ReadDir(crdsDir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"extensionservices.projectcontour.io.yaml", "httpproxies.projectcontour.io.yaml"}, names)
+
+	data, err := os.ReadFile(filepath.Join(crdsDir, "extensionservices.projectcontour.io.yaml"))
+	require.NoError(t, err)
+	condition := `and .Values.contour.manageCRDs (dig "extensionservices" true (.Values.contour.crds | default dict))`
+	assert.Equal(t, "# Conditional: "+condition+"\n{{- if "+condition+" }}\n"+testExtensionServiceCRDs+"{{- end }}\n", string(data))
+
+	// The split templates are up to date.
+	_, err = Run(context.Background(), Options{ChartPath: chartPath, Files: files, Source: &ArchiveSource{URL: srv.SourceURL()}, Check: true})
+	require.NoError(t, err)
+
+	// A stale template is drift.
+	stalePath := filepath.Join(crdsDir, "tlscertificatedelegations.projectcontour.io.yaml")
+	require.NoError(t, os.WriteFile(stalePath, []byte(testContourCRDs), 0o600))
+	_, err = Run(context.Background(), Options{ChartPath: chartPath, Files: files, Source: &ArchiveSource{URL: srv.SourceURL()}, Check: true, Out: io.Discard})
+	require.ErrorIs(t, err, ErrDrift)
+	assert.ErrorContains(t, err, stalePath)
+	_, err = os.Stat(stalePath)
+	require.NoError(t, err)
+}
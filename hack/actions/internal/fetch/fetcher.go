@@ -0,0 +1,184 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// StatusError is returned when the server answers with an unexpected status code.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed to fetch %s: status code %d", e.URL, e.StatusCode)
+}
+
+// Fetcher downloads files over HTTP. Responses carrying an ETag or Last-Modified header are cached in
+// CacheDir and revalidated with a conditional request on the next fetch, so that unchanged files such as
+// the Contour source tarball are not downloaded again.
+type Fetcher struct {
+	// Client performs the requests. If nil, http.DefaultClient is used.
+	// Use a client with a Transport to retry transient failures.
+	Client *http.Client
+
+	// CacheDir is the directory downloads are cached in. If empty, nothing is cached.
+	CacheDir string
+}
+
+// cacheEntry is the metadata of a cached download, stored next to its content.
+type cacheEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// DefaultCacheDir returns the cache directory of the hack tools in the user cache directory,
+// or an empty string if there is none.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "projectcontour-helm-charts")
+}
+
+// Open fetches url and returns a reader for its content. The caller must close it.
+// Any status code other than 200, or 304 for a cached download, fails with a *StatusError.
+func (f *Fetcher) Open(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var contentPath, entryPath string
+	var cached *cacheEntry
+	if f.CacheDir != "" {
+		sum := sha256.Sum256([]byte(url))
+		contentPath = filepath.Join(f.CacheDir, hex.EncodeToString(sum[:]))
+		entryPath = contentPath + ".json"
+		if cached = readCacheEntry(entryPath, contentPath, url); cached != nil {
+			if cached.ETag != "" {
+				req.Header.Set("If-None-Match", cached.ETag)
+			}
+			if cached.LastModified != "" {
+				req.Header.Set("If-Modified-Since", cached.LastModified)
+			}
+		}
+	}
+
+	resp, err := f.client().Do(req) //nolint:gosec // G704: URLs are provided by the operator of the tool
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		resp.Body.Close()
+		log.Infof("Using cached %s", url)
+		file, err := os.Open(contentPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open cached %s: %w", url, err)
+		}
+		return file, nil
+	case resp.StatusCode != http.StatusOK:
+		resp.Body.Close()
+		return nil, &StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+
+	entry := cacheEntry{URL: url, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	if f.CacheDir == "" || (entry.ETag == "" && entry.LastModified == "") {
+		// Without a validator the download could not be revalidated, so there is no point in caching it.
+		return resp.Body, nil
+	}
+
+	defer resp.Body.Close()
+	if err := writeCache(f.CacheDir, contentPath, entryPath, entry, resp.Body); err != nil {
+		return nil, fmt.Errorf("failed to cache %s: %w", url, err)
+	}
+	file, err := os.Open(contentPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cached %s: %w", url, err)
+	}
+	return file, nil
+}
+
+func (f *Fetcher) client() *http.Client {
+	if f.Client != nil {
+		return f.Client
+	}
+	return http.DefaultClient
+}
+
+// readCacheEntry returns the cache entry of url, or nil if it is not cached.
+func readCacheEntry(entryPath, contentPath, url string) *cacheEntry {
+	data, err := os.ReadFile(entryPath)
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != url {
+		return nil
+	}
+	if _, err := os.Stat(contentPath); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// writeCache stores the content and its cache entry. The content is written to a temporary file first,
+// so that an interrupted download never leaves a truncated file in the cache.
+func writeCache(dir, contentPath, entryPath string, entry cacheEntry, content io.Reader) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:gosec // G301: the cache holds public downloads
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "download-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// Drop the old entry first, so that a failure below never pairs it with the new content.
+	if err := os.Remove(entryPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), contentPath); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(entryPath, data, 0o644) //nolint:gosec // G306: the cache holds public downloads
+}
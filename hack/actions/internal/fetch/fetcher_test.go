@@ -0,0 +1,165 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fileServer serves a file with the given validators and counts full downloads.
+type fileServer struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	content      string
+	etag         string
+	lastModified string
+	downloads    int
+}
+
+func newFileServer(t *testing.T, content, etag, lastModified string) *fileServer {
+	t.Helper()
+
+	s := &fileServer{content: content, etag: etag, lastModified: lastModified}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.etag != "" {
+			w.Header().Set("ETag", s.etag)
+			if r.Header.Get("If-None-Match") == s.etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		if s.lastModified != "" {
+			w.Header().Set("Last-Modified", s.lastModified)
+			if s.etag == "" && r.Header.Get("If-Modified-Since") == s.lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		s.downloads++
+		_, _ = w.Write([]byte(s.content))
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *fileServer) update(content, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.content, s.etag = content, etag
+}
+
+func (s *fileServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.downloads
+}
+
+func read(t *testing.T, f *Fetcher, url string) string {
+	t.Helper()
+
+	r, err := f.Open(context.Background(), url)
+	require.NoError(t, err)
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestFetcherCache(t *testing.T) {
+	tests := map[string]struct {
+		etag         string
+		lastModified string
+		wantCached   bool
+	}{
+		"ETag":          {etag: `"v1"`, wantCached: true},
+		"Last-Modified": {lastModified: "Mon, 02 Jan 2006 15:04:05 GMT", wantCached: true},
+		"no validators": {wantCached: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := newFileServer(t, "tarball", tc.etag, tc.lastModified)
+			f := &Fetcher{Client: srv.Client(), CacheDir: t.TempDir()}
+
+			assert.Equal(t, "tarball", read(t, f, srv.URL+"/contour.tar.gz"))
+			assert.Equal(t, "tarball", read(t, f, srv.URL+"/contour.tar.gz"))
+			if tc.wantCached {
+				assert.Equal(t, 1, srv.count())
+			} else {
+				assert.Equal(t, 2, srv.count())
+			}
+		})
+	}
+}
+
+func TestFetcherCacheUpdated(t *testing.T) {
+	srv := newFileServer(t, "v1", `"v1"`, "")
+	f := &Fetcher{Client: srv.Client(), CacheDir: t.TempDir()}
+
+	assert.Equal(t, "v1", read(t, f, srv.URL))
+
+	srv.update("v2", `"v2"`)
+	assert.Equal(t, "v2", read(t, f, srv.URL))
+	assert.Equal(t, "v2", read(t, f, srv.URL))
+	assert.Equal(t, 2, srv.count())
+
+	// Only the content and entry of the URL are kept, without leftover temporary files.
+	entries, err := os.ReadDir(f.CacheDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestFetcherNoCache(t *testing.T) {
+	srv := newFileServer(t, "versions", `"v1"`, "")
+	f := &Fetcher{Client: srv.Client()}
+
+	assert.Equal(t, "versions", read(t, f, srv.URL))
+	assert.Equal(t, "versions", read(t, f, srv.URL))
+	assert.Equal(t, 2, srv.count())
+}
+
+func TestFetcherStatusError(t *testing.T) {
+	srv := newFlakyServer(t, failure{status: http.StatusNotFound})
+	f := &Fetcher{Client: srv.Client(), CacheDir: t.TempDir()}
+
+	_, err := f.Open(context.Background(), srv.URL+"/missing")
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	assert.EqualError(t, err, "failed to fetch "+srv.URL+"/missing: status code 404")
+}
+
+func TestFetcherRetries(t *testing.T) {
+	srv := newFlakyServer(t, failure{status: http.StatusBadGateway}, failure{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "0"}})
+	f := &Fetcher{Client: newTestClient(&Transport{})}
+
+	assert.Equal(t, "ok", read(t, f, srv.URL))
+	assert.Equal(t, 3, srv.count())
+}
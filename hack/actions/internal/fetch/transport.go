@@ -0,0 +1,209 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fetch downloads files over HTTP for the hack tools. Its Transport retries transient failures
+// such as GitHub 5xx and rate-limit responses, and its Fetcher caches downloads on disk between runs.
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.StandardLogger()
+
+const (
+	// DefaultMaxAttempts is the default number of attempts of a request.
+	DefaultMaxAttempts = 5
+
+	// DefaultMinBackoff is the default delay before the first retry.
+	DefaultMinBackoff = time.Second
+
+	// DefaultMaxBackoff is the default upper bound of the delay between attempts.
+	DefaultMaxBackoff = time.Minute
+
+	// DefaultTimeout bounds all attempts of a request made with http.DefaultClient once configured.
+	DefaultTimeout = 5 * time.Minute
+)
+
+// ConfigureDefaultClient makes http.DefaultClient retry transient failures with a Transport, and
+// authenticate to GitHub with $GITHUB_TOKEN if set. Its timeout covers all attempts of a request.
+func ConfigureDefaultClient() {
+	http.DefaultClient.Timeout = DefaultTimeout
+	http.DefaultClient.Transport = &Transport{GitHubToken: os.Getenv("GITHUB_TOKEN")}
+}
+
+// Transport is an http.RoundTripper that retries GET and HEAD requests failing with a network error,
+// a 5xx status, 429 Too Many Requests or a GitHub rate-limit 403, with exponential backoff.
+// A Retry-After or X-RateLimit-Reset header in the response overrides the backoff, up to MaxBackoff.
+type Transport struct {
+	// Base performs the requests. If nil, a transport with the usual defaults and proxy settings is used.
+	Base http.RoundTripper
+
+	// MaxAttempts is the number of attempts of a request, including the first one.
+	// Defaults to DefaultMaxAttempts.
+	MaxAttempts int
+
+	// MinBackoff is the delay before the first retry. It doubles for each further retry.
+	// Defaults to DefaultMinBackoff.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the upper bound of the delay between attempts. Defaults to DefaultMaxBackoff.
+	MaxBackoff time.Duration
+
+	// GitHubToken authenticates requests to the GitHub hosts in gitHubHosts that carry no Authorization
+	// header yet, which raises the GitHub rate limit. It is never sent to other hosts.
+	GitHubToken string
+}
+
+var _ http.RoundTripper = &Transport{}
+
+// RoundTrip performs the request, retrying it on transient failures.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.GitHubToken != "" && req.Header.Get("Authorization") == "" && isGitHubHost(req.URL.Hostname()) {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+t.GitHubToken)
+	}
+
+	// Only requests without side effects are safe to repeat.
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return t.base().RoundTrip(req)
+	}
+
+	maxAttempts := t.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base().RoundTrip(req)
+		if attempt >= maxAttempts || !retryable(resp, err) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, resp)
+		if err != nil {
+			log.Warnf("Request to %s failed: %v, retrying in %s (attempt %d of %d)", req.URL, err, delay, attempt+1, maxAttempts)
+		} else {
+			log.Warnf("Request to %s failed with status code %d, retrying in %s (attempt %d of %d)", req.URL, resp.StatusCode, delay, attempt+1, maxAttempts)
+			// Drain the body so that the connection can be reused.
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return defaultBase
+}
+
+// defaultBase is the base transport of all Transports without one, so that they share connections.
+var defaultBase http.RoundTripper = &http.Transport{
+	Proxy:                 http.ProxyFromEnvironment,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: time.Second,
+}
+
+// backoff returns the delay before the next attempt, after the given number of failed attempts.
+func (t *Transport) backoff(attempt int, resp *http.Response) time.Duration {
+	minBackoff, maxBackoff := t.MinBackoff, t.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = DefaultMinBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	delay := maxBackoff
+	if attempt < 32 && minBackoff<<(attempt-1) < maxBackoff {
+		delay = minBackoff << (attempt - 1)
+	}
+	if resp != nil {
+		if after, ok := retryAfter(resp, time.Now()); ok {
+			delay = min(after, maxBackoff)
+		}
+	}
+	return delay
+}
+
+// retryable reports whether the outcome of an attempt is a transient failure.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		// A canceled or expired context fails every further attempt as well.
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true
+	case resp.StatusCode == http.StatusForbidden:
+		// GitHub answers 403 when the rate limit is exceeded.
+		return resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != ""
+	default:
+		return false
+	}
+}
+
+// retryAfter returns the delay requested by the Retry-After header, in seconds or as an HTTP date,
+// or by the GitHub X-RateLimit-Reset header, in Unix seconds.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if at, err := http.ParseTime(v); err == nil {
+			return max(at.Sub(now), 0), true
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now), 0), true
+		}
+	}
+
+	return 0, false
+}
+
+// gitHubHosts are the GitHub hosts the token is sent to: github.com, its API, the codeload host serving
+// source tarballs, and raw.githubusercontent.com. Other githubusercontent.com hosts, such as the ones
+// release downloads redirect to, serve pre-signed URLs that must not carry the token.
+var gitHubHosts = []string{"github.com", "api.github.com", "codeload.github.com", "raw.githubusercontent.com"}
+
+// isGitHubHost reports whether host is one of gitHubHosts.
+func isGitHubHost(host string) bool {
+	return slices.Contains(gitHubHosts, strings.ToLower(host))
+}
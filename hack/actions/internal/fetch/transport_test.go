@@ -0,0 +1,337 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyServer answers with the given failures in order, then with 200 and body "ok".
+type flakyServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests int
+}
+
+type failure struct {
+	status  int
+	headers map[string]string
+}
+
+func newFlakyServer(t *testing.T, failures ...failure) *flakyServer {
+	t.Helper()
+
+	s := &flakyServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		s.mu.Lock()
+		n := s.requests
+		s.requests++
+		s.mu.Unlock()
+
+		if n < len(failures) {
+			for k, v := range failures[n].headers {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(failures[n].status)
+			_, _ = w.Write([]byte("failure"))
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *flakyServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func newTestClient(t *Transport) *http.Client {
+	t.MinBackoff = time.Millisecond
+	t.MaxBackoff = 10 * time.Millisecond
+	return &http.Client{Transport: t}
+}
+
+func get(t *testing.T, client *http.Client, method, url string) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), method, url, http.NoBody)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body)
+}
+
+func TestTransportRetries(t *testing.T) {
+	rateLimitReset := strconv.FormatInt(time.Now().Unix(), 10)
+
+	tests := map[string]struct {
+		failures     []failure
+		method       string
+		wantStatus   int
+		wantRequests int
+	}{
+		"success": {
+			wantStatus:   http.StatusOK,
+			wantRequests: 1,
+		},
+		"server errors": {
+			failures:     []failure{{status: http.StatusBadGateway}, {status: http.StatusServiceUnavailable}},
+			wantStatus:   http.StatusOK,
+			wantRequests: 3,
+		},
+		"too many requests with Retry-After": {
+			failures:     []failure{{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "0"}}},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+		},
+		"GitHub rate limit": {
+			failures: []failure{{status: http.StatusForbidden, headers: map[string]string{
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     rateLimitReset,
+			}}},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+		},
+		"forbidden is not retried": {
+			failures:     []failure{{status: http.StatusForbidden}},
+			wantStatus:   http.StatusForbidden,
+			wantRequests: 1,
+		},
+		"not found is not retried": {
+			failures:     []failure{{status: http.StatusNotFound}},
+			wantStatus:   http.StatusNotFound,
+			wantRequests: 1,
+		},
+		"attempts are exhausted": {
+			failures: []failure{
+				{status: http.StatusInternalServerError},
+				{status: http.StatusInternalServerError},
+				{status: http.StatusInternalServerError},
+			},
+			wantStatus:   http.StatusInternalServerError,
+			wantRequests: 3,
+		},
+		"POST is not retried": {
+			failures:     []failure{{status: http.StatusServiceUnavailable}},
+			method:       http.MethodPost,
+			wantStatus:   http.StatusServiceUnavailable,
+			wantRequests: 1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := newFlakyServer(t, tc.failures...)
+			client := newTestClient(&Transport{MaxAttempts: 3})
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			resp, body := get(t, client, method, srv.URL)
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+			assert.Equal(t, tc.wantRequests, srv.count())
+			if tc.wantStatus == http.StatusOK {
+				assert.Equal(t, "ok", body)
+			}
+		})
+	}
+}
+
+func TestTransportNetworkError(t *testing.T) {
+	attempts := 0
+	client := newTestClient(&Transport{
+		MaxAttempts: 3,
+		Base: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			attempts++
+			return nil, errors.New("connection reset by peer")
+		}),
+	})
+
+	_, err := client.Get("https://example.com/versions.yaml")
+	require.ErrorContains(t, err, "connection reset by peer")
+	assert.Equal(t, 3, attempts)
+}
+
+func TestTransportCanceled(t *testing.T) {
+	srv := newFlakyServer(t, failure{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "60"}})
+	transport := &Transport{MaxBackoff: time.Minute}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, http.NoBody)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = (&http.Client{Transport: transport}).Do(req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Equal(t, 1, srv.count())
+}
+
+func TestTransportGitHubToken(t *testing.T) {
+	var auth []string
+	client := &http.Client{Transport: &Transport{
+		GitHubToken: "test-token",
+		Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			auth = append(auth, req.URL.Host+" "+req.Header.Get("Authorization"))
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+		}),
+	}}
+
+	for _, url := range []string{
+		"https://raw.githubusercontent.com/projectcontour/contour/main/versions.yaml",
+		"https://codeload.github.com/projectcontour/contour/tar.gz/refs/tags/v1.33.6",
+		"https://registry-1.docker.io/v2/",
+	} {
+		resp, err := client.Get(url)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// An explicit Authorization header is kept.
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://api.github.com/repos", http.NoBody)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer other")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, []string{
+		"raw.githubusercontent.com Bearer test-token",
+		"codeload.github.com Bearer test-token",
+		"registry-1.docker.io ",
+		"api.github.com Bearer other",
+	}, auth)
+}
+
+func TestTransportGitHubTokenRedirect(t *testing.T) {
+	var auth []string
+	client := &http.Client{Transport: &Transport{
+		GitHubToken: "test-token",
+		Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			auth = append(auth, req.URL.Host+" "+req.Header.Get("Authorization"))
+			resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}
+			if req.URL.Host == "github.com" {
+				// GitHub redirects release downloads to pre-signed URLs.
+				resp.StatusCode = http.StatusFound
+				resp.Header.Set("Location", "https://release-assets.githubusercontent.com/github-production-release-asset/1?sig=abc")
+			}
+			return resp, nil
+		}),
+	}}
+
+	resp, err := client.Get("https://github.com/kubernetes-sigs/gateway-api/releases/download/v1.3.0/standard-install.yaml")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, []string{
+		"github.com Bearer test-token",
+		"release-assets.githubusercontent.com ",
+	}, auth)
+}
+
+func TestIsGitHubHost(t *testing.T) {
+	for _, host := range []string{"github.com", "API.github.com", "codeload.github.com", "raw.githubusercontent.com"} {
+		assert.True(t, isGitHubHost(host), host)
+	}
+	for _, host := range []string{"objects.githubusercontent.com", "release-assets.githubusercontent.com", "gist.github.com", "github.com.example.com", "example.com"} {
+		assert.False(t, isGitHubHost(host), host)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := map[string]struct {
+		headers map[string]string
+		want    time.Duration
+		wantOK  bool
+	}{
+		"seconds":                 {headers: map[string]string{"Retry-After": "7"}, want: 7 * time.Second, wantOK: true},
+		"HTTP date":               {headers: map[string]string{"Retry-After": now.Add(time.Minute).Format(http.TimeFormat)}, want: time.Minute, wantOK: true},
+		"past date":               {headers: map[string]string{"Retry-After": now.Add(-time.Minute).Format(http.TimeFormat)}, want: 0, wantOK: true},
+		"invalid":                 {headers: map[string]string{"Retry-After": "soon"}},
+		"none":                    {},
+		"rate limit":              {headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(now.Add(30*time.Second).Unix(), 10)}, want: 30 * time.Second, wantOK: true},
+		"rate limit not exceeded": {headers: map[string]string{"X-RateLimit-Remaining": "10", "X-RateLimit-Reset": strconv.FormatInt(now.Unix(), 10)}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			for k, v := range tc.headers {
+				resp.Header.Set(k, v)
+			}
+			got, ok := retryAfter(resp, now)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tr := &Transport{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, tr.backoff(1, nil))
+	assert.Equal(t, 2*time.Second, tr.backoff(2, nil))
+	assert.Equal(t, 4*time.Second, tr.backoff(3, nil))
+	assert.Equal(t, 5*time.Second, tr.backoff(4, nil))
+	assert.Equal(t, 5*time.Second, tr.backoff(100, nil))
+
+	// Retry-After wins over the backoff, but not over MaxBackoff.
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"3"}}}
+	assert.Equal(t, 3*time.Second, tr.backoff(1, resp))
+	resp.Header.Set("Retry-After", "3600")
+	assert.Equal(t, 5*time.Second, tr.backoff(1, resp))
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestConfigureDefaultClient(t *testing.T) {
+	timeout, transport := http.DefaultClient.Timeout, http.DefaultClient.Transport
+	t.Cleanup(func() {
+		http.DefaultClient.Timeout, http.DefaultClient.Transport = timeout, transport
+	})
+	t.Setenv("GITHUB_TOKEN", "test-token")
+
+	ConfigureDefaultClient()
+
+	assert.Equal(t, DefaultTimeout, http.DefaultClient.Timeout)
+	assert.Equal(t, &Transport{GitHubToken: "test-token"}, http.DefaultClient.Transport)
+}
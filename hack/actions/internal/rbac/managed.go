@@ -0,0 +1,100 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A managed block holds the rules of the upstream role of a kind, between marker comments in the
+// rules of the chart role, such as:
+//
+//	rules:
+//	  # BEGIN ClusterRole rules managed by synchronize-rbac, do not edit.
+//	  - apiGroups: ...
+//	  # END ClusterRole rules managed by synchronize-rbac.
+//
+// Rules outside of the markers are left alone, so that the chart can grant more.
+var markerPattern = regexp.MustCompile(`^(\s*)# (BEGIN|END) (\w+) rules managed by synchronize-rbac\b`)
+
+// updateManagedBlocks replaces the managed blocks of the chart template data with the rules of the
+// upstream roles. It returns the kinds of the managed blocks found.
+func updateManagedBlocks(data []byte, upstream map[string]*role) ([]byte, []string, error) {
+	var out bytes.Buffer
+	var kinds []string
+	current := ""
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		m := markerPattern.FindStringSubmatch(line)
+		switch {
+		case m == nil && current != "":
+			// Replaced by the upstream rules.
+			continue
+		case m == nil:
+			out.WriteString(line)
+		case m[2] == "BEGIN":
+			if current != "" {
+				return nil, nil, fmt.Errorf("managed %s rules start before the end of the managed %s rules", m[3], current)
+			}
+			r, ok := upstream[m[3]]
+			if !ok {
+				return nil, nil, fmt.Errorf("managed %s rules have no upstream %s", m[3], m[3])
+			}
+			rules, err := marshalRules(r.Rules, m[1])
+			if err != nil {
+				return nil, nil, err
+			}
+			out.WriteString(line)
+			out.Write(rules)
+			current = m[3]
+			kinds = append(kinds, current)
+		case m[3] != current:
+			return nil, nil, fmt.Errorf("unexpected end of managed %s rules", m[3])
+		default:
+			out.WriteString(line)
+			current = ""
+		}
+	}
+	if current != "" {
+		return nil, nil, fmt.Errorf("managed %s rules do not end", current)
+	}
+	return out.Bytes(), kinds, nil
+}
+
+// marshalRules writes rules as a YAML sequence indented by indent, in the layout of the chart templates.
+// No rules make an empty block.
+func marshalRules(rules []policyRule, indent string) ([]byte, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(rules); err != nil {
+		return nil, fmt.Errorf("failed to marshal rules: %w", err)
+	}
+
+	var out bytes.Buffer
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line != "" {
+			out.WriteString(indent + line)
+		}
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,167 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testUpstreamRoles = map[string]*role{
+	"ClusterRole": {Kind: "ClusterRole", Rules: []policyRule{
+		{APIGroups: []string{""}, Resources: []string{"secrets", "services"}, Verbs: []string{"get", "list", "watch"}},
+	}},
+	"Role": {Kind: "Role", Rules: []policyRule{
+		{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"}, Verbs: []string{"create", "get", "update"}},
+	}},
+}
+
+func TestUpdateManagedBlocks(t *testing.T) {
+	tests := map[string]struct {
+		data      string
+		want      string
+		wantKinds []string
+	}{
+		"no managed blocks": {
+			data: "kind: ClusterRole\nrules:\n- apiGroups: [\"\"]\n",
+			want: "kind: ClusterRole\nrules:\n- apiGroups: [\"\"]\n",
+		},
+		"replaced rules": {
+			data: `kind: ClusterRole
+rules:
+  # BEGIN ClusterRole rules managed by synchronize-rbac, do not edit.
+  - apiGroups: [""]
+    resources: [secrets]
+    verbs: [get]
+  # END ClusterRole rules managed by synchronize-rbac.
+  - apiGroups: [""]
+    resources: [configmaps]
+    verbs: [create]
+{{- end }}
+`,
+			want: `kind: ClusterRole
+rules:
+  # BEGIN ClusterRole rules managed by synchronize-rbac, do not edit.
+  - apiGroups:
+      - ""
+    resources:
+      - secrets
+      - services
+    verbs:
+      - get
+      - list
+      - watch
+  # END ClusterRole rules managed by synchronize-rbac.
+  - apiGroups: [""]
+    resources: [configmaps]
+    verbs: [create]
+{{- end }}
+`,
+			wantKinds: []string{"ClusterRole"},
+		},
+		"several blocks": {
+			data: `rules:
+# BEGIN ClusterRole rules managed by synchronize-rbac, do not edit.
+# END ClusterRole rules managed by synchronize-rbac.
+---
+rules:
+# BEGIN Role rules managed by synchronize-rbac, do not edit.
+# END Role rules managed by synchronize-rbac.
+`,
+			want: `rules:
+# BEGIN ClusterRole rules managed by synchronize-rbac, do not edit.
+- apiGroups:
+    - ""
+  resources:
+    - secrets
+    - services
+  verbs:
+    - get
+    - list
+    - watch
+# END ClusterRole rules managed by synchronize-rbac.
+---
+rules:
+# BEGIN Role rules managed by synchronize-rbac, do not edit.
+- apiGroups:
+    - coordination.k8s.io
+  resources:
+    - leases
+  verbs:
+    - create
+    - get
+    - update
+# END Role rules managed by synchronize-rbac.
+`,
+			wantKinds: []string{"ClusterRole", "Role"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, kinds, err := updateManagedBlocks([]byte(tc.data), testUpstreamRoles)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(got))
+			assert.Equal(t, tc.wantKinds, kinds)
+
+			// Updating again changes nothing.
+			again, _, err := updateManagedBlocks(got, testUpstreamRoles)
+			require.NoError(t, err)
+			assert.Equal(t, string(got), string(again))
+		})
+	}
+}
+
+func TestUpdateManagedBlocksErrors(t *testing.T) {
+	tests := map[string]struct {
+		data    string
+		wantErr string
+	}{
+		"nested blocks": {
+			data:    "# BEGIN ClusterRole rules managed by synchronize-rbac\n# BEGIN Role rules managed by synchronize-rbac\n",
+			wantErr: "managed Role rules start before the end of the managed ClusterRole rules",
+		},
+		"no upstream role": {
+			data:    "# BEGIN RoleBinding rules managed by synchronize-rbac\n",
+			wantErr: "managed RoleBinding rules have no upstream RoleBinding",
+		},
+		"unexpected end": {
+			data:    "# END Role rules managed by synchronize-rbac\n",
+			wantErr: "unexpected end of managed Role rules",
+		},
+		"mismatched end": {
+			data:    "# BEGIN ClusterRole rules managed by synchronize-rbac\n# END Role rules managed by synchronize-rbac\n",
+			wantErr: "unexpected end of managed Role rules",
+		},
+		"missing end": {
+			data:    "# BEGIN Role rules managed by synchronize-rbac\n- apiGroups: []\n",
+			wantErr: "managed Role rules do not end",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := updateManagedBlocks([]byte(tc.data), testUpstreamRoles)
+			require.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestMarshalRulesEmpty(t *testing.T) {
+	data, err := marshalRules(nil, "  ")
+	require.NoError(t, err)
+	assert.Empty(t, data)
+}
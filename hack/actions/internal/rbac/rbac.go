@@ -0,0 +1,220 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rbac compares the RBAC rules of the chart with the role manifests of the Contour source,
+// and keeps the managed blocks of rules in the chart templates in sync with them.
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/render"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.StandardLogger()
+
+// ErrDrift is returned in check mode when managed rules of the chart differ from the upstream ones.
+var ErrDrift = errors.New("chart RBAC rules are out of date")
+
+// kinds are the kinds of roles compared, in report order.
+var kinds = []string{"ClusterRole", "Role"}
+
+// File maps an upstream role manifest to the chart template rendering the same roles.
+type File struct {
+	// Name names the roles in logs and reports, such as Contour.
+	Name string `yaml:"name"`
+
+	// Source is the path of the role manifest in the Contour source, such as examples/contour/02-role-contour.yaml.
+	Source string `yaml:"source"`
+
+	// Destination is the path of the chart template rendering the roles, relative to the chart directory,
+	// such as templates/contour/rbac.yaml. The ClusterRole and Role of the manifest are compared with the ones
+	// the template renders with the default values, and its managed blocks of rules are kept in sync with them.
+	Destination string `yaml:"destination"`
+}
+
+// Options configures Run.
+type Options struct {
+	// ChartPath is the path to the chart's Chart.yaml. Templates are read relative to its directory.
+	ChartPath string
+
+	// Files lists the role files of the chart. If empty, there is nothing to compare.
+	Files []File
+
+	// Source provides the Contour source tree. Defaults to the release tarball of the chart appVersion,
+	// downloaded without verification.
+	Source crds.Source
+
+	// Check compares the managed rules with the upstream ones instead of writing them. Run writes a
+	// unified diff of the templates that differ to Out and returns an error wrapping ErrDrift.
+	Check bool
+
+	// Out receives the diffs in check mode. Defaults to os.Stdout.
+	Out io.Writer
+}
+
+// Run updates the managed rules of the chart templates from the Contour source of the chart's appVersion,
+// and returns a report of the rules that the roles rendered from the chart lack or grant on top of upstream.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if len(opts.Files) == 0 {
+		log.Infof("No RBAC rules to synchronize in %s", opts.ChartPath)
+		return &Report{}, nil
+	}
+
+	chartDir := filepath.Dir(opts.ChartPath)
+	metadata, err := render.ReadMetadata(chartDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current chart appVersion: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "contour-source-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	source := opts.Source
+	if source == nil {
+		source = &crds.ArchiveSource{}
+	}
+	log.Infof("Reading Contour source from %s", source)
+	tree, err := source.Open(ctx, metadata.AppVersion, tmpDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to synchronize RBAC rules: %w", err)
+	}
+
+	report := &Report{}
+	var drifted []string
+	for _, f := range opts.Files {
+		diffs, changed, err := syncFile(opts, f, tree, chartDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to synchronize %s RBAC rules: %w", f.Name, err)
+		}
+		if changed != "" {
+			drifted = append(drifted, changed)
+		}
+		report.Differences = append(report.Differences, diffs...)
+	}
+
+	if len(drifted) > 0 {
+		return report, fmt.Errorf("%w: %s", ErrDrift, strings.Join(drifted, ", "))
+	}
+	return report, nil
+}
+
+// syncFile updates or checks the managed rules of the chart template of f, and compares the roles it renders
+// with the upstream ones. In check mode, it returns the path of the template if its managed rules are out of date.
+func syncFile(opts Options, f File, tree fs.FS, chartDir string) ([]Difference, string, error) {
+	data, err := fs.ReadFile(tree, f.Source)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file %s: %w", f.Source, err)
+	}
+	upstream, err := parseRoles(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", f.Source, err)
+	}
+
+	destPath := filepath.Join(chartDir, f.Destination)
+	current, err := os.ReadFile(destPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file %s: %w", destPath, err)
+	}
+	updated, managed, err := updateManagedBlocks(current, upstream)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to update %s: %w", destPath, err)
+	}
+	if len(managed) == 0 {
+		log.Infof("%s has no managed rules, only comparing them", destPath)
+	}
+
+	drifted := ""
+	switch {
+	case bytes.Equal(current, updated):
+		log.Infof("%s is up to date", destPath)
+	case opts.Check:
+		if err := writeDiff(opts.Out, destPath, current, updated); err != nil {
+			return nil, "", err
+		}
+		drifted = destPath
+	default:
+		if err := os.WriteFile(destPath, updated, 0o644); err != nil { //nolint:gosec // G306: chart files are intentionally world-readable
+			return nil, "", fmt.Errorf("failed to write file %s: %w", destPath, err)
+		}
+		log.Infof("Wrote %s", destPath)
+	}
+
+	// The roles are compared as the chart renders them, with the rules outside of the managed blocks.
+	rendered, err := render.Template(chartDir, f.Destination)
+	if err != nil {
+		return nil, "", err
+	}
+	chart, err := parseRoles(rendered)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read roles rendered from %s: %w", destPath, err)
+	}
+
+	var diffs []Difference
+	for _, kind := range kinds {
+		u, ok := upstream[kind]
+		if !ok {
+			continue
+		}
+		c, ok := chart[kind]
+		if !ok {
+			return nil, "", fmt.Errorf("%s renders no %s with the default values", destPath, kind)
+		}
+		kindDiffs, err := compareRoles(u.Rules, c.Rules)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to compare %ss: %w", kind, err)
+		}
+		for _, d := range kindDiffs {
+			d.Set = f.Name
+			d.Kind = kind
+			diffs = append(diffs, d)
+		}
+	}
+	return diffs, drifted, nil
+}
+
+// writeDiff writes a unified diff of the committed and updated template to out.
+func writeDiff(out io.Writer, destPath string, current, updated []byte) error {
+	if out == nil {
+		out = os.Stdout
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(current)),
+		B:        difflib.SplitLines(string(updated)),
+		FromFile: path.Join("a", filepath.ToSlash(destPath)),
+		ToFile:   path.Join("b", filepath.ToSlash(destPath)),
+		Context:  3,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to diff %s: %w", destPath, err)
+	}
+	if _, err := io.WriteString(out, diff); err != nil {
+		return fmt.Errorf("failed to write diff: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds/crdstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUpstreamManifest = `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: contour
+rules:
+- apiGroups:
+  - ""
+  resources:
+  - secrets
+  verbs:
+  - get
+  - list
+  - watch
+- apiGroups:
+  - gateway.networking.k8s.io
+  resources:
+  - backendtlspolicies/status
+  verbs:
+  - update
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: contour
+  namespace: projectcontour
+rules:
+- apiGroups:
+  - coordination.k8s.io
+  resources:
+  - leases
+  verbs:
+  - create
+  - get
+  - update
+`
+
+const testTemplate = `{{- if .Values.rbac.create -}}
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: {{ include "test.fullname" . }}
+rules:
+  # BEGIN ClusterRole rules managed by synchronize-rbac, do not edit.
+  - apiGroups:
+      - ""
+    resources:
+      - secrets
+    verbs:
+      - get
+      - list
+      - watch
+  # END ClusterRole rules managed by synchronize-rbac.
+  - apiGroups:
+      - ""
+    resources:
+      - endpoints
+    verbs:
+      - get
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: {{ include "test.fullname" . }}
+  namespace: {{ .Release.Namespace }}
+rules:
+  # BEGIN Role rules managed by synchronize-rbac, do not edit.
+  - apiGroups:
+      - coordination.k8s.io
+    resources:
+      - leases
+    verbs:
+      - create
+      - get
+      - update
+  # END Role rules managed by synchronize-rbac.
+{{- end }}
+`
+
+var testFiles = []File{{Name: "Contour", Source: "examples/contour/02-role-contour.yaml", Destination: "templates/rbac.yaml"}}
+
+// writeChart writes a chart rendering the roles of template, and returns the path of its Chart.yaml.
+func writeChart(t *testing.T, template string) string {
+	return crdstest.WriteChart(t, map[string]string{
+		"values.yaml":            "rbac:\n  create: true\n",
+		"templates/_helpers.tpl": `{{- define "test.fullname" -}}{{ .Release.Name }}-{{ .Chart.Name }}{{- end -}}` + "\n",
+		"templates/rbac.yaml":    template,
+	})
+}
+
+// writeSource writes a Contour checkout holding the upstream role manifest.
+func writeSource(t *testing.T, manifest string) crds.Source {
+	return &crds.DirSource{Path: crdstest.WriteCheckout(t, map[string]string{"examples/contour/02-role-contour.yaml": manifest})}
+}
+
+func TestRun(t *testing.T) {
+	chartPath := writeChart(t, testTemplate)
+	templatePath := filepath.Join(filepath.Dir(chartPath), "templates", "rbac.yaml")
+
+	report, err := Run(context.Background(), Options{ChartPath: chartPath, Files: testFiles, Source: writeSource(t, testUpstreamManifest)})
+	require.NoError(t, err)
+
+	// The missing rule was added to the managed block, leaving the extra rule alone.
+	data, err := os.ReadFile(templatePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `      - watch
+  - apiGroups:
+      - gateway.networking.k8s.io
+    resources:
+      - backendtlspolicies/status
+    verbs:
+      - update
+  # END ClusterRole rules managed by synchronize-rbac.
+  - apiGroups:
+      - ""
+    resources:
+      - endpoints
+`)
+	assert.Equal(t, []Difference{
+		{Set: "Contour", Kind: "ClusterRole", Rule: Rule{Resource: "endpoints", Verbs: []string{"get"}}},
+	}, report.Differences)
+	assert.False(t, report.Missing())
+}
+
+func TestRunCheck(t *testing.T) {
+	chartPath := writeChart(t, testTemplate)
+	templatePath := filepath.Join(filepath.Dir(chartPath), "templates", "rbac.yaml")
+	opts := Options{ChartPath: chartPath, Files: testFiles, Source: writeSource(t, testUpstreamManifest), Check: true}
+
+	t.Run("out of date", func(t *testing.T) {
+		var out bytes.Buffer
+		opts.Out = &out
+		report, err := Run(context.Background(), opts)
+		require.ErrorIs(t, err, ErrDrift)
+		assert.Equal(t, ErrDrift.Error()+": "+templatePath, err.Error())
+		assert.Contains(t, out.String(), "--- "+path.Join("a", filepath.ToSlash(templatePath))+"\n")
+		assert.Contains(t, out.String(), "+      - backendtlspolicies/status\n")
+
+		// The roles are compared as committed, so the chart lacks the new rule.
+		assert.True(t, report.Missing())
+		assert.Contains(t, report.Differences, Difference{
+			Set: "Contour", Kind: "ClusterRole", Missing: true,
+			Rule: Rule{APIGroup: "gateway.networking.k8s.io", Resource: "backendtlspolicies/status", Verbs: []string{"update"}},
+		})
+
+		// Nothing was written.
+		data, err := os.ReadFile(templatePath)
+		require.NoError(t, err)
+		assert.Equal(t, testTemplate, string(data))
+	})
+
+	opts.Check = false
+	_, err := Run(context.Background(), opts)
+	require.NoError(t, err)
+	opts.Check = true
+
+	t.Run("up to date", func(t *testing.T) {
+		var out bytes.Buffer
+		opts.Out = &out
+		report, err := Run(context.Background(), opts)
+		require.NoError(t, err)
+		assert.Empty(t, out.String())
+		assert.False(t, report.Missing())
+	})
+}
+
+func TestRunUnmanaged(t *testing.T) {
+	// Without markers, the rules are only compared.
+	template := strings.NewReplacer(
+		"  # BEGIN ClusterRole rules managed by synchronize-rbac, do not edit.\n", "",
+		"  # END ClusterRole rules managed by synchronize-rbac.\n", "",
+		"  # BEGIN Role rules managed by synchronize-rbac, do not edit.\n", "",
+		"  # END Role rules managed by synchronize-rbac.\n", "",
+	).Replace(testTemplate)
+	chartPath := writeChart(t, template)
+
+	var out bytes.Buffer
+	report, err := Run(context.Background(), Options{ChartPath: chartPath, Files: testFiles, Source: writeSource(t, testUpstreamManifest), Check: true, Out: &out})
+	require.NoError(t, err)
+	assert.Empty(t, out.String())
+
+	var text bytes.Buffer
+	require.NoError(t, report.WriteText(&text))
+	assert.Equal(t, `Contour ClusterRole:
+  missing backendtlspolicies.gateway.networking.k8s.io/status: update
+  extra endpoints: get
+`, text.String())
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := map[string]struct {
+		template string
+		manifest string
+		wantErr  string
+	}{
+		"role not rendered": {
+			template: "{{- if false }}\n" + testTemplate + "{{- end }}\n",
+			manifest: testUpstreamManifest,
+			wantErr:  "renders no ClusterRole with the default values",
+		},
+		"invalid upstream manifest": {
+			template: testTemplate,
+			manifest: "kind: [ClusterRole\n",
+			wantErr:  "failed to read examples/contour/02-role-contour.yaml",
+		},
+		"unterminated managed block": {
+			template: strings.Replace(testTemplate, "  # END Role rules managed by synchronize-rbac.\n", "", 1),
+			manifest: testUpstreamManifest,
+			wantErr:  "managed Role rules do not end",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Run(context.Background(), Options{ChartPath: writeChart(t, tc.template), Files: testFiles, Source: writeSource(t, tc.manifest), Check: true, Out: &bytes.Buffer{}})
+			require.ErrorContains(t, err, "failed to synchronize Contour RBAC rules")
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestRunNoFiles(t *testing.T) {
+	// Nothing is read from the missing source.
+	report, err := Run(context.Background(), Options{ChartPath: writeChart(t, testTemplate), Source: &crds.DirSource{Path: filepath.Join(t.TempDir(), "contour")}})
+	require.NoError(t, err)
+	assert.Empty(t, report.Differences)
+}
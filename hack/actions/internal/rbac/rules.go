@@ -0,0 +1,240 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// role is a ClusterRole or Role manifest.
+type role struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Rules []policyRule `yaml:"rules"`
+}
+
+// policyRule is a rule of a role, as written in its manifest.
+type policyRule struct {
+	APIGroups       []string `yaml:"apiGroups,omitempty"`
+	Resources       []string `yaml:"resources,omitempty"`
+	ResourceNames   []string `yaml:"resourceNames,omitempty"`
+	NonResourceURLs []string `yaml:"nonResourceURLs,omitempty"`
+	Verbs           []string `yaml:"verbs"`
+}
+
+// parseRoles returns the ClusterRoles and Roles of a multi-document manifest, by kind.
+// Each kind must appear at most once, since roles are matched by kind.
+func parseRoles(data []byte) (map[string]*role, error) {
+	roles := map[string]*role{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		r := &role{}
+		err := dec.Decode(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal yaml: %w", err)
+		}
+		if r.Kind != "ClusterRole" && r.Kind != "Role" {
+			continue
+		}
+		if _, ok := roles[r.Kind]; ok {
+			return nil, fmt.Errorf("found several %ss, only one per file is supported", r.Kind)
+		}
+		roles[r.Kind] = r
+	}
+	return roles, nil
+}
+
+// Rule grants verbs on a resource of an API group. Roles are compared rule by rule after flattening
+// their policy rules, so that the same permissions written differently compare equal.
+type Rule struct {
+	// APIGroup is the API group of the resource, empty for the core group.
+	APIGroup string
+
+	// Resource is the resource, such as ingresses or ingresses/status.
+	Resource string
+
+	// Verbs are the verbs granted on the resource, sorted.
+	Verbs []string
+}
+
+// String formats the rule like kubectl names resources, such as ingresses.networking.k8s.io/status: get, update.
+func (r Rule) String() string {
+	name, subresource, _ := strings.Cut(r.Resource, "/")
+	if r.APIGroup != "" {
+		name += "." + r.APIGroup
+	}
+	if subresource != "" {
+		name += "/" + subresource
+	}
+	return name + ": " + strings.Join(r.Verbs, ", ")
+}
+
+// grants is the set of verbs granted by a role, by API group and resource.
+type grants map[string]map[string][]string
+
+// flatten returns the verbs granted by rules. Rules restricted to resource names or granting
+// non-resource URLs cannot be compared that way and are rejected.
+func flatten(rules []policyRule) (grants, error) {
+	g := grants{}
+	for _, rule := range rules {
+		if len(rule.ResourceNames) > 0 || len(rule.NonResourceURLs) > 0 {
+			return nil, errors.New("rules with resourceNames or nonResourceURLs are not supported")
+		}
+		for _, group := range rule.APIGroups {
+			if g[group] == nil {
+				g[group] = map[string][]string{}
+			}
+			for _, resource := range rule.Resources {
+				for _, verb := range rule.Verbs {
+					if !slices.Contains(g[group][resource], verb) {
+						g[group][resource] = append(g[group][resource], verb)
+					}
+				}
+			}
+		}
+	}
+	return g, nil
+}
+
+// allows reports whether the grants allow verb on resource of group, taking wildcards into account.
+func (g grants) allows(group, resource, verb string) bool {
+	for _, grp := range []string{group, "*"} {
+		for _, res := range []string{resource, "*"} {
+			verbs := g[grp][res]
+			if slices.Contains(verbs, verb) || slices.Contains(verbs, "*") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rules returns the grants as rules sorted by API group and resource.
+func (g grants) rules() []Rule {
+	var rules []Rule
+	for group, resources := range g {
+		for resource, verbs := range resources {
+			rules = append(rules, Rule{APIGroup: group, Resource: resource, Verbs: slices.Sorted(slices.Values(verbs))})
+		}
+	}
+	slices.SortFunc(rules, func(a, b Rule) int {
+		return cmp.Or(cmp.Compare(a.APIGroup, b.APIGroup), cmp.Compare(a.Resource, b.Resource))
+	})
+	return rules
+}
+
+// Difference is a rule granted only by the upstream role or only by the chart role.
+type Difference struct {
+	// Set is the name of the role file in the chart's config file, such as Contour.
+	Set string
+
+	// Kind is the kind of the role, ClusterRole or Role.
+	Kind string
+
+	// Rule holds the verbs missing from the chart role, or granted on top of the upstream role.
+	Rule Rule
+
+	// Missing is set if the chart role lacks the rule. Otherwise the chart grants it on top of the
+	// upstream role, which may be on purpose, such as for older versions of the component.
+	Missing bool
+}
+
+func (d Difference) String() string {
+	if d.Missing {
+		return "missing " + d.Rule.String()
+	}
+	return "extra " + d.Rule.String()
+}
+
+// compareRoles returns the rules the chart role lacks and the rules it grants on top of upstream,
+// missing ones first.
+func compareRoles(upstream, chart []policyRule) ([]Difference, error) {
+	want, err := flatten(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upstream rules: %w", err)
+	}
+	got, err := flatten(chart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chart rules: %w", err)
+	}
+
+	var diffs []Difference
+	for _, rule := range want.rules() {
+		var missing []string
+		for _, verb := range rule.Verbs {
+			if !got.allows(rule.APIGroup, rule.Resource, verb) {
+				missing = append(missing, verb)
+			}
+		}
+		if len(missing) > 0 {
+			rule.Verbs = missing
+			diffs = append(diffs, Difference{Rule: rule, Missing: true})
+		}
+	}
+	for _, rule := range got.rules() {
+		var extra []string
+		for _, verb := range rule.Verbs {
+			if !want.allows(rule.APIGroup, rule.Resource, verb) {
+				extra = append(extra, verb)
+			}
+		}
+		if len(extra) > 0 {
+			rule.Verbs = extra
+			diffs = append(diffs, Difference{Rule: rule})
+		}
+	}
+	return diffs, nil
+}
+
+// Report lists the differences between the upstream roles and the roles rendered from the chart.
+type Report struct {
+	Differences []Difference
+}
+
+// Missing reports whether the chart lacks rules granted upstream.
+func (r *Report) Missing() bool {
+	return slices.ContainsFunc(r.Differences, func(d Difference) bool { return d.Missing })
+}
+
+// WriteText writes the differences grouped by role, one per line.
+func (r *Report) WriteText(w io.Writer) error {
+	if len(r.Differences) == 0 {
+		_, err := io.WriteString(w, "No RBAC differences.\n")
+		return err
+	}
+	for i, d := range r.Differences {
+		if i == 0 || d.Set != r.Differences[i-1].Set || d.Kind != r.Differences[i-1].Kind {
+			if _, err := fmt.Fprintf(w, "%s %s:\n", d.Set, d.Kind); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "  %s\n", d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
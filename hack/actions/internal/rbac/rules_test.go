@@ -0,0 +1,186 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRoles(t *testing.T) {
+	roles, err := parseRoles([]byte(`apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: contour
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: contour
+rules:
+- apiGroups: [""]
+  resources: [secrets]
+  verbs: [get, list, watch]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: contour
+rules:
+- apiGroups: [coordination.k8s.io]
+  resources: [leases]
+  verbs: [create, get, update]
+`))
+	require.NoError(t, err)
+	require.Len(t, roles, 2)
+	assert.Equal(t, []policyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get", "list", "watch"}}}, roles["ClusterRole"].Rules)
+	assert.Equal(t, "contour", roles["Role"].Metadata.Name)
+
+	_, err = parseRoles([]byte("kind: Role\n---\nkind: Role\n"))
+	require.EqualError(t, err, "found several Roles, only one per file is supported")
+
+	_, err = parseRoles([]byte("kind: [Role\n"))
+	require.ErrorContains(t, err, "failed to unmarshal yaml")
+}
+
+func TestRuleString(t *testing.T) {
+	tests := map[string]struct {
+		rule Rule
+		want string
+	}{
+		"core group": {
+			rule: Rule{Resource: "secrets", Verbs: []string{"get", "list"}},
+			want: "secrets: get, list",
+		},
+		"named group": {
+			rule: Rule{APIGroup: "networking.k8s.io", Resource: "ingresses", Verbs: []string{"get"}},
+			want: "ingresses.networking.k8s.io: get",
+		},
+		"subresource": {
+			rule: Rule{APIGroup: "networking.k8s.io", Resource: "ingresses/status", Verbs: []string{"create", "get"}},
+			want: "ingresses.networking.k8s.io/status: create, get",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.rule.String())
+		})
+	}
+}
+
+func TestCompareRoles(t *testing.T) {
+	upstream := []policyRule{
+		{APIGroups: []string{""}, Resources: []string{"secrets", "services"}, Verbs: []string{"get", "list", "watch"}},
+		{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"ingresses/status"}, Verbs: []string{"update"}},
+	}
+
+	tests := map[string]struct {
+		chart []policyRule
+		want  []Difference
+	}{
+		"same rules": {
+			chart: upstream,
+		},
+		"same rules written differently": {
+			chart: []policyRule{
+				{APIGroups: []string{""}, Resources: []string{"services"}, Verbs: []string{"watch", "list", "get"}},
+				{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}},
+				{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"list", "watch"}},
+				{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"ingresses/status"}, Verbs: []string{"update"}},
+			},
+		},
+		"missing and extra verbs": {
+			chart: []policyRule{
+				{APIGroups: []string{""}, Resources: []string{"secrets", "services"}, Verbs: []string{"get", "list"}},
+				{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"create"}},
+				{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"ingresses/status"}, Verbs: []string{"create", "update"}},
+			},
+			want: []Difference{
+				{Rule: Rule{Resource: "secrets", Verbs: []string{"watch"}}, Missing: true},
+				{Rule: Rule{Resource: "services", Verbs: []string{"watch"}}, Missing: true},
+				{Rule: Rule{Resource: "configmaps", Verbs: []string{"create"}}},
+				{Rule: Rule{APIGroup: "networking.k8s.io", Resource: "ingresses/status", Verbs: []string{"create"}}},
+			},
+		},
+		"wildcards": {
+			chart: []policyRule{
+				{APIGroups: []string{""}, Resources: []string{"*"}, Verbs: []string{"get", "list", "watch"}},
+				{APIGroups: []string{"*"}, Resources: []string{"ingresses/status"}, Verbs: []string{"*"}},
+			},
+			want: []Difference{
+				{Rule: Rule{Resource: "*", Verbs: []string{"get", "list", "watch"}}},
+				{Rule: Rule{APIGroup: "*", Resource: "ingresses/status", Verbs: []string{"*"}}},
+			},
+		},
+		"no rules": {
+			want: []Difference{
+				{Rule: Rule{Resource: "secrets", Verbs: []string{"get", "list", "watch"}}, Missing: true},
+				{Rule: Rule{Resource: "services", Verbs: []string{"get", "list", "watch"}}, Missing: true},
+				{Rule: Rule{APIGroup: "networking.k8s.io", Resource: "ingresses/status", Verbs: []string{"update"}}, Missing: true},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			diffs, err := compareRoles(upstream, tc.chart)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, diffs)
+		})
+	}
+}
+
+func TestCompareRolesUnsupported(t *testing.T) {
+	_, err := compareRoles(nil, []policyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, ResourceNames: []string{"tls"}, Verbs: []string{"get"}}})
+	require.EqualError(t, err, "failed to read chart rules: rules with resourceNames or nonResourceURLs are not supported")
+
+	_, err = compareRoles([]policyRule{{NonResourceURLs: []string{"/metrics"}, Verbs: []string{"get"}}}, nil)
+	require.EqualError(t, err, "failed to read upstream rules: rules with resourceNames or nonResourceURLs are not supported")
+}
+
+func TestReport(t *testing.T) {
+	t.Run("no differences", func(t *testing.T) {
+		report := &Report{}
+		var out bytes.Buffer
+		require.NoError(t, report.WriteText(&out))
+		assert.Equal(t, "No RBAC differences.\n", out.String())
+		assert.False(t, report.Missing())
+	})
+
+	t.Run("differences", func(t *testing.T) {
+		report := &Report{Differences: []Difference{
+			{Set: "Contour", Kind: "ClusterRole", Rule: Rule{APIGroup: "gateway.networking.k8s.io", Resource: "backendtlspolicies/status", Verbs: []string{"update"}}, Missing: true},
+			{Set: "Contour", Kind: "ClusterRole", Rule: Rule{Resource: "endpoints", Verbs: []string{"get", "list", "watch"}}},
+			{Set: "Contour", Kind: "Role", Rule: Rule{Resource: "events", Verbs: []string{"create"}}, Missing: true},
+		}}
+		var out bytes.Buffer
+		require.NoError(t, report.WriteText(&out))
+		assert.Equal(t, `Contour ClusterRole:
+  missing backendtlspolicies.gateway.networking.k8s.io/status: update
+  extra endpoints: get, list, watch
+Contour Role:
+  missing events: create
+`, out.String())
+		assert.True(t, report.Missing())
+	})
+
+	t.Run("extra rules only", func(t *testing.T) {
+		report := &Report{Differences: []Difference{{Set: "Contour", Kind: "Role", Rule: Rule{Resource: "configmaps", Verbs: []string{"create"}}}}}
+		assert.False(t, report.Missing())
+	})
+}
@@ -0,0 +1,165 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const maxConfigSize = 4 << 20
+
+// ErrPlatformNotFound is returned when an image is not published for a required platform.
+var ErrPlatformNotFound = errors.New("platform not found")
+
+// Platform is an operating system and CPU architecture an image is published for, such as linux/arm64.
+type Platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// ParsePlatform parses a platform such as linux/amd64 or linux/arm/v7.
+func ParsePlatform(s string) (Platform, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 || slices.Contains(parts, "") {
+		return Platform{}, fmt.Errorf("invalid platform %q, expected OS/ARCH[/VARIANT]", s)
+	}
+
+	p := Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p, nil
+}
+
+// ParsePlatforms parses a comma-separated list of platforms, such as linux/amd64,linux/arm64.
+// An empty string yields no platforms.
+func ParsePlatforms(s string) ([]Platform, error) {
+	var platforms []Platform
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		p, err := ParsePlatform(field)
+		if err != nil {
+			return nil, err
+		}
+		platforms = append(platforms, p)
+	}
+	return platforms, nil
+}
+
+func (p Platform) String() string {
+	if p.Variant == "" {
+		return p.OS + "/" + p.Architecture
+	}
+	return p.OS + "/" + p.Architecture + "/" + p.Variant
+}
+
+// Satisfies reports whether an image for p can be used where want is required.
+// A required platform without a variant accepts any variant.
+func (p Platform) Satisfies(want Platform) bool {
+	return p.OS == want.OS && p.Architecture == want.Architecture && (want.Variant == "" || p.Variant == want.Variant)
+}
+
+// Platforms returns the platforms the manifest of ref is published for. For a multi-arch index these are
+// the platforms of its manifests, and for a single image manifest the platform of its image config.
+func (c *Client) Platforms(ctx context.Context, ref Reference, m *Manifest) ([]Platform, error) {
+	if m.IsIndex() {
+		var index struct {
+			Manifests []struct {
+				Platform *Platform `json:"platform"`
+			} `json:"manifests"`
+		}
+		if err := json.Unmarshal(m.Body, &index); err != nil {
+			return nil, fmt.Errorf("failed to decode index of %s: %w", ref, err)
+		}
+
+		var platforms []Platform
+		for _, d := range index.Manifests {
+			// Skip entries without a platform and attestation manifests, which use unknown/unknown.
+			if d.Platform == nil || d.Platform.OS == "unknown" {
+				continue
+			}
+			platforms = append(platforms, *d.Platform)
+		}
+		return platforms, nil
+	}
+
+	var manifest struct {
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(m.Body, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest of %s: %w", ref, err)
+	}
+	if manifest.Config.Digest == "" {
+		return nil, fmt.Errorf("manifest of %s has no image config", ref)
+	}
+
+	resp, err := c.fetch(ctx, ref.Registry, ref.Repository, "blobs/"+manifest.Config.Digest, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch image config %s of %s: status code %d", manifest.Config.Digest, ref, resp.StatusCode)
+	}
+
+	var config Platform
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxConfigSize)).Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to decode image config of %s: %w", ref, err)
+	}
+	return []Platform{config}, nil
+}
+
+// Verify fetches the manifest of ref and checks that the image is published for all of the given platforms.
+// It returns ErrNotFound if the tag does not exist and ErrPlatformNotFound if a platform is missing.
+func (c *Client) Verify(ctx context.Context, ref Reference, platforms []Platform) (*Manifest, error) {
+	m, err := c.Manifest(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := c.Platforms(ctx, ref, m)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, want := range platforms {
+		if !slices.ContainsFunc(available, func(p Platform) bool { return p.Satisfies(want) }) {
+			missing = append(missing, want.String())
+		}
+	}
+	if len(missing) > 0 {
+		names := make([]string, 0, len(available))
+		for _, p := range available {
+			names = append(names, p.String())
+		}
+		return nil, fmt.Errorf("image %s is not published for %s, only for %s: %w",
+			ref, strings.Join(missing, ", "), strings.Join(names, ", "), ErrPlatformNotFound)
+	}
+
+	return m, nil
+}
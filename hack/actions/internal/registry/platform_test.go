@@ -0,0 +1,128 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/registry/registrytest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlatform(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    Platform
+		wantErr bool
+	}{
+		"os and architecture": {input: "linux/amd64", want: Platform{OS: "linux", Architecture: "amd64"}},
+		"with variant":        {input: "linux/arm/v7", want: Platform{OS: "linux", Architecture: "arm", Variant: "v7"}},
+		"missing arch":        {input: "linux", wantErr: true},
+		"empty arch":          {input: "linux/", wantErr: true},
+		"too many parts":      {input: "linux/arm/v7/x", wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParsePlatform(tc.input)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.input, got.String())
+		})
+	}
+}
+
+func TestParsePlatforms(t *testing.T) {
+	got, err := ParsePlatforms("linux/amd64, linux/arm64")
+	require.NoError(t, err)
+	assert.Equal(t, []Platform{{OS: "linux", Architecture: "amd64"}, {OS: "linux", Architecture: "arm64"}}, got)
+
+	got, err = ParsePlatforms("")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = ParsePlatforms("linux/amd64,arm64")
+	require.ErrorContains(t, err, `invalid platform "arm64"`)
+}
+
+func TestVerify(t *testing.T) {
+	reg := registrytest.New(t)
+	indexDigest := reg.PushIndex("projectcontour/contour", "v1.33.6", "linux/amd64", "linux/arm64/v8", "unknown/unknown")
+	reg.PushIndex("projectcontour/contour", "v1.33.7", "linux/amd64")
+	imageDigest := reg.PushImage("envoyproxy/envoy", "v1.35.8", "linux/amd64")
+
+	c := &Client{HTTPClient: reg.Client()}
+	amd64 := Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := Platform{OS: "linux", Architecture: "arm64"}
+
+	tests := map[string]struct {
+		ref        Reference
+		platforms  []Platform
+		wantDigest string
+		wantErr    error
+		wantMsg    string
+	}{
+		"index with all platforms": {
+			ref:        Reference{Registry: reg.Host(), Repository: "projectcontour/contour", Tag: "v1.33.6"},
+			platforms:  []Platform{amd64, arm64},
+			wantDigest: indexDigest,
+		},
+		"index missing a platform": {
+			ref:       Reference{Registry: reg.Host(), Repository: "projectcontour/contour", Tag: "v1.33.7"},
+			platforms: []Platform{amd64, arm64},
+			wantErr:   ErrPlatformNotFound,
+			wantMsg:   "is not published for linux/arm64, only for linux/amd64",
+		},
+		"variant must match when required": {
+			ref:       Reference{Registry: reg.Host(), Repository: "projectcontour/contour", Tag: "v1.33.6"},
+			platforms: []Platform{{OS: "linux", Architecture: "arm64", Variant: "v7"}},
+			wantErr:   ErrPlatformNotFound,
+		},
+		"single-arch image": {
+			ref:        Reference{Registry: reg.Host(), Repository: "envoyproxy/envoy", Tag: "v1.35.8"},
+			platforms:  []Platform{amd64},
+			wantDigest: imageDigest,
+		},
+		"single-arch image missing a platform": {
+			ref:       Reference{Registry: reg.Host(), Repository: "envoyproxy/envoy", Tag: "v1.35.8"},
+			platforms: []Platform{amd64, arm64},
+			wantErr:   ErrPlatformNotFound,
+			wantMsg:   "is not published for linux/arm64, only for linux/amd64",
+		},
+		"missing tag": {
+			ref:       Reference{Registry: reg.Host(), Repository: "envoyproxy/envoy", Tag: "v0.0.0"},
+			platforms: []Platform{amd64},
+			wantErr:   ErrNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m, err := c.Verify(context.Background(), tc.ref, tc.platforms)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				assert.ErrorContains(t, err, tc.wantMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantDigest, m.Digest)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package registry resolves container image manifests over the OCI distribution API.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Media types of manifests and indexes accepted from registries.
+const (
+	MediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+	MediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	maxManifestSize         = 4 << 20
+)
+
+// ErrNotFound is returned when a manifest does not exist in the registry.
+var ErrNotFound = errors.New("not found")
+
+var acceptedMediaTypes = strings.Join([]string{
+	MediaTypeOCIIndex,
+	MediaTypeDockerList,
+	MediaTypeOCIManifest,
+	MediaTypeDockerManifest,
+}, ", ")
+
+// Reference identifies an image tag in a registry, for example ghcr.io/projectcontour/contour:v1.33.6.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+}
+
+func (r Reference) String() string {
+	return fmt.Sprintf("%s/%s:%s", r.Registry, r.Repository, r.Tag)
+}
+
+// Manifest is a manifest or multi-arch index as served by the registry.
+type Manifest struct {
+	MediaType string
+	Digest    string
+	Body      []byte
+}
+
+// IsIndex reports whether the manifest is a multi-arch index.
+func (m *Manifest) IsIndex() bool {
+	return m.MediaType == MediaTypeOCIIndex || m.MediaType == MediaTypeDockerList
+}
+
+// Client talks to registries over the OCI distribution API.
+// Anonymous bearer tokens are requested automatically when a registry asks for them.
+type Client struct {
+	// HTTPClient is the HTTP client used for requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// PlainHTTP makes the client use http instead of https, for local registries.
+	PlainHTTP bool
+}
+
+// Digest returns the digest of the manifest the tag points to.
+// For multi-arch images this is the digest of the index.
+func (c *Client) Digest(ctx context.Context, ref Reference) (string, error) {
+	m, err := c.Manifest(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return m.Digest, nil
+}
+
+// Manifest fetches the manifest the tag points to.
+func (c *Client) Manifest(ctx context.Context, ref Reference) (*Manifest, error) {
+	return c.fetchManifest(ctx, ref.Registry, ref.Repository, ref.Tag)
+}
+
+func (c *Client) fetchManifest(ctx context.Context, registry, repository, reference string) (*Manifest, error) {
+	resp, err := c.fetch(ctx, registry, repository, "manifests/"+reference, acceptedMediaTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("manifest %s not found in %s/%s: %w", reference, registry, repository, ErrNotFound)
+	default:
+		return nil, fmt.Errorf("failed to fetch manifest %s from %s/%s: status code %d", reference, registry, repository, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	sum := sha256.Sum256(body)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if header := resp.Header.Get("Docker-Content-Digest"); header != "" && strings.HasPrefix(header, "sha256:") && header != digest {
+		return nil, fmt.Errorf("manifest digest mismatch for %s/%s:%s: registry reported %s, computed %s", registry, repository, reference, header, digest)
+	}
+
+	mediaType := resp.Header.Get("Content-Type")
+	if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	if mediaType == "" || mediaType == "application/json" {
+		// Fall back to the media type declared in the manifest itself.
+		var m struct {
+			MediaType string `json:"mediaType"`
+		}
+		if err := json.Unmarshal(body, &m); err == nil {
+			mediaType = m.MediaType
+		}
+	}
+
+	return &Manifest{MediaType: mediaType, Digest: digest, Body: body}, nil
+}
+
+// fetch performs a GET request for path below /v2/<repository>/ in the registry, such as manifests/v1.33.6,
+// authenticating with an anonymous token if the registry asks for one. The caller must close the response body.
+func (c *Client) fetch(ctx context.Context, registry, repository, path, accept string) (*http.Response, error) {
+	host, repository := resolve(registry, repository)
+	url := fmt.Sprintf("%s://%s/v2/%s/%s", c.scheme(), host, repository, path)
+
+	resp, err := c.get(ctx, url, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		token, err := c.token(ctx, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to authenticate to %s: %w", host, err)
+		}
+
+		return c.get(ctx, url, accept, "Authorization", "Bearer "+token)
+	}
+
+	return resp, nil
+}
+
+// get performs a GET request with the given Accept header and optional extra header key/value pairs.
+func (c *Client) get(ctx context.Context, url, accept string, headers ...string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := c.httpClient().Do(req) //nolint:gosec // G704: registry URLs come from the chart values
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	return resp, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) scheme() string {
+	if c.PlainHTTP {
+		return "http"
+	}
+	return "https"
+}
+
+// resolve maps a registry and repository as written in values.yaml to the API host and repository name.
+func resolve(registry, repository string) (string, string) {
+	switch registry {
+	case "docker.io", "index.docker.io", "registry.hub.docker.com":
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+		return "registry-1.docker.io", repository
+	default:
+		return registry, repository
+	}
+}
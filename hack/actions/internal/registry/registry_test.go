@@ -0,0 +1,96 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/registry/registrytest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigest(t *testing.T) {
+	reg := registrytest.New(t)
+	want := reg.PushIndex("projectcontour/contour", "v1.33.6", "linux/amd64", "linux/arm64")
+
+	c := &Client{HTTPClient: reg.Client()}
+	ref := Reference{Registry: reg.Host(), Repository: "projectcontour/contour", Tag: "v1.33.6"}
+
+	got, err := c.Digest(context.Background(), ref)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	m, err := c.Manifest(context.Background(), ref)
+	require.NoError(t, err)
+	assert.True(t, m.IsIndex())
+	assert.Equal(t, MediaTypeOCIIndex, m.MediaType)
+
+	_, err = c.Digest(context.Background(), Reference{Registry: reg.Host(), Repository: "projectcontour/contour", Tag: "v0.0.0"})
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDigestPlainHTTP(t *testing.T) {
+	reg := registrytest.NewPlainHTTP(t)
+	want := reg.PushIndex("envoyproxy/envoy", "v1.35.8", "linux/amd64")
+
+	c := &Client{HTTPClient: reg.Client(), PlainHTTP: true}
+	got, err := c.Digest(context.Background(), Reference{Registry: reg.Host(), Repository: "envoyproxy/envoy", Tag: "v1.35.8"})
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestDigestMismatch(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", MediaTypeOCIIndex)
+		w.Header().Set("Docker-Content-Digest", "sha256:0000")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	c := &Client{HTTPClient: srv.Client()}
+	_, err := c.Digest(context.Background(), Reference{Registry: srv.Listener.Addr().String(), Repository: "a/b", Tag: "c"})
+	require.ErrorContains(t, err, "manifest digest mismatch")
+}
+
+func TestResolve(t *testing.T) {
+	host, repo := resolve("docker.io", "envoyproxy/envoy")
+	assert.Equal(t, "registry-1.docker.io", host)
+	assert.Equal(t, "envoyproxy/envoy", repo)
+
+	host, repo = resolve("docker.io", "busybox")
+	assert.Equal(t, "registry-1.docker.io", host)
+	assert.Equal(t, "library/busybox", repo)
+
+	host, repo = resolve("ghcr.io", "projectcontour/contour")
+	assert.Equal(t, "ghcr.io", host)
+	assert.Equal(t, "projectcontour/contour", repo)
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:projectcontour/contour:pull"`)
+	assert.Equal(t, "Bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://ghcr.io/token",
+		"service": "ghcr.io",
+		"scope":   "repository:projectcontour/contour:pull",
+	}, params)
+
+	scheme, params = parseChallenge(`Basic realm=registry`)
+	assert.Equal(t, "Basic", scheme)
+	assert.Equal(t, map[string]string{"realm": "registry"}, params)
+}
@@ -0,0 +1,256 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package registrytest provides an in-memory registry implementing the subset of the
+// OCI distribution API used by the hack tools. It stands in for a local registry:2 in tests.
+package registrytest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const token = "registrytest-token"
+
+// Registry is a fake container registry. Requests must present an anonymous
+// bearer token obtained through the WWW-Authenticate challenge, like ghcr.io and Docker Hub.
+type Registry struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	manifests map[string]manifest
+	blobs     map[string][]byte
+}
+
+type manifest struct {
+	mediaType string
+	body      []byte
+}
+
+// New starts a TLS registry that is shut down when the test ends.
+// Use the server's Client() to talk to it.
+func New(t *testing.T) *Registry {
+	t.Helper()
+
+	r := &Registry{manifests: map[string]manifest{}, blobs: map[string][]byte{}}
+	r.Server = httptest.NewTLSServer(http.HandlerFunc(r.serveHTTP))
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+// NewPlainHTTP starts a registry without TLS, like a local registry:2 on localhost:5000.
+func NewPlainHTTP(t *testing.T) *Registry {
+	t.Helper()
+
+	r := &Registry{manifests: map[string]manifest{}, blobs: map[string][]byte{}}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serveHTTP))
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+// Host returns the host:port of the registry, as used in image references.
+func (r *Registry) Host() string {
+	return strings.TrimPrefix(strings.TrimPrefix(r.URL, "https://"), "http://")
+}
+
+// PushIndex stores a multi-arch OCI index for repository:tag with an image manifest
+// for each of the given platforms, such as linux/amd64. It returns the index digest.
+func (r *Registry) PushIndex(repository, tag string, platforms ...string) string {
+	type platform struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+		Variant      string `json:"variant,omitempty"`
+	}
+	type descriptor struct {
+		MediaType string    `json:"mediaType"`
+		Digest    string    `json:"digest"`
+		Size      int       `json:"size"`
+		Platform  *platform `json:"platform,omitempty"`
+	}
+
+	index := struct {
+		SchemaVersion int          `json:"schemaVersion"`
+		MediaType     string       `json:"mediaType"`
+		Manifests     []descriptor `json:"manifests"`
+	}{SchemaVersion: 2, MediaType: "application/vnd.oci.image.index.v1+json", Manifests: []descriptor{}}
+
+	for _, p := range platforms {
+		parts := strings.SplitN(p, "/", 3)
+		pl := &platform{OS: parts[0], Architecture: parts[1]}
+		if len(parts) == 3 {
+			pl.Variant = parts[2]
+		}
+
+		// The manifest content only needs to be unique per tag and platform.
+		body := []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json","annotations":{"registrytest":"` + tag + " " + p + `"}}`)
+		digest := r.PushManifest(repository, "", "application/vnd.oci.image.manifest.v1+json", body)
+		index.Manifests = append(index.Manifests, descriptor{
+			MediaType: "application/vnd.oci.image.manifest.v1+json",
+			Digest:    digest,
+			Size:      len(body),
+			Platform:  pl,
+		})
+	}
+
+	body, err := json.Marshal(index)
+	if err != nil {
+		panic(err)
+	}
+	return r.PushManifest(repository, tag, index.MediaType, body)
+}
+
+// PushImage stores a single-arch OCI image manifest for repository:tag whose image config
+// declares the given platform, such as linux/amd64. It returns the manifest digest.
+func (r *Registry) PushImage(repository, tag, platform string) string {
+	parts := strings.SplitN(platform, "/", 3)
+	config := map[string]string{"os": parts[0], "architecture": parts[1]}
+	if len(parts) == 3 {
+		config["variant"] = parts[2]
+	}
+	configBody, err := json.Marshal(config)
+	if err != nil {
+		panic(err)
+	}
+	configDigest := r.PushBlob(repository, configBody)
+
+	body, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"config": map[string]any{
+			"mediaType": "application/vnd.oci.image.config.v1+json",
+			"digest":    configDigest,
+			"size":      len(configBody),
+		},
+		"layers": []any{},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return r.PushManifest(repository, tag, "application/vnd.oci.image.manifest.v1+json", body)
+}
+
+// PushBlob stores a blob for repository and returns its digest.
+func (r *Registry) PushBlob(repository string, body []byte) string {
+	sum := sha256.Sum256(body)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.blobs[repository+"@"+digest] = body
+
+	return digest
+}
+
+// PushManifest stores a raw manifest for repository, by digest and under tag if it is not empty.
+// It returns the manifest digest.
+func (r *Registry) PushManifest(repository, tag, mediaType string, body []byte) string {
+	sum := sha256.Sum256(body)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := manifest{mediaType: mediaType, body: body}
+	r.manifests[repository+"@"+digest] = m
+	if tag != "" {
+		r.manifests[repository+":"+tag] = m
+	}
+
+	return digest
+}
+
+func (r *Registry) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token":"` + token + `"}`))
+		return
+	}
+
+	path, ok := strings.CutPrefix(req.URL.Path, "/v2/")
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	if req.Header.Get("Authorization") != "Bearer "+token {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+r.URL+`/token",service="registrytest",scope="repository:`+path+`:pull"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.NotFound(w, req)
+		return
+	}
+
+	if i := strings.LastIndex(path, "/blobs/"); i >= 0 {
+		r.serveBlob(w, req, path[:i], path[i+len("/blobs/"):])
+		return
+	}
+
+	i := strings.LastIndex(path, "/manifests/")
+	if i < 0 {
+		http.NotFound(w, req)
+		return
+	}
+	repository, reference := path[:i], path[i+len("/manifests/"):]
+
+	key := repository + ":" + reference
+	if strings.HasPrefix(reference, "sha256:") {
+		key = repository + "@" + reference
+	}
+
+	r.mu.Lock()
+	m, ok := r.manifests[key]
+	r.mu.Unlock()
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`))
+		return
+	}
+
+	sum := sha256.Sum256(m.body)
+	w.Header().Set("Content-Type", m.mediaType)
+	w.Header().Set("Docker-Content-Digest", "sha256:"+hex.EncodeToString(sum[:]))
+	if req.Method == http.MethodGet {
+		_, _ = w.Write(m.body)
+	}
+}
+
+func (r *Registry) serveBlob(w http.ResponseWriter, req *http.Request, repository, digest string) {
+	r.mu.Lock()
+	body, ok := r.blobs[repository+"@"+digest]
+	r.mu.Unlock()
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[{"code":"BLOB_UNKNOWN","message":"blob unknown"}]}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", digest)
+	if req.Method == http.MethodGet {
+		_, _ = w.Write(body)
+	}
+}
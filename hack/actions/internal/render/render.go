@@ -0,0 +1,81 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package render renders chart templates with the Helm engine, as helm template does: with the chart's
+// default values, the default capabilities of the Helm library, and lookup finding nothing.
+package render
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/chart/common/util"
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
+	"helm.sh/helm/v4/pkg/engine"
+)
+
+// ReleaseName and Namespace are the release the templates are rendered for, as helm template defaults to.
+const (
+	ReleaseName = "release-name"
+	Namespace   = "default"
+)
+
+// Metadata is the part of Chart.yaml the hack tools read.
+type Metadata struct {
+	Name       string `yaml:"name"`
+	Version    string `yaml:"version"`
+	AppVersion string `yaml:"appVersion"`
+}
+
+// ReadMetadata reads the Chart.yaml of the chart in chartDir.
+func ReadMetadata(chartDir string) (*Metadata, error) {
+	path := filepath.Join(chartDir, "Chart.yaml")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	var metadata Metadata
+	if err := yaml.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal yaml from %s: %w", path, err)
+	}
+	return &metadata, nil
+}
+
+// Template renders the chart in chartDir and returns the template at name, relative to chartDir,
+// such as templates/contour/rbac.yaml.
+func Template(chartDir, name string) ([]byte, error) {
+	chrt, err := loader.LoadDir(chartDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart %s: %w", chartDir, err)
+	}
+
+	options := common.ReleaseOptions{Name: ReleaseName, Namespace: Namespace, Revision: 1, IsInstall: true}
+	values, err := util.ToRenderValues(chrt, nil, options, common.DefaultCapabilities.Copy())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute values of chart %s: %w", chartDir, err)
+	}
+
+	rendered, err := engine.Render(chrt, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart %s: %w", chartDir, err)
+	}
+
+	out, ok := rendered[chrt.Name()+"/"+filepath.ToSlash(name)]
+	if !ok {
+		return nil, fmt.Errorf("failed to render template %s: not found in chart %s", name, chartDir)
+	}
+	return []byte(out), nil
+}
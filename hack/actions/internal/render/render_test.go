@@ -0,0 +1,138 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds/crdstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v4/pkg/chart/common"
+)
+
+// writeChart writes a chart with the given templates and values, and returns its directory.
+func writeChart(t *testing.T, values string, templates map[string]string) string {
+	files := map[string]string{"values.yaml": values}
+	for name, content := range templates {
+		files["templates/"+name] = content
+	}
+	return filepath.Dir(crdstest.WriteChart(t, files))
+}
+
+func TestReadMetadata(t *testing.T) {
+	metadata, err := ReadMetadata(writeChart(t, "", nil))
+	require.NoError(t, err)
+	assert.Equal(t, &Metadata{Name: "test", Version: "1.0.0", AppVersion: "1.33.6"}, metadata)
+
+	_, err = ReadMetadata(t.TempDir())
+	require.ErrorContains(t, err, "failed to read file")
+}
+
+func TestTemplate(t *testing.T) {
+	values := `name: envoy
+labels:
+  team: networking
+extra: "{{ .Release.Name }}-extra"
+`
+	tests := map[string]struct {
+		template string
+		want     string
+	}{
+		"built-in objects": {
+			template: "{{ .Release.Name }} {{ .Release.Namespace }} {{ .Chart.Name }} {{ .Chart.Version }} {{ .Chart.AppVersion }} {{ .Template.BasePath }}",
+			want:     "release-name default test 1.0.0 1.33.6 test/templates",
+		},
+		"include": {
+			template: `{{ include "test.name" . | upper }}`,
+			want:     "RELEASE-NAME-ENVOY",
+		},
+		"tpl": {
+			template: "{{ tpl .Values.extra . }}",
+			want:     "release-name-extra",
+		},
+		"toYaml": {
+			template: "labels:\n  {{- toYaml .Values.labels | nindent 2 }}",
+			want:     "labels:\n  team: networking",
+		},
+		"required": {
+			template: `{{ required "name is required" .Values.name }}`,
+			want:     "envoy",
+		},
+		"lookup finds nothing": {
+			template: `{{ if lookup "v1" "Secret" "default" "tls" }}found{{ else }}none{{ end }}`,
+			want:     "none",
+		},
+		"capabilities": {
+			template: `{{ .Capabilities.KubeVersion.Version }} {{ .Capabilities.APIVersions.Has "monitoring.coreos.com/v1" }}`,
+			want:     common.DefaultCapabilities.KubeVersion.Version + " false",
+		},
+		"missing values": {
+			template: "{{ .Values.missing | default \"none\" }}",
+			want:     "none",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := writeChart(t, values, map[string]string{
+				"_helpers.tpl":  `{{- define "test.name" -}}{{ .Release.Name }}-{{ .Values.name }}{{- end -}}`,
+				"resource.yaml": tc.template,
+			})
+			got, err := Template(dir, "templates/resource.yaml")
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	tests := map[string]struct {
+		template string
+		wantErr  string
+	}{
+		"required value": {
+			template: `{{ required "name is required" .Values.name }}`,
+			wantErr:  "name is required",
+		},
+		"fail": {
+			template: `{{ fail "invalid values" }}`,
+			wantErr:  "invalid values",
+		},
+		"environment": {
+			template: `{{ env "HOME" }}`,
+			wantErr:  `function "env" not defined`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Template(writeChart(t, "name: \"\"\n", map[string]string{"resource.yaml": tc.template}), "templates/resource.yaml")
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestTemplateMissing(t *testing.T) {
+	_, err := Template(writeChart(t, "", nil), "templates/missing.yaml")
+	require.ErrorContains(t, err, "failed to render template templates/missing.yaml: not found in chart")
+}
+
+func TestTemplateChart(t *testing.T) {
+	got, err := Template(filepath.Join("..", "..", "..", "..", "charts", "contour"), "templates/contour/rbac.yaml")
+	require.NoError(t, err)
+	assert.Contains(t, string(got), "kind: ClusterRole\n")
+	assert.Contains(t, string(got), "name: release-name-contour-contour\n")
+}
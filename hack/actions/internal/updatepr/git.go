@@ -0,0 +1,145 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updatepr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Git is the git repository the update is committed to.
+type Git interface {
+	// HasChanges reports whether the working tree has uncommitted changes.
+	HasChanges(ctx context.Context) (bool, error)
+
+	// Status returns the short status of the working tree.
+	Status(ctx context.Context) (string, error)
+
+	// RemoteBranchExists reports whether the branch exists on the remote.
+	RemoteBranchExists(ctx context.Context, branch string) (bool, error)
+
+	// CreateBranch creates and checks out a new branch.
+	CreateBranch(ctx context.Context, branch string) error
+
+	// CommitAll stages all changes and commits them with a DCO sign-off.
+	CommitAll(ctx context.Context, message string) error
+
+	// Push pushes the branch to the remote.
+	Push(ctx context.Context, branch string) error
+}
+
+// ExecGit runs the git command line in a working tree.
+type ExecGit struct {
+	// Dir is the working tree. If empty, the current directory is used.
+	Dir string
+
+	// Remote is the name of the remote to push to. Defaults to origin.
+	Remote string
+
+	// DryRun prints commands that would modify the repository or the remote to Out instead of running them.
+	DryRun bool
+
+	// Out receives dry-run output.
+	Out io.Writer
+}
+
+var _ Git = &ExecGit{}
+
+// HasChanges reports whether the working tree has uncommitted changes, including untracked files.
+func (g *ExecGit) HasChanges(ctx context.Context) (bool, error) {
+	out, err := g.output(ctx, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// Status returns the short status of the working tree.
+func (g *ExecGit) Status(ctx context.Context) (string, error) {
+	return g.output(ctx, "status", "--short")
+}
+
+// RemoteBranchExists reports whether the branch exists on the remote.
+func (g *ExecGit) RemoteBranchExists(ctx context.Context, branch string) (bool, error) {
+	out, err := g.output(ctx, "ls-remote", "--quiet", g.remote(), "refs/heads/"+branch)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// CreateBranch creates and checks out a new branch.
+func (g *ExecGit) CreateBranch(ctx context.Context, branch string) error {
+	return g.mutate(ctx, "checkout", "-b", branch)
+}
+
+// CommitAll stages all changes and commits them with a DCO sign-off.
+func (g *ExecGit) CommitAll(ctx context.Context, message string) error {
+	if err := g.mutate(ctx, "add", "--all"); err != nil {
+		return err
+	}
+	return g.mutate(ctx, "commit", "--signoff", "--message", message)
+}
+
+// Push pushes the branch to the remote.
+func (g *ExecGit) Push(ctx context.Context, branch string) error {
+	return g.mutate(ctx, "push", g.remote(), branch)
+}
+
+func (g *ExecGit) remote() string {
+	if g.Remote == "" {
+		return "origin"
+	}
+	return g.Remote
+}
+
+// mutate runs a git command that modifies the repository or the remote, or prints it in dry-run mode.
+func (g *ExecGit) mutate(ctx context.Context, args ...string) error {
+	if g.DryRun {
+		fmt.Fprintf(g.Out, "[DRY RUN] git %s\n", shellJoin(args))
+		return nil
+	}
+	_, err := g.output(ctx, args...)
+	return err
+}
+
+// output runs a git command and returns its standard output.
+func (g *ExecGit) output(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...) //nolint:gosec // G204: arguments are built by this tool
+	cmd.Dir = g.Dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", shellJoin(args), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// shellJoin joins args into a command line that can be pasted into a shell.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`!*?;&|<>()[]{}#~") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
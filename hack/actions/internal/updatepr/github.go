@@ -0,0 +1,132 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updatepr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultGitHubAPIURL is the GitHub REST API endpoint.
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// PullRequest describes a pull request to open.
+type PullRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Head  string `json:"head"`
+	Base  string `json:"base"`
+}
+
+// GitHub opens pull requests.
+type GitHub interface {
+	// CreatePullRequest opens the pull request and returns its URL.
+	CreatePullRequest(ctx context.Context, pr PullRequest) (string, error)
+}
+
+// GitHubClient opens pull requests through the GitHub REST API.
+type GitHubClient struct {
+	// APIURL is the GitHub REST API endpoint. Defaults to DefaultGitHubAPIURL.
+	APIURL string
+
+	// Repository is the repository to open pull requests in, as owner/name.
+	Repository string
+
+	// Token authenticates requests.
+	Token string
+
+	// HTTPClient is the HTTP client used for requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// DryRun prints the pull request to Out instead of opening it.
+	DryRun bool
+
+	// Out receives dry-run output.
+	Out io.Writer
+}
+
+var _ GitHub = &GitHubClient{}
+
+// CreatePullRequest opens the pull request and returns its URL.
+func (c *GitHubClient) CreatePullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/pulls", strings.TrimSuffix(c.apiURL(), "/"), c.Repository)
+
+	if c.DryRun {
+		fmt.Fprintf(c.Out, "[DRY RUN] POST %s\n", url)
+		fmt.Fprintf(c.Out, "[DRY RUN]   base: %s\n", pr.Base)
+		fmt.Fprintf(c.Out, "[DRY RUN]   head: %s\n", pr.Head)
+		fmt.Fprintf(c.Out, "[DRY RUN]   title: %s\n", pr.Title)
+		fmt.Fprintf(c.Out, "[DRY RUN]   body:\n")
+		for _, line := range strings.Split(pr.Body, "\n") {
+			fmt.Fprintf(c.Out, "[DRY RUN]     %s\n", line)
+		}
+		return "", nil
+	}
+
+	if c.Repository == "" {
+		return "", fmt.Errorf("no GitHub repository configured")
+	}
+
+	body, err := json.Marshal(pr)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req) //nolint:gosec // G704: URL is built from the configured GitHub API endpoint
+	if err != nil {
+		return "", fmt.Errorf("failed to create pull request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("failed to create pull request: status code %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("failed to decode pull request response: %w", err)
+	}
+
+	return created.HTMLURL, nil
+}
+
+func (c *GitHubClient) apiURL() string {
+	if c.APIURL == "" {
+		return DefaultGitHubAPIURL
+	}
+	return c.APIURL
+}
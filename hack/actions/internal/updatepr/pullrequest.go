@@ -0,0 +1,198 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updatepr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/bump"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds"
+)
+
+// chartUpdate is the outcome of the version bump and CRD synchronization of one chart.
+type chartUpdate struct {
+	Chart  string
+	Report *bump.Report
+	CRDs   *crds.Report
+}
+
+// changedCharts returns the updates of the charts whose versions were bumped,
+// or all updates if only other files such as CRDs changed.
+func changedCharts(updates []chartUpdate) []chartUpdate {
+	var changed []chartUpdate
+	for _, u := range updates {
+		if u.Report.Changed {
+			changed = append(changed, u)
+		}
+	}
+	if len(changed) == 0 {
+		return updates
+	}
+	return changed
+}
+
+// contourVersion returns the highest Contour version the changed charts are updated to.
+func contourVersion(updates []chartUpdate) string {
+	var highest *semver.Version
+	var version string
+	for _, u := range changedCharts(updates) {
+		v, err := semver.NewVersion(u.Report.AppVersion.New)
+		if err != nil {
+			if version == "" {
+				version = u.Report.AppVersion.New
+			}
+			continue
+		}
+		if highest == nil || v.GreaterThan(highest) {
+			highest, version = v, u.Report.AppVersion.New
+		}
+	}
+	return version
+}
+
+// branchName returns the name of the pull request branch for the update.
+func branchName(updates []chartUpdate) string {
+	return "github-actions/contour-" + contourVersion(updates)
+}
+
+// title returns the commit message and pull request title for the update.
+func title(updates []chartUpdate) string {
+	changed := changedCharts(updates)
+	names := make([]string, 0, len(changed))
+	for _, u := range changed {
+		names = append(names, u.Chart)
+	}
+
+	charts := "Helm chart"
+	if len(names) > 1 {
+		charts = "Helm charts"
+	}
+	return fmt.Sprintf("Update %s %s to Contour %s", strings.Join(names, ", "), charts, contourVersion(updates))
+}
+
+// body returns the pull request body for the update, with a table of old and new versions
+// and the CRD changes of each changed chart.
+func body(updates []chartUpdate) string {
+	var b strings.Builder
+	for i, u := range changedCharts(updates) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		chartBody(&b, u.Chart, u.Report)
+		crdsBody(&b, u.CRDs)
+	}
+	return b.String()
+}
+
+func chartBody(b *strings.Builder, chart string, report *bump.Report) {
+	fmt.Fprintf(b, "## %s\n\n", chart)
+	fmt.Fprintf(b, "This PR updates the %s Helm chart to Contour version %s and chart version %s.\n",
+		chart, report.AppVersion.New, report.ChartVersion.New)
+
+	b.WriteString("\n| | Old | New |\n|---|---|---|\n")
+	row := func(name string, c bump.Change) {
+		fmt.Fprintf(b, "| %s | %s | %s |\n", name, tableValue(c.Old), tableValue(c.New))
+	}
+	row("Chart version", report.ChartVersion)
+	row("appVersion", report.AppVersion)
+	if report.KubeVersion.Changed() {
+		row("kubeVersion", report.KubeVersion)
+	}
+	// Charts without a Contour or Envoy image have neither tag.
+	if report.ContourTag != (bump.Change{}) {
+		row("Contour image tag", report.ContourTag)
+	}
+	if report.ContourDigest.Changed() {
+		row("Contour image digest", report.ContourDigest)
+	}
+	if report.EnvoyTag != (bump.Change{}) {
+		row("Envoy image tag", report.EnvoyTag)
+	}
+	if report.EnvoyDigest.Changed() {
+		row("Envoy image digest", report.EnvoyDigest)
+	}
+
+	if len(report.KubernetesVersions) > 0 {
+		versions := make([]string, 0, len(report.KubernetesVersions))
+		for _, v := range report.KubernetesVersions {
+			versions = append(versions, tableValue(v))
+		}
+		fmt.Fprintf(b, "\nContour %s is tested against Kubernetes %s.\n", report.AppVersion.New, strings.Join(versions, ", "))
+	}
+
+	if len(report.Files) > 0 {
+		b.WriteString("\nFiles updated by the version bump:\n")
+		for _, f := range report.Files {
+			fmt.Fprintf(b, "- `%s`\n", f)
+		}
+	}
+}
+
+// crdsBody lists the semantic CRD changes of each set of CRDs, breaking ones first, since the CRD diffs are too
+// large to review.
+func crdsBody(b *strings.Builder, report *crds.Report) {
+	if report == nil || len(report.Changes) == 0 {
+		return
+	}
+
+	b.WriteString("\n### CRD changes\n\n")
+	if report.Breaking() {
+		b.WriteString("> [!WARNING]\n> This update contains breaking CRD changes.\n\n")
+	}
+	// The changes are grouped by set, which are only titled when there are several of them.
+	changes := report.Changes
+	titled := changes[0].Set != changes[len(changes)-1].Set
+	for len(changes) > 0 {
+		n := 1
+		for n < len(changes) && changes[n].Set == changes[0].Set {
+			n++
+		}
+		if titled {
+			fmt.Fprintf(b, "#### %s\n\n", changes[0].Set)
+		}
+		crdChanges(b, changes[:n])
+		if titled && n < len(changes) {
+			b.WriteString("\n")
+		}
+		changes = changes[n:]
+	}
+}
+
+func crdChanges(b *strings.Builder, changes []crds.Change) {
+	for _, breaking := range []bool{true, false} {
+		for _, c := range changes {
+			if c.Breaking != breaking {
+				continue
+			}
+			prefix := ""
+			if c.Breaking {
+				prefix = "**Breaking:** "
+			}
+			name := "`" + c.CRD + "`"
+			if c.Version != "" {
+				name += " `" + c.Version + "`"
+			}
+			fmt.Fprintf(b, "- %s%s: %s\n", prefix, name, c.Description)
+		}
+	}
+}
+
+func tableValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return "`" + s + "`"
+}
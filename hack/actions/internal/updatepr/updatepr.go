@@ -0,0 +1,127 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package updatepr implements the update-and-create-pr tool, which bumps the charts,
+// synchronizes their CRDs and opens a pull request with the result.
+package updatepr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/bump"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/charts"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.StandardLogger()
+
+// Options configures an update.
+type Options struct {
+	// Charts are the charts to update.
+	Charts []*charts.Chart
+
+	// Bump configures the version bump. The paths and images are filled in for each chart.
+	Bump bump.Options
+
+	// CRDs configures the CRD synchronization. The paths and CRD files are filled in for each chart.
+	CRDs crds.Options
+
+	// Git is the repository the update is committed to.
+	Git Git
+
+	// GitHub opens the pull request.
+	GitHub GitHub
+
+	// Base is the branch the pull request is opened against.
+	Base string
+}
+
+// Run bumps the versions of all charts, synchronizes their CRDs and, if anything changed,
+// commits the result to a new branch and opens a pull request for it.
+func Run(ctx context.Context, opts Options) error {
+	updates := make([]chartUpdate, 0, len(opts.Charts))
+	for _, c := range opts.Charts {
+		log.Infof("Updating Helm chart %s versions", c.Name)
+		report, err := bump.Run(ctx, c.Bump(opts.Bump))
+		if err != nil {
+			return fmt.Errorf("failed to bump %s chart versions: %w", c.Name, err)
+		}
+
+		log.Infof("Synchronizing Helm chart %s CRDs", c.Name)
+		crdReport, err := crds.Run(ctx, c.CRDs(opts.CRDs))
+		if err != nil {
+			return fmt.Errorf("failed to synchronize %s chart CRDs: %w", c.Name, err)
+		}
+		updates = append(updates, chartUpdate{Chart: c.Name, Report: report, CRDs: crdReport})
+	}
+
+	changed, err := opts.Git.HasChanges(ctx)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		log.Infof("No update needed")
+		return nil
+	}
+
+	branch := branchName(updates)
+	exists, err := opts.Git.RemoteBranchExists(ctx, branch)
+	if err != nil {
+		return err
+	}
+	if exists {
+		log.Infof("Pull request branch for %s already exists on remote, skipping update", branch)
+		return nil
+	}
+
+	log.Infof("Creating branch %s", branch)
+	if err := opts.Git.CreateBranch(ctx, branch); err != nil {
+		return err
+	}
+
+	// Show the changed files for logging purposes.
+	status, err := opts.Git.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimRight(status, "\n"), "\n") {
+		log.Infof("  %s", line)
+	}
+
+	log.Infof("Committing and pushing changes")
+	if err := opts.Git.CommitAll(ctx, title(updates)); err != nil {
+		return err
+	}
+	if err := opts.Git.Push(ctx, branch); err != nil {
+		return err
+	}
+
+	log.Infof("Creating pull request")
+	url, err := opts.GitHub.CreatePullRequest(ctx, PullRequest{
+		Title: title(updates),
+		Body:  body(updates),
+		Head:  branch,
+		Base:  opts.Base,
+	})
+	if err != nil {
+		return err
+	}
+	if url != "" {
+		log.Infof("Created pull request %s", url)
+	}
+
+	return nil
+}
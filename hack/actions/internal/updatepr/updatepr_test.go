@@ -0,0 +1,346 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updatepr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/bump"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/charts"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds/crdstest"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/fetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testVersions = `versions:
+- version: v1.33.10
+  supported: "true"
+  dependencies:
+    envoy: 1.35.9
+- version: v1.33.6
+  supported: "true"
+  dependencies:
+    envoy: 1.35.8
+`
+
+const testConfig = `component: contour
+images:
+  contour: contour.image
+  envoy: envoy.image
+crds:
+  - name: Contour
+    source: examples/contour/01-crds.yaml
+    destination: templates/crds/contour-crds.yaml
+    conditional: .Values.contour.manageCRDs
+  - name: Gateway API
+    source: examples/gateway/00-crds.yaml
+    destination: templates/crds/gateway-api-crds.yaml
+    conditional: .Values.gatewayAPI.manageCRDs
+`
+
+// fakeGitHub records the pull requests opened through the GitHub REST API.
+type fakeGitHub struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	pulls   []PullRequest
+	headers []http.Header
+}
+
+func newFakeGitHub(t *testing.T) *fakeGitHub {
+	t.Helper()
+
+	gh := &fakeGitHub{}
+	gh.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/projectcontour/helm-charts/pulls" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var pr PullRequest
+		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		gh.mu.Lock()
+		gh.pulls = append(gh.pulls, pr)
+		gh.headers = append(gh.headers, r.Header.Clone())
+		gh.mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"html_url":"https://github.com/projectcontour/helm-charts/pull/1"}`))
+	}))
+	t.Cleanup(gh.Close)
+
+	return gh
+}
+
+// testRepo is a working tree cloned from a local bare repository standing in for GitHub.
+type testRepo struct {
+	origin string
+	work   string
+}
+
+func newTestRepo(t *testing.T, appVersion string) *testRepo {
+	t.Helper()
+
+	dir := t.TempDir()
+	r := &testRepo{origin: filepath.Join(dir, "origin.git"), work: filepath.Join(dir, "work")}
+
+	runGit(t, dir, "-c", "init.defaultBranch=main", "init", "--quiet", "--bare", r.origin)
+	runGit(t, dir, "-c", "init.defaultBranch=main", "init", "--quiet", r.work)
+	runGit(t, r.work, "config", "user.name", "github-actions[bot]")
+	runGit(t, r.work, "config", "user.email", "github-actions[bot]@users.noreply.github.com")
+	runGit(t, r.work, "config", "commit.gpgsign", "false")
+	runGit(t, r.work, "remote", "add", "origin", r.origin)
+
+	chartDir := filepath.Join(r.work, "charts", "contour")
+	require.NoError(t, os.MkdirAll(filepath.Join(chartDir, "templates", "crds"), 0o755))
+	files := map[string]string{
+		"Chart.yaml":                           "annotations:\n  category: Infrastructure\napiVersion: v2\nappVersion: " + appVersion + "\nversion: 0.7.0\n",
+		"values.yaml":                          "contour:\n  image:\n    registry: ghcr.io\n    repository: projectcontour/contour\n    tag: v" + appVersion + "\n    digest: \"\"\nenvoy:\n  image:\n    registry: docker.io\n    repository: envoyproxy/envoy\n    tag: v1.35.8\n    digest: \"\"\n",
+		"CHANGELOG.md":                         "# Changelog\n",
+		"upstream.yaml":                        testConfig,
+		"templates/crds/contour-crds.yaml":     "",
+		"templates/crds/gateway-api-crds.yaml": "",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(chartDir, name), []byte(content), 0o600))
+	}
+
+	runGit(t, r.work, "add", "--all")
+	runGit(t, r.work, "commit", "--quiet", "--message", "initial")
+	runGit(t, r.work, "push", "--quiet", "origin", "main")
+
+	return r
+}
+
+// addCRDsChart commits a chart that tracks only the Contour CRDs, without images or changelog.
+func (r *testRepo) addCRDsChart(t *testing.T, name, appVersion string) {
+	t.Helper()
+
+	chartDir := filepath.Join(r.work, "charts", name)
+	require.NoError(t, os.MkdirAll(filepath.Join(chartDir, "templates"), 0o755))
+	files := map[string]string{
+		"Chart.yaml":                  "annotations:\n  category: Infrastructure\napiVersion: v2\nappVersion: " + appVersion + "\nversion: 0.1.0\n",
+		"values.yaml":                 "{}\n",
+		"upstream.yaml":               "component: contour\ncrds:\n  - name: Contour\n    source: examples/contour/01-crds.yaml\n    destination: templates/contour-crds.yaml\n",
+		"templates/contour-crds.yaml": "",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(chartDir, name), []byte(content), 0o600))
+	}
+
+	runGit(t, r.work, "add", "--all")
+	runGit(t, r.work, "commit", "--quiet", "--message", "add "+name)
+	runGit(t, r.work, "push", "--quiet", "origin", "main")
+}
+
+func (r *testRepo) chartPath(name string) string {
+	return filepath.Join(r.work, "charts", "contour", name)
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return string(out)
+}
+
+// newOptions returns options for an update of repo, with all network access served by local fakes.
+func newOptions(t *testing.T, repo *testRepo, gh *fakeGitHub) Options {
+	t.Helper()
+
+	versions := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(testVersions))
+	}))
+	t.Cleanup(versions.Close)
+
+	crdFiles := map[string]string{
+		"examples/contour/01-crds.yaml": "---\nkind: CustomResourceDefinition\nmetadata:\n  name: httpproxies.projectcontour.io\n",
+		"examples/gateway/00-crds.yaml": "---\nkind: CustomResourceDefinition\nmetadata:\n  name: gateways.gateway.networking.k8s.io\n",
+	}
+	source := crdstest.NewServer(t, map[string][]byte{
+		"1.33.6":  crdstest.Tarball(t, "1.33.6", crdFiles),
+		"1.33.10": crdstest.Tarball(t, "1.33.10", crdFiles),
+	})
+
+	discovered, err := charts.Discover(filepath.Join(repo.work, "charts"))
+	require.NoError(t, err)
+
+	return Options{
+		Charts: discovered,
+		Bump: bump.Options{
+			Source: &bump.URLSource{URL: versions.URL, Fetcher: &fetch.Fetcher{Client: versions.Client()}},
+		},
+		CRDs: crds.Options{
+			Source: &crds.ArchiveSource{URL: source.SourceURL()},
+		},
+		Git: &ExecGit{Dir: repo.work},
+		GitHub: &GitHubClient{
+			APIURL:     gh.URL,
+			Repository: "projectcontour/helm-charts",
+			Token:      "test-token",
+			HTTPClient: gh.Client(),
+		},
+		Base: "main",
+	}
+}
+
+func TestRun(t *testing.T) {
+	repo := newTestRepo(t, "1.33.6")
+	gh := newFakeGitHub(t)
+
+	require.NoError(t, Run(context.Background(), newOptions(t, repo, gh)))
+
+	// The branch was pushed with a signed-off commit of all changes.
+	assert.NotEmpty(t, runGit(t, repo.work, "ls-remote", "origin", "refs/heads/github-actions/contour-1.33.10"))
+	message := runGit(t, repo.origin, "log", "-1", "--format=%B", "github-actions/contour-1.33.10")
+	assert.Equal(t, "Update contour Helm chart to Contour 1.33.10\n\nSigned-off-by: github-actions[bot] <github-actions[bot]@users.noreply.github.com>\n\n", message)
+	files := runGit(t, repo.origin, "diff", "--name-only", "main", "github-actions/contour-1.33.10")
+	assert.Equal(t, "charts/contour/CHANGELOG.md\n"+
+		"charts/contour/Chart.yaml\n"+
+		"charts/contour/templates/crds/contour-crds.yaml\n"+
+		"charts/contour/templates/crds/gateway-api-crds.yaml\n"+
+		"charts/contour/values.yaml\n", files)
+
+	// The pull request was opened against the base branch.
+	require.Len(t, gh.pulls, 1)
+	assert.Equal(t, "Bearer test-token", gh.headers[0].Get("Authorization"))
+	assert.Equal(t, "Update contour Helm chart to Contour 1.33.10", gh.pulls[0].Title)
+	assert.Equal(t, "github-actions/contour-1.33.10", gh.pulls[0].Head)
+	assert.Equal(t, "main", gh.pulls[0].Base)
+	assert.Contains(t, gh.pulls[0].Body, "| appVersion | `1.33.6` | `1.33.10` |")
+	assert.Contains(t, gh.pulls[0].Body, "\n### CRD changes\n\n"+
+		"#### Contour\n\n"+
+		"- `httpproxies.projectcontour.io`: added CRD\n\n"+
+		"#### Gateway API\n\n"+
+		"- `gateways.gateway.networking.k8s.io`: added CRD\n")
+}
+
+func TestRunMultipleCharts(t *testing.T) {
+	repo := newTestRepo(t, "1.33.6")
+	repo.addCRDsChart(t, "contour-crds", "1.33.6")
+	gh := newFakeGitHub(t)
+
+	require.NoError(t, Run(context.Background(), newOptions(t, repo, gh)))
+
+	files := runGit(t, repo.origin, "diff", "--name-only", "main", "github-actions/contour-1.33.10")
+	assert.Equal(t, "charts/contour-crds/Chart.yaml\n"+
+		"charts/contour-crds/templates/contour-crds.yaml\n"+
+		"charts/contour/CHANGELOG.md\n"+
+		"charts/contour/Chart.yaml\n"+
+		"charts/contour/templates/crds/contour-crds.yaml\n"+
+		"charts/contour/templates/crds/gateway-api-crds.yaml\n"+
+		"charts/contour/values.yaml\n", files)
+
+	require.Len(t, gh.pulls, 1)
+	assert.Equal(t, "Update contour, contour-crds Helm charts to Contour 1.33.10", gh.pulls[0].Title)
+
+	contour, crdsChart, found := strings.Cut(gh.pulls[0].Body, "## contour-crds\n")
+	require.True(t, found)
+	assert.Contains(t, contour, "## contour\n")
+	assert.Contains(t, contour, "| Envoy image tag | `v1.35.8` | `v1.35.9` |")
+	assert.Contains(t, crdsChart, "| Chart version | `0.1.0` | `0.1.1` |")
+	assert.NotContains(t, crdsChart, "image tag")
+}
+
+func TestRunExistingBranch(t *testing.T) {
+	repo := newTestRepo(t, "1.33.6")
+	runGit(t, repo.work, "push", "--quiet", "origin", "main:github-actions/contour-1.33.10")
+	gh := newFakeGitHub(t)
+
+	require.NoError(t, Run(context.Background(), newOptions(t, repo, gh)))
+
+	assert.Equal(t, "main\n", runGit(t, repo.work, "branch", "--show-current"))
+	assert.Empty(t, gh.pulls)
+}
+
+func TestRunNoChanges(t *testing.T) {
+	repo := newTestRepo(t, "1.33.10")
+	gh := newFakeGitHub(t)
+
+	// Sync the CRDs once so that the working tree matches the upstream release.
+	opts := newOptions(t, repo, gh)
+	_, err := crds.Run(context.Background(), opts.Charts[0].CRDs(opts.CRDs))
+	require.NoError(t, err)
+	runGit(t, repo.work, "commit", "--quiet", "--all", "--message", "sync CRDs")
+	runGit(t, repo.work, "push", "--quiet", "origin", "main")
+
+	require.NoError(t, Run(context.Background(), opts))
+
+	assert.Equal(t, "main\n", runGit(t, repo.work, "branch", "--show-current"))
+	assert.Empty(t, gh.pulls)
+}
+
+func TestRunDryRun(t *testing.T) {
+	repo := newTestRepo(t, "1.33.6")
+	gh := newFakeGitHub(t)
+
+	var out bytes.Buffer
+	opts := newOptions(t, repo, gh)
+	opts.Git.(*ExecGit).DryRun = true
+	opts.Git.(*ExecGit).Out = &out
+	opts.GitHub.(*GitHubClient).DryRun = true
+	opts.GitHub.(*GitHubClient).Out = &out
+
+	require.NoError(t, Run(context.Background(), opts))
+
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, []string{
+		"[DRY RUN] git checkout -b github-actions/contour-1.33.10",
+		"[DRY RUN] git add --all",
+		"[DRY RUN] git commit --signoff --message 'Update contour Helm chart to Contour 1.33.10'",
+		"[DRY RUN] git push origin github-actions/contour-1.33.10",
+		"[DRY RUN] POST " + gh.URL + "/repos/projectcontour/helm-charts/pulls",
+		"[DRY RUN]   base: main",
+		"[DRY RUN]   head: github-actions/contour-1.33.10",
+		"[DRY RUN]   title: Update contour Helm chart to Contour 1.33.10",
+		"[DRY RUN]   body:",
+	}, lines[:9])
+
+	assert.Equal(t, "main\n", runGit(t, repo.work, "branch", "--show-current"))
+	assert.Empty(t, runGit(t, repo.work, "ls-remote", "origin", "refs/heads/github-actions/contour-1.33.10"))
+	assert.Empty(t, gh.pulls)
+}
+
+func TestBodyBreakingCRDChanges(t *testing.T) {
+	report := &bump.Report{AppVersion: bump.Change{Old: "1.33.6", New: "1.34.0"}, ChartVersion: bump.Change{Old: "0.7.0", New: "0.8.0"}}
+	crdReport := &crds.Report{Changes: []crds.Change{
+		{CRD: "extensionservices.projectcontour.io", Description: "added CRD"},
+		{CRD: "httpproxies.projectcontour.io", Version: "v1", Description: "removed field spec.virtualhost.tls", Breaking: true},
+	}}
+
+	_, crdsBody, found := strings.Cut(body([]chartUpdate{{Chart: "contour", Report: report, CRDs: crdReport}}), "### CRD changes\n")
+	require.True(t, found)
+	assert.Equal(t, "\n> [!WARNING]\n> This update contains breaking CRD changes.\n\n"+
+		"- **Breaking:** `httpproxies.projectcontour.io` `v1`: removed field spec.virtualhost.tls\n"+
+		"- `extensionservices.projectcontour.io`: added CRD\n", crdsBody)
+}
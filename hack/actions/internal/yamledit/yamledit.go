@@ -0,0 +1,442 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package yamledit makes surgical edits to YAML documents. Instead of re-encoding the
+// whole document, only the bytes of the edited scalar are rewritten, so comments,
+// quoting, indentation and flow-style collections elsewhere in the file are preserved.
+package yamledit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetFile sets the scalar at the dot-separated fieldPath in the YAML file to value.
+func SetFile(filePath, fieldPath, value string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	out, err := Set(data, fieldPath, value)
+	if err != nil {
+		return fmt.Errorf("failed to update field %s in %s: %w", fieldPath, filePath, err)
+	}
+
+	if err := os.WriteFile(filePath, out, 0o644); err != nil { //nolint:gosec // G306: chart files are intentionally world-readable
+		return fmt.Errorf("failed to write file %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// SetOrInsertFile sets the scalar at the dot-separated fieldPath in the YAML file to value,
+// adding the field if it does not exist yet. See SetOrInsert.
+func SetOrInsertFile(filePath, fieldPath, value string) error {
+	return SetOrInsertPathFile(filePath, strings.Split(fieldPath, "."), value)
+}
+
+// SetOrInsertPathFile is like SetOrInsertFile, but takes the field as a list of keys, for keys that contain dots.
+func SetOrInsertPathFile(filePath string, path []string, value string) error {
+	fieldPath := strings.Join(path, ".")
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	out, err := SetOrInsertPath(data, path, value)
+	if err != nil {
+		return fmt.Errorf("failed to update field %s in %s: %w", fieldPath, filePath, err)
+	}
+
+	if err := os.WriteFile(filePath, out, 0o644); err != nil { //nolint:gosec // G306: chart files are intentionally world-readable
+		return fmt.Errorf("failed to write file %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// GetFile returns the scalar at the dot-separated fieldPath in the YAML file.
+func GetFile(filePath, fieldPath string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	value, err := Get(data, fieldPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read field %s in %s: %w", fieldPath, filePath, err)
+	}
+
+	return value, nil
+}
+
+// Get returns the scalar at the dot-separated fieldPath in data.
+func Get(data []byte, fieldPath string) (string, error) {
+	node, err := root(data)
+	if err != nil {
+		return "", err
+	}
+
+	_, _, target, err := lookup(node, strings.Split(fieldPath, "."))
+	if err != nil {
+		return "", err
+	}
+	if target.Kind != yaml.ScalarNode {
+		return "", fmt.Errorf("field %s is not a scalar", fieldPath)
+	}
+
+	return target.Value, nil
+}
+
+// Set returns a copy of data with the scalar at the dot-separated fieldPath set to value.
+// The scalar keeps its original quoting style where value can be represented in it.
+// Multi-line values in block mappings are written as literal block scalars.
+func Set(data []byte, fieldPath, value string) ([]byte, error) {
+	return SetPath(data, strings.Split(fieldPath, "."), value)
+}
+
+// SetPath is like Set, but takes the field as a list of keys, for keys that contain dots.
+func SetPath(data []byte, path []string, value string) ([]byte, error) {
+	fieldPath := strings.Join(path, ".")
+
+	node, err := root(data)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, key, target, err := lookup(node, path)
+	if err != nil {
+		return nil, err
+	}
+	if target.Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("field %s is not a scalar", fieldPath)
+	}
+
+	// Block scalars are indented relative to their key, and cannot be used in flow mappings.
+	indent := ""
+	if parent.Style&yaml.FlowStyle == 0 {
+		indent = strings.Repeat(" ", key.Column+1)
+	}
+
+	// A key without a value (e.g. "tag:") has no bytes to replace, so the new
+	// value is inserted right after the colon that follows the key instead.
+	if target.Tag == "!!null" && target.Value == "" && target.Style == 0 {
+		start, end, err := scalarRange(data, key)
+		if err != nil {
+			return nil, err
+		}
+		colon := bytes.IndexByte(data[end:], ':')
+		if colon < 0 || strings.TrimSpace(string(data[end:end+colon])) != "" {
+			return nil, fmt.Errorf("failed to locate value of field %s after key at offset %d", fieldPath, start)
+		}
+		pos := end + colon + 1
+		return splice(data, pos, pos, " "+render(value, 0, indent)), nil
+	}
+
+	var start, end int
+	if target.Style == yaml.LiteralStyle {
+		start, end, err = blockRange(data, key, target)
+	} else {
+		start, end, err = scalarRange(data, target)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("field %s: %w", fieldPath, err)
+	}
+
+	return splice(data, start, end, render(value, target.Style, indent)), nil
+}
+
+// SetOrInsert is like Set, but adds the last element of fieldPath to its block mapping if it does not exist.
+// The new field is inserted before the first key that sorts after it, so that sorted mappings stay
+// sorted, or appended to the document if it belongs at the end of the top-level mapping.
+func SetOrInsert(data []byte, fieldPath, value string) ([]byte, error) {
+	return SetOrInsertPath(data, strings.Split(fieldPath, "."), value)
+}
+
+// SetOrInsertPath is like SetOrInsert, but takes the field as a list of keys, for keys that contain dots.
+func SetOrInsertPath(data []byte, path []string, value string) ([]byte, error) {
+	fieldPath := strings.Join(path, ".")
+
+	node, err := root(data)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := node
+	if len(path) > 1 {
+		if _, _, parent, err = lookup(node, path[:len(path)-1]); err != nil {
+			return nil, err
+		}
+	}
+	if parent.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected mapping node")
+	}
+
+	name := path[len(path)-1]
+	for i := 0; i < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == name {
+			return SetPath(data, path, value)
+		}
+	}
+
+	if parent.Style&yaml.FlowStyle != 0 || len(parent.Content) == 0 {
+		return nil, fmt.Errorf("cannot add field %s to a flow or empty mapping", fieldPath)
+	}
+
+	indent := strings.Repeat(" ", parent.Content[0].Column-1)
+	line := indent + render(name, 0, "") + ": " + render(value, 0, indent+"  ") + "\n"
+
+	for i := 0; i < len(parent.Content); i += 2 {
+		key := parent.Content[i]
+		if key.Value <= name {
+			continue
+		}
+		pos, err := offset(data, key.Line, 1)
+		if err != nil {
+			return nil, err
+		}
+		return splice(data, headComment(data, pos), headComment(data, pos), line), nil
+	}
+
+	// Appending after the last value of a nested mapping would need to know where that value ends.
+	if parent != node {
+		return nil, fmt.Errorf("cannot add field %s after the last key of a nested mapping", fieldPath)
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		line = "\n" + line
+	}
+	return splice(data, len(data), len(data), line), nil
+}
+
+// headComment returns the offset of the comment lines directly above the line starting at pos,
+// so that a key inserted there does not separate a key from its comment.
+func headComment(data []byte, pos int) int {
+	for pos > 0 {
+		start := bytes.LastIndexByte(data[:pos-1], '\n') + 1
+		if !strings.HasPrefix(strings.TrimSpace(string(data[start:pos-1])), "#") {
+			break
+		}
+		pos = start
+	}
+	return pos
+}
+
+// root parses data and returns the top-level node of the document.
+func root(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal yaml: %w", err)
+	}
+
+	node := &doc
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind == 0 || node.Kind == yaml.DocumentNode {
+		return nil, fmt.Errorf("empty document")
+	}
+
+	return node, nil
+}
+
+// lookup walks the mapping nodes along path and returns the mapping, key and value nodes of the last element.
+func lookup(node *yaml.Node, path []string) (*yaml.Node, *yaml.Node, *yaml.Node, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil, nil, fmt.Errorf("expected mapping node")
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == path[0] {
+			if len(path) == 1 {
+				return node, node.Content[i], node.Content[i+1], nil
+			}
+			return lookup(node.Content[i+1], path[1:])
+		}
+	}
+
+	return nil, nil, nil, fmt.Errorf("field %s not found", path[0])
+}
+
+// scalarRange returns the byte range of the single-line scalar node in data.
+func scalarRange(data []byte, node *yaml.Node) (int, int, error) {
+	start, err := offset(data, node.Line, node.Column)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Only look at the rest of the line: multi-line scalars are not supported.
+	line := data[start:]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+
+	switch node.Style {
+	case 0:
+		// A single-line plain scalar has no escapes, so its source text is the value itself.
+		if node.Value == "" || !bytes.HasPrefix(line, []byte(node.Value)) {
+			return 0, 0, fmt.Errorf("unsupported plain scalar at line %d", node.Line)
+		}
+		return start, start + len(node.Value), nil
+	case yaml.DoubleQuotedStyle:
+		for i := 1; i < len(line); i++ {
+			switch line[i] {
+			case '\\':
+				i++
+			case '"':
+				return start, start + i + 1, nil
+			}
+		}
+	case yaml.SingleQuotedStyle:
+		for i := 1; i < len(line); i++ {
+			if line[i] == '\'' {
+				if i+1 < len(line) && line[i+1] == '\'' {
+					i++
+					continue
+				}
+				return start, start + i + 1, nil
+			}
+		}
+	}
+
+	return 0, 0, fmt.Errorf("unsupported scalar style at line %d", node.Line)
+}
+
+// blockRange returns the byte range of the literal block scalar node of key in data,
+// from the block indicator to the end of its last non-empty line.
+func blockRange(data []byte, key, node *yaml.Node) (int, int, error) {
+	start, err := offset(data, node.Line, node.Column)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end := start + bytes.IndexByte(data[start:], '\n')
+	if end < start {
+		return start, len(data), nil
+	}
+
+	// The block continues as long as lines are empty or indented deeper than the key.
+	for pos := end + 1; pos < len(data); {
+		next := len(data)
+		if i := bytes.IndexByte(data[pos:], '\n'); i >= 0 {
+			next = pos + i
+		}
+		line := string(data[pos:next])
+		if strings.TrimSpace(line) != "" {
+			if len(line)-len(strings.TrimLeft(line, " ")) < key.Column {
+				break
+			}
+			end = next
+		}
+		pos = next + 1
+	}
+
+	return start, end, nil
+}
+
+// offset converts a 1-based line and character column reported by the YAML parser into a byte offset.
+func offset(data []byte, line, column int) (int, error) {
+	pos := 0
+	for l := 1; l < line; l++ {
+		i := bytes.IndexByte(data[pos:], '\n')
+		if i < 0 {
+			return 0, fmt.Errorf("line %d out of range", line)
+		}
+		pos += i + 1
+	}
+
+	for c := 1; c < column; c++ {
+		if pos >= len(data) || data[pos] == '\n' {
+			return 0, fmt.Errorf("column %d out of range on line %d", column, line)
+		}
+		_, size := utf8.DecodeRune(data[pos:])
+		pos += size
+	}
+
+	return pos, nil
+}
+
+// render formats value as a YAML scalar, keeping style where the value allows it.
+// Multi-line values are written as literal block scalars indented by indent, unless indent is empty.
+func render(value string, style yaml.Style, indent string) string {
+	if indent != "" && (style == yaml.LiteralStyle || strings.Contains(value, "\n")) {
+		if s, ok := literal(value, indent); ok {
+			return s
+		}
+	}
+
+	switch style {
+	case yaml.SingleQuotedStyle:
+		if !strings.ContainsAny(value, "\n") {
+			return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+		}
+	case yaml.DoubleQuotedStyle:
+		return quote(value)
+	}
+
+	// Keep plain style unless the value would then be read back as something
+	// other than the same string, such as a number, a boolean or a null.
+	out, err := yaml.Marshal(value)
+	if err == nil {
+		if s := strings.TrimSuffix(string(out), "\n"); s == value {
+			return s
+		}
+	}
+	return quote(value)
+}
+
+// literal returns value as a literal block scalar indented by indent. It returns false
+// for values that need an explicit indentation indicator or keep chomping.
+func literal(value, indent string) (string, bool) {
+	text, clip := strings.CutSuffix(value, "\n")
+	if strings.HasPrefix(value, " ") || strings.HasSuffix(text, "\n") || strings.Contains(value, "\r") {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteString("|")
+	if !clip {
+		b.WriteString("-")
+	}
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString("\n")
+		if line != "" {
+			b.WriteString(indent + line)
+		}
+	}
+	return b.String(), true
+}
+
+// quote returns value as a double-quoted YAML scalar.
+func quote(value string) string {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: yaml.DoubleQuotedStyle}
+	out, err := yaml.Marshal(n)
+	if err != nil {
+		return fmt.Sprintf("%q", value)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+// splice replaces data[start:end] with s.
+func splice(data []byte, start, end int, s string) []byte {
+	out := make([]byte, 0, len(data)-(end-start)+len(s))
+	out = append(out, data[:start]...)
+	out = append(out, s...)
+	return append(out, data[end:]...)
+}
@@ -0,0 +1,247 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yamledit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet(t *testing.T) {
+	tests := map[string]struct {
+		in            string
+		field         string
+		value         string
+		want          string
+		wantErrString string
+	}{
+		"plain scalar": {
+			in:    "version: 0.7.0\nappVersion: 1.33.6\n",
+			field: "appVersion",
+			value: "1.33.7",
+			want:  "version: 0.7.0\nappVersion: 1.33.7\n",
+		},
+		"trailing comment is kept": {
+			in:    "image:\n  tag: v1.33.6 # pinned\n",
+			field: "image.tag",
+			value: "v1.33.10",
+			want:  "image:\n  tag: v1.33.10 # pinned\n",
+		},
+		"double-quoted scalar": {
+			in:    "image:\n  digest: \"\"\n  pullPolicy: IfNotPresent\n",
+			field: "image.digest",
+			value: "sha256:abcd",
+			want:  "image:\n  digest: \"sha256:abcd\"\n  pullPolicy: IfNotPresent\n",
+		},
+		"double-quoted scalar with escapes": {
+			in:    "a: \"x\\\"y\" # c\n",
+			field: "a",
+			value: "z",
+			want:  "a: \"z\" # c\n",
+		},
+		"single-quoted scalar": {
+			in:    "a: 'it''s'\nb: c\n",
+			field: "a",
+			value: "don't",
+			want:  "a: 'don''t'\nb: c\n",
+		},
+		"plain value that would change type is quoted": {
+			in:    "version: v1\n",
+			field: "version",
+			value: "1.34",
+			want:  "version: \"1.34\"\n",
+		},
+		"empty value is inserted after the key": {
+			in:    "image:\n  tag:\n  digest: \"\"\n",
+			field: "image.tag",
+			value: "v1.0.0",
+			want:  "image:\n  tag: v1.0.0\n  digest: \"\"\n",
+		},
+		"comments, flow lists and unicode are untouched": {
+			in: "## @param café Some ünicode\n" +
+				"ünï: {a: b}\n" +
+				"securityContext:\n" +
+				"  capabilities:\n" +
+				"    drop: [\"ALL\"]\n" +
+				"ünïcode:\n" +
+				"  tâg: v1 # comment\n",
+			field: "ünïcode.tâg",
+			value: "v2",
+			want: "## @param café Some ünicode\n" +
+				"ünï: {a: b}\n" +
+				"securityContext:\n" +
+				"  capabilities:\n" +
+				"    drop: [\"ALL\"]\n" +
+				"ünïcode:\n" +
+				"  tâg: v2 # comment\n",
+		},
+		"missing field": {
+			in:            "a: b\n",
+			field:         "c",
+			wantErrString: "field c not found",
+		},
+		"non-scalar field": {
+			in:            "a:\n  b: c\n",
+			field:         "a",
+			wantErrString: "field a is not a scalar",
+		},
+		"literal block scalar": {
+			in:    "a: |\n  one\n\n  two\n\nb: 1\n",
+			field: "a",
+			value: "three\n",
+			want:  "a: |\n  three\n\nb: 1\n",
+		},
+		"literal block scalar at end of document": {
+			in:    "a:\n  b: |-\n    one\n    two",
+			field: "a.b",
+			value: "three\n\nfour",
+			want:  "a:\n  b: |-\n    three\n\n    four",
+		},
+		"multi-line value becomes a literal block scalar": {
+			in:    "annotations:\n  images: \"\"\n  name: contour\n",
+			field: "annotations.images",
+			value: "- name: contour\n  image: ghcr.io/projectcontour/contour:v1.33.10\n",
+			want:  "annotations:\n  images: |\n    - name: contour\n      image: ghcr.io/projectcontour/contour:v1.33.10\n  name: contour\n",
+		},
+		"multi-line value in flow mapping is quoted": {
+			in:    "a: {b: c}\n",
+			field: "a.b",
+			value: "one\ntwo",
+			want:  "a: {b: \"one\\ntwo\"}\n",
+		},
+		"folded block scalar is refused": {
+			in:            "a: >\n  b\n",
+			field:         "a",
+			wantErrString: "unsupported scalar style",
+		},
+		"empty document": {
+			in:            "",
+			field:         "a",
+			wantErrString: "empty document",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Set([]byte(tc.in), tc.field, tc.value)
+			if tc.wantErrString != "" {
+				require.ErrorContains(t, err, tc.wantErrString)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	const in = "envoy:\n  image:\n    tag: v1.35.8 # comment\n    digest: \"\"\n"
+
+	got, err := Get([]byte(in), "envoy.image.tag")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.35.8", got)
+
+	got, err = Get([]byte(in), "envoy.image.digest")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = Get([]byte(in), "envoy.image")
+	require.ErrorContains(t, err, "field envoy.image is not a scalar")
+
+	_, err = Get([]byte(in), "contour.image.tag")
+	require.ErrorContains(t, err, "field contour not found")
+}
+
+func TestSetOrInsert(t *testing.T) {
+	tests := map[string]struct {
+		in            string
+		field         string
+		path          []string
+		value         string
+		want          string
+		wantErrString string
+	}{
+		"existing field is set": {
+			in:    "kubeVersion: '>=1.28.0-0'\nname: contour\n",
+			field: "kubeVersion",
+			value: ">=1.29.0-0",
+			want:  "kubeVersion: '>=1.29.0-0'\nname: contour\n",
+		},
+		"inserted in sort order": {
+			in:    "appVersion: 1.33.6\nkeywords:\n  - ingress\nmaintainers:\n  - name: Contour Team\n",
+			field: "kubeVersion",
+			value: ">=1.28.0-0",
+			want:  "appVersion: 1.33.6\nkeywords:\n  - ingress\nkubeVersion: \">=1.28.0-0\"\nmaintainers:\n  - name: Contour Team\n",
+		},
+		"inserted above the comment of the next key": {
+			in:    "a: 1\n# about c\nc: 3\n",
+			field: "b",
+			value: "2",
+			want:  "a: 1\nb: \"2\"\n# about c\nc: 3\n",
+		},
+		"appended to the document": {
+			in:    "a: 1\nb: 2",
+			field: "c",
+			value: "three",
+			want:  "a: 1\nb: 2\nc: three\n",
+		},
+		"inserted in nested mapping": {
+			in:    "image:\n  repository: contour\n  tag: v1.33.6\n",
+			field: "image.digest",
+			value: "sha256:abc",
+			want:  "image:\n  digest: sha256:abc\n  repository: contour\n  tag: v1.33.6\n",
+		},
+		"key with dots and multi-line value": {
+			in:    "annotations:\n  category: Infrastructure\n",
+			path:  []string{"annotations", "artifacthub.io/images"},
+			value: "- name: contour\n",
+			want:  "annotations:\n  artifacthub.io/images: |\n    - name: contour\n  category: Infrastructure\n",
+		},
+		"nested mapping cannot be appended to": {
+			in:            "image:\n  tag: v1.33.6\nname: contour\n",
+			field:         "image.version",
+			wantErrString: "cannot add field image.version after the last key of a nested mapping",
+		},
+		"flow mapping is refused": {
+			in:            "image: {tag: v1.33.6}\n",
+			field:         "image.digest",
+			wantErrString: "cannot add field image.digest to a flow or empty mapping",
+		},
+		"missing parent": {
+			in:            "a: 1\n",
+			field:         "image.digest",
+			wantErrString: "field image not found",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := tc.path
+			if path == nil {
+				path = strings.Split(tc.field, ".")
+			}
+
+			got, err := SetOrInsertPath([]byte(tc.in), path, tc.value)
+			if tc.wantErrString != "" {
+				require.ErrorContains(t, err, tc.wantErrString)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(got))
+		})
+	}
+}
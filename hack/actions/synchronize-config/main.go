@@ -0,0 +1,103 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build none
+
+// This script generates the values schema and the commented reference of the Contour configuration the Helm charts
+// render from their values, such as configInline. For every chart under charts/ that maps a configuration file in its
+// upstream.yaml config file, it uses Chart.yaml appVersion to determine which Contour version to download, and reads
+// the Go type Contour decodes its configuration file into, such as Parameters in pkg/config, with the doc comments
+// of its fields.
+//
+// It writes the schema of the value into the chart values schema, such as values.schema.json, leaving its other
+// properties alone, so that helm install and helm upgrade reject unknown keys, such as a misspelled accesslog-fromat.
+// It also writes a reference listing every key, commented out, with its documentation. The example configuration
+// of the Contour source and the default value of the chart must only set known keys.
+//
+// Tarballs are downloaded, verified against -lockfile and cached like synchronize-crds does, and the configuration
+// can be taken from a branch or commit with -source-ref, from a local Contour checkout with -source-dir, or from a
+// local source tarball with -source-tarball instead.
+//
+// With -check, nothing is written. The tool prints a unified diff and exits non-zero if the generated files are
+// out of date.
+//
+// Usage:
+//
+//	go run hack/actions/synchronize-config/main.go [-charts DIR] [-only CHART,...] [-cache-dir DIR] [-lockfile FILE] [-frozen-lockfile]
+//		[-source-ref REF | -source-dir DIR | -source-tarball FILE] [-check]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"strings"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/charts"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/contourconfig"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/fetch"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.StandardLogger()
+
+func main() {
+	log.SetFormatter(&logrus.TextFormatter{ForceColors: true})
+
+	chartsDir := flag.String("charts", charts.DefaultDir, "Directory holding the charts.")
+	cacheDir := flag.String("cache-dir", fetch.DefaultCacheDir(), "Directory to cache Contour source tarballs in, empty to disable caching.")
+	lockfilePath := flag.String("lockfile", crds.DefaultLockfilePath, "Lockfile pinning the checksums of the Contour source tarballs, empty to skip verification.")
+	frozenLockfile := flag.Bool("frozen-lockfile", false, "Reject source tarballs that are not pinned in the lockfile instead of pinning them.")
+	only := flag.String("only", "", "Comma-separated names of the charts to synchronize, empty for all of them.")
+	sourceRef := flag.String("source-ref", "", "Contour branch or commit to take the configuration from instead of the appVersion release.")
+	sourceDir := flag.String("source-dir", "", "Local Contour checkout to take the configuration from instead of downloading it.")
+	sourceTarball := flag.String("source-tarball", "", "Local Contour source tarball to take the configuration from instead of downloading it.")
+	check := flag.Bool("check", false, "Print a diff and exit non-zero if the generated files are out of date, instead of writing them.")
+	flag.Parse()
+
+	source, err := crds.NewSource(*sourceRef, *sourceDir, *sourceTarball, crds.Download{
+		LockfilePath:     *lockfilePath,
+		FrozenLockfile:   *frozenLockfile,
+		ReadOnlyLockfile: *check,
+		Fetcher:          &fetch.Fetcher{CacheDir: *cacheDir},
+	})
+	if err != nil {
+		log.Fatalf("Failed to select the Contour source: %v", err)
+	}
+
+	discovered, err := charts.Discover(*chartsDir, charts.ParseNames(*only)...)
+	if err != nil {
+		log.Fatalf("Failed to discover charts: %v", err)
+	}
+
+	fetch.ConfigureDefaultClient()
+
+	var drifted []string
+	for _, c := range discovered {
+		log.Infof("Synchronizing chart %s configuration schema", c.Name)
+		err := contourconfig.Run(context.Background(), c.Configuration(contourconfig.Options{Source: source, Check: *check}))
+		switch {
+		case errors.Is(err, contourconfig.ErrDrift):
+			log.Errorf("Chart %s: %v", c.Name, err)
+			drifted = append(drifted, c.Name)
+		case err != nil:
+			log.Fatalf("%v", err)
+		}
+	}
+
+	if len(drifted) > 0 {
+		log.Fatalf("Configuration schemas of charts %s are out of date, run synchronize-config to update them.", strings.Join(drifted, ", "))
+	}
+	log.Infof("Configuration schemas are up to date.")
+}
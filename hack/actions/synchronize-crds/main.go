@@ -13,163 +13,93 @@
 
 //go:build none
 
-// This script synchronizes the CRDs in the Helm chart with the ones from the Contour source code.
-// It uses Chart.yaml appVersion to determine which Contour version to download.
+// This script synchronizes the CRDs in the Helm charts with the ones from the Contour source code.
+// For every chart under charts/ that has an upstream.yaml config file, it uses Chart.yaml appVersion to
+// determine which Contour version to download, copies the CRD files listed in the config file, and prints
+// a report of their semantic changes. Downloads are verified against the checksums pinned in -lockfile.
 //
 // Usage:
 //
-//	go run hack/actions/synchronize-crds/main.go
+//	go run hack/actions/synchronize-crds/main.go [-charts DIR] [-only CHART,...] [-cache-dir DIR] [-lockfile FILE] [-frozen-lockfile]
+//		[-source-ref REF | -source-dir DIR | -source-tarball FILE] [-gateway-api-dir DIR] [-check]
 package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"io/fs"
-	"net/http"
 	"os"
-	"path"
-	"time"
+	"strings"
 
-	"github.com/mholt/archives"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/charts"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/fetch"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
 )
 
-var (
-	log = logrus.StandardLogger()
-
-	contourSourceURL = "https://github.com/projectcontour/contour/archive/refs/tags/v%s.tar.gz"
-
-	chartPath = "./charts/contour/Chart.yaml"
-
-	contourCRDSourcePath = "examples/contour/01-crds.yaml"
-	contourCRDDestPath   = "./charts/contour/templates/crds/contour-crds.yaml"
-
-	gatewayCRDSourcePath = "examples/gateway/00-crds.yaml"
-	gatewayCRDDestPath   = "./charts/contour/templates/crds/gateway-api-crds.yaml"
-)
+var log = logrus.StandardLogger()
 
 func main() {
 	log.SetFormatter(&logrus.TextFormatter{ForceColors: true})
 
-	// Define global HTTP client timeout.
-	http.DefaultClient.Timeout = 2 * time.Minute
-
-	// Read current app version.
-	currentChartAppVersion, err := getCurrentChartAppVersion(chartPath)
-	if err != nil {
-		log.Fatalf("Failed to get current chart appVersion: %v", err)
-	}
-	log.Infof("Current chart appVersion: %s", currentChartAppVersion)
-
-	// Download source code for the current Contour version.
-	tmpDir, err := os.MkdirTemp("", "contour-source-")
-	if err != nil {
-		log.Fatalf("Failed to create temp dir: %v", err)
-	}
-
-	if err := syncCRDs(tmpDir, currentChartAppVersion); err != nil {
-		os.RemoveAll(tmpDir)
-		log.Fatalf("Failed to synchronize CRDs: %v", err)
-	}
-	os.RemoveAll(tmpDir)
-
-	log.Infof("Successfully synchronized CRDs.")
-}
-
-func syncCRDs(tmpDir, currentChartAppVersion string) error {
-	downloadPath := path.Join(tmpDir, "contour.tar.gz")
-	log.Infof("Downloading Contour source tarball for version %s to %s", currentChartAppVersion, downloadPath)
-	err := downloadFile(fmt.Sprintf(contourSourceURL, currentChartAppVersion), downloadPath)
-	if err != nil {
-		return fmt.Errorf("failed to download release: %w", err)
-	}
-
-	ctx := context.Background()
-	tar, err := archives.FileSystem(ctx, downloadPath, nil)
-	if err != nil {
-		return fmt.Errorf("failed to open archive %s: %w", downloadPath, err)
-	}
-
-	fullContourCRDSourcePath := fmt.Sprintf("contour-%s/%s", currentChartAppVersion, contourCRDSourcePath)
-	if err := copyCRD(tar, fullContourCRDSourcePath, contourCRDDestPath, ".Values.contour.manageCRDs"); err != nil {
-		return fmt.Errorf("failed to copy Contour CRDs: %w", err)
-	}
-	log.Infof("Wrote Contour CRDs to %s", contourCRDDestPath)
-
-	fullGatewayCRDSourcePath := fmt.Sprintf("contour-%s/%s", currentChartAppVersion, gatewayCRDSourcePath)
-	if err := copyCRD(tar, fullGatewayCRDSourcePath, gatewayCRDDestPath, ".Values.gatewayAPI.manageCRDs"); err != nil {
-		return fmt.Errorf("failed to copy Gateway API CRDs: %w", err)
-	}
-	log.Infof("Wrote Gateway API CRDs to %s", gatewayCRDDestPath)
-
-	return nil
-}
-
-// getCurrentChartAppVersion reads the current chart and app version.
-func getCurrentChartAppVersion(filePath string) (string, error) {
-	data, err := os.ReadFile(filePath)
+	chartsDir := flag.String("charts", charts.DefaultDir, "Directory holding the charts.")
+	cacheDir := flag.String("cache-dir", fetch.DefaultCacheDir(), "Directory to cache downloads in, revalidated on each run, empty to disable caching.")
+	lockfilePath := flag.String("lockfile", crds.DefaultLockfilePath, "Lockfile pinning the SHA-256 checksums of the downloads, empty to skip verification. New downloads are pinned in it.")
+	frozenLockfile := flag.Bool("frozen-lockfile", false, "Reject downloads that are not pinned in the lockfile instead of pinning them.")
+	only := flag.String("only", "", "Comma-separated names of the charts to synchronize, empty for all of them.")
+	sourceRef := flag.String("source-ref", "", "Contour branch or commit to take the CRDs from instead of the appVersion release, not verified against the lockfile.")
+	sourceDir := flag.String("source-dir", "", "Local Contour checkout to take the CRDs from instead of downloading them, trusted as is.")
+	sourceTarball := flag.String("source-tarball", "", "Local Contour source tarball to take the CRDs from instead of downloading them, trusted as is.")
+	gatewayAPIDir := flag.String("gateway-api-dir", "", "Local directory holding the Gateway API release assets, such as standard-install.yaml, instead of downloading them, trusted as is.")
+	check := flag.Bool("check", false, "Print a diff and exit non-zero if the chart files are out of date, instead of writing them. Downloads not pinned in the lockfile are accepted with a warning.")
+	flag.Parse()
+
+	download := crds.Download{
+		LockfilePath:     *lockfilePath,
+		FrozenLockfile:   *frozenLockfile,
+		ReadOnlyLockfile: *check,
+		Fetcher:          &fetch.Fetcher{CacheDir: *cacheDir},
+	}
+
+	source, err := crds.NewSource(*sourceRef, *sourceDir, *sourceTarball, download)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file %s: %w", filePath, err)
+		log.Fatalf("Failed to select the Contour source: %v", err)
 	}
+	gatewayAPI := &crds.GatewayAPIRelease{Dir: *gatewayAPIDir, Download: download}
 
-	type ChartYaml struct {
-		AppVersion string `yaml:"appVersion"`
-	}
-
-	var chart ChartYaml
-	if err := yaml.Unmarshal(data, &chart); err != nil {
-		return "", fmt.Errorf("failed to unmarshal yaml from %s: %w", filePath, err)
-	}
-
-	return chart.AppVersion, nil
-}
-
-// downloadFile downloads a file from the given URL to the specified destination path.
-func downloadFile(sourceURL, destPath string) error {
-	resp, err := http.Get(sourceURL) //nolint:gosec // G107: URL is constructed from a hardcoded constant
+	discovered, err := charts.Discover(*chartsDir, charts.ParseNames(*only)...)
 	if err != nil {
-		return fmt.Errorf("failed to download %s: %w", sourceURL, err)
+		log.Fatalf("Failed to discover charts: %v", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download %s: status code %d", sourceURL, resp.StatusCode)
-	}
+	fetch.ConfigureDefaultClient()
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
+	var drifted []string
+	for _, c := range discovered {
+		log.Infof("Synchronizing chart %s CRDs", c.Name)
+		report, err := crds.Run(context.Background(), c.CRDs(crds.Options{Source: source, GatewayAPI: gatewayAPI, Check: *check}))
+		if report != nil {
+			fmt.Printf("Chart %s CRD changes:\n", c.Name)
+			if err := report.WriteText(os.Stdout); err != nil {
+				log.Fatalf("Failed to write report: %v", err)
+			}
+		}
+		switch {
+		case errors.Is(err, crds.ErrDrift):
+			log.Errorf("Chart %s: %v", c.Name, err)
+			drifted = append(drifted, c.Name)
+		case err != nil:
+			log.Fatalf("%v", err)
+		}
 	}
 
-	if err := os.WriteFile(destPath, data, 0o644); err != nil { //nolint:gosec // G306: chart files are intentionally world-readable
-		return fmt.Errorf("failed to write file %s: %w", destPath, err)
+	if len(drifted) > 0 {
+		log.Fatalf("CRDs of charts %s are out of date, run synchronize-crds to update them.", strings.Join(drifted, ", "))
 	}
-
-	return nil
-}
-
-func copyCRD(fsys fs.FS, srcPath, destPath, conditional string) error {
-	f, err := fsys.Open(srcPath)
-	if err != nil {
-		return fmt.Errorf("failed to open source file %s: %w", srcPath, err)
-	}
-	defer f.Close()
-
-	data, err := io.ReadAll(f)
-	if err != nil {
-		return fmt.Errorf("failed to read source file %s: %w", srcPath, err)
+	if *check {
+		log.Infof("CRDs are up to date.")
+		return
 	}
-
-	if err := os.WriteFile(destPath, injectConditional(conditional, data), 0o644); err != nil { //nolint:gosec // G306: chart files are intentionally world-readable
-		return fmt.Errorf("failed to write destination file %s: %w", destPath, err)
-	}
-
-	return nil
-}
-
-// injectConditional wraps the given data with Helm conditional statement.
-func injectConditional(condition string, data []byte) []byte {
-	return []byte(fmt.Sprintf("# Conditional: %s\n{{- if %s }}\n%s{{- end }}\n", condition, condition, string(data)))
+	log.Infof("Successfully synchronized CRDs.")
 }
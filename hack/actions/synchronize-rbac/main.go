@@ -0,0 +1,120 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build none
+
+// This script keeps the RBAC rules of the Helm charts in sync with the role manifests of the Contour source code.
+// For every chart under charts/ that lists role manifests in its upstream.yaml config file, it uses Chart.yaml
+// appVersion to determine which Contour version to download, reads the ClusterRole and Role of each manifest,
+// such as examples/contour/02-role-contour.yaml, and rewrites the managed blocks of rules of the chart template
+// between the "# BEGIN ClusterRole rules managed by synchronize-rbac" and "# END ..." comments.
+//
+// It then renders the template with the chart's default values and reports, role by role, the rules granted
+// upstream that the chart lacks, and the rules the chart grants on top of upstream. Missing rules fail the run,
+// since Contour cannot watch the resources it lacks permissions for. Extra rules are only reported, as the chart
+// may grant them on purpose.
+//
+// Tarballs are downloaded, verified against -lockfile and cached like synchronize-crds does, and the rules can
+// be taken from a branch or commit with -source-ref, from a local Contour checkout with -source-dir, or from a
+// local source tarball with -source-tarball instead.
+//
+// With -check, nothing is written. The tool prints a unified diff and exits non-zero if managed rules are out
+// of date, and compares the rules of the committed templates.
+//
+// Usage:
+//
+//	go run hack/actions/synchronize-rbac/main.go [-charts DIR] [-only CHART,...] [-cache-dir DIR] [-lockfile FILE] [-frozen-lockfile]
+//		[-source-ref REF | -source-dir DIR | -source-tarball FILE] [-check]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/charts"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/fetch"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/rbac"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.StandardLogger()
+
+func main() {
+	log.SetFormatter(&logrus.TextFormatter{ForceColors: true})
+
+	chartsDir := flag.String("charts", charts.DefaultDir, "Directory holding the charts.")
+	cacheDir := flag.String("cache-dir", fetch.DefaultCacheDir(), "Directory to cache Contour source tarballs in, empty to disable caching.")
+	lockfilePath := flag.String("lockfile", crds.DefaultLockfilePath, "Lockfile pinning the checksums of the Contour source tarballs, empty to skip verification.")
+	frozenLockfile := flag.Bool("frozen-lockfile", false, "Reject source tarballs that are not pinned in the lockfile instead of pinning them.")
+	only := flag.String("only", "", "Comma-separated names of the charts to synchronize, empty for all of them.")
+	sourceRef := flag.String("source-ref", "", "Contour branch or commit to take the roles from instead of the appVersion release.")
+	sourceDir := flag.String("source-dir", "", "Local Contour checkout to take the roles from instead of downloading them.")
+	sourceTarball := flag.String("source-tarball", "", "Local Contour source tarball to take the roles from instead of downloading them.")
+	check := flag.Bool("check", false, "Print a diff and exit non-zero if the managed rules are out of date, instead of writing them.")
+	flag.Parse()
+
+	source, err := crds.NewSource(*sourceRef, *sourceDir, *sourceTarball, crds.Download{
+		LockfilePath:     *lockfilePath,
+		FrozenLockfile:   *frozenLockfile,
+		ReadOnlyLockfile: *check,
+		Fetcher:          &fetch.Fetcher{CacheDir: *cacheDir},
+	})
+	if err != nil {
+		log.Fatalf("Failed to select the Contour source: %v", err)
+	}
+
+	discovered, err := charts.Discover(*chartsDir, charts.ParseNames(*only)...)
+	if err != nil {
+		log.Fatalf("Failed to discover charts: %v", err)
+	}
+
+	fetch.ConfigureDefaultClient()
+
+	var drifted, missing []string
+	for _, c := range discovered {
+		log.Infof("Synchronizing chart %s RBAC rules", c.Name)
+		report, err := rbac.Run(context.Background(), c.RBAC(rbac.Options{Source: source, Check: *check}))
+		if report != nil {
+			fmt.Printf("Chart %s RBAC differences:\n", c.Name)
+			if err := report.WriteText(os.Stdout); err != nil {
+				log.Fatalf("Failed to write report: %v", err)
+			}
+			if report.Missing() {
+				missing = append(missing, c.Name)
+			}
+		}
+		switch {
+		case errors.Is(err, rbac.ErrDrift):
+			log.Errorf("Chart %s: %v", c.Name, err)
+			drifted = append(drifted, c.Name)
+		case err != nil:
+			log.Fatalf("%v", err)
+		}
+	}
+
+	if len(drifted) > 0 {
+		log.Errorf("RBAC rules of charts %s are out of date, run synchronize-rbac to update them.", strings.Join(drifted, ", "))
+	}
+	if len(missing) > 0 {
+		log.Errorf("Charts %s lack RBAC rules granted upstream.", strings.Join(missing, ", "))
+	}
+	if len(drifted) > 0 || len(missing) > 0 {
+		os.Exit(1)
+	}
+	log.Infof("RBAC rules are in sync.")
+}
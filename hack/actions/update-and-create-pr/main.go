@@ -0,0 +1,131 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build none
+
+// This script bumps every chart under charts/ that has an upstream.yaml config file to the latest stable
+// Contour release and synchronizes its CRDs, both in-process, and opens a single pull request with the result.
+// The config file names the upstream component the chart tracks, the values.yaml paths of its Contour and
+// Envoy images, and the CRD files copied from the Contour source. Use -only to update some of the charts.
+//
+// - If nothing changed, no pull request is opened.
+// - If the pull request branch github-actions/contour-<appVersion> already exists on the remote, no pull request is opened.
+// - Otherwise the changes are committed with a signoff to the new branch, which is pushed to the remote,
+// and a pull request is opened against -base.
+//
+// The new Contour and Envoy image tags must be published for all of -platforms, linux/amd64 and linux/arm64
+// by default, or the script fails before changing any file.
+//
+// It runs in dry-run mode by default, printing the git commands and the pull request it would create
+// instead of running them. Use -real-run to commit, push and open the pull request.
+//
+// The pull request is opened in -repository, which defaults to $GITHUB_REPOSITORY, using the token in $GITHUB_TOKEN.
+// Downloads are retried on transient failures and authenticated to GitHub with the same token. Contour source
+// tarballs and Gateway API release bundles are cached in -cache-dir, and verified against the checksums pinned in
+// -lockfile. The checksum of a new file is pinned in the lockfile as part of the pull request.
+//
+// Usage:
+//
+//	go run hack/actions/update-and-create-pr/main.go [-real-run] [-base BRANCH] [-charts DIR] [-only CHART,...] [-contour-minor MAJOR.MINOR|current] [-contour-version VERSION] [-channel stable|rc] [-platforms OS/ARCH,...] [-pin-digests] [-cache-dir DIR] [-lockfile FILE] [-versions URL|FILE|-]
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"os"
+
+	"github.com/projectcontour/helm-charts/hack/actions/internal/bump"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/charts"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/crds"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/fetch"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/registry"
+	"github.com/projectcontour/helm-charts/hack/actions/internal/updatepr"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.StandardLogger()
+
+func main() {
+	log.SetFormatter(&logrus.TextFormatter{ForceColors: true})
+
+	realRun := flag.Bool("real-run", false, "Commit, push and open the pull request instead of printing what would be done.")
+	base := flag.String("base", "main", "Branch to open the pull request against, for example a release branch of a maintenance release line.")
+	chartsDir := flag.String("charts", charts.DefaultDir, "Directory holding the charts.")
+	cacheDir := flag.String("cache-dir", fetch.DefaultCacheDir(), "Directory to cache Contour source tarballs in, empty to disable caching.")
+	lockfilePath := flag.String("lockfile", crds.DefaultLockfilePath, "Lockfile pinning the checksums of the Contour source tarballs, empty to skip verification.")
+	only := flag.String("only", "", "Comma-separated names of the charts to update, empty for all of them.")
+	contourMinor := flag.String("contour-minor", "", "Contour minor version tracked by the base branch, such as 1.32, or \"current\". Empty tracks the latest Contour.")
+	contourVersion := flag.String("contour-version", "", "Bump to this exact Contour version, such as 1.34.0-rc.1, instead of the latest one.")
+	channelName := flag.String("channel", string(bump.ChannelStable), "Release channel of the latest Contour version: stable or rc.")
+	platformList := flag.String("platforms", bump.DefaultPlatforms, "Comma-separated platforms the new image tags must be published for, empty to skip the check.")
+	pinDigests := flag.Bool("pin-digests", false, "Resolve the new image tags to manifest digests and write them to values.yaml.")
+	versions := flag.String("versions", bump.DefaultVersionsURL, "Location of Contour versions.yaml: an http(s) URL, a local file, or - for stdin.")
+	repository := flag.String("repository", os.Getenv("GITHUB_REPOSITORY"), "GitHub repository to open the pull request in, as owner/name.")
+	apiURL := flag.String("github-api-url", cmp.Or(os.Getenv("GITHUB_API_URL"), updatepr.DefaultGitHubAPIURL), "GitHub REST API endpoint.")
+	flag.Parse()
+
+	channel, err := bump.ParseChannel(*channelName)
+	if err != nil {
+		log.Fatalf("Invalid flags: %v", err)
+	}
+	platforms, err := registry.ParsePlatforms(*platformList)
+	if err != nil {
+		log.Fatalf("Invalid flags: %v", err)
+	}
+	discovered, err := charts.Discover(*chartsDir, charts.ParseNames(*only)...)
+	if err != nil {
+		log.Fatalf("Failed to discover charts: %v", err)
+	}
+	if *realRun && *repository == "" {
+		log.Fatalf("Invalid flags: -repository or $GITHUB_REPOSITORY is required with -real-run")
+	}
+
+	fetch.ConfigureDefaultClient()
+
+	download := crds.Download{
+		LockfilePath: *lockfilePath,
+		Fetcher:      &fetch.Fetcher{CacheDir: *cacheDir},
+	}
+	opts := updatepr.Options{
+		Charts: discovered,
+		Bump: bump.Options{
+			Source:         bump.NewVersionSource(*versions),
+			ContourMinor:   *contourMinor,
+			ContourVersion: *contourVersion,
+			Channel:        channel,
+			PinDigests:     *pinDigests,
+			Platforms:      platforms,
+		},
+		CRDs: crds.Options{
+			Source:     &crds.ArchiveSource{Download: download},
+			GatewayAPI: &crds.GatewayAPIRelease{Download: download},
+		},
+		Git: &updatepr.ExecGit{
+			DryRun: !*realRun,
+			Out:    os.Stdout,
+		},
+		GitHub: &updatepr.GitHubClient{
+			APIURL:     *apiURL,
+			Repository: *repository,
+			Token:      os.Getenv("GITHUB_TOKEN"),
+			DryRun:     !*realRun,
+			Out:        os.Stdout,
+		},
+		Base: *base,
+	}
+
+	if err := updatepr.Run(context.Background(), opts); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
@@ -16,6 +16,7 @@
 package e2e
 
 import (
+	"regexp"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -96,6 +97,187 @@ var _ = Describe("Contour", func() {
 		})
 	})
 
+	// synchronize-crds injects the keep policy, the chart labels and commonAnnotations into the
+	// metadata of every CRD, next to the upstream labels and annotations.
+	Describe("CRD metadata", func() {
+		const httpProxyCRD = "templates/crds/contour/httpproxies.projectcontour.io.yaml"
+
+		It("keeps the CRDs and labels them by default", func() {
+			rendered := HelmTemplate(releaseName, chartPath, "--show-only", httpProxyCRD)
+
+			Expect(rendered).To(MatchRegexp(`(?m)^    helm\.sh/resource-policy: keep$`),
+				"CRDs should survive helm uninstall by default")
+			Expect(rendered).To(MatchRegexp(`(?m)^    controller-gen\.kubebuilder\.io/version: \S+$`),
+				"upstream annotations should be kept")
+			Expect(rendered).To(MatchRegexp(`(?m)^    app\.kubernetes\.io/managed-by: Helm$`),
+				"CRDs should carry the standard chart labels")
+			Expect(rendered).To(MatchRegexp(`(?m)^    app\.kubernetes\.io/instance: `+releaseName+`$`),
+				"CRDs should carry the standard chart labels")
+		})
+
+		It("drops the keep policy when contour.keepCRDs=false", func() {
+			rendered := HelmTemplate(releaseName, chartPath,
+				"--show-only", httpProxyCRD,
+				"--set", "contour.keepCRDs=false",
+			)
+
+			Expect(rendered).NotTo(ContainSubstring("helm.sh/resource-policy"))
+			Expect(rendered).To(MatchRegexp(`(?m)^  annotations:\n    controller-gen\.kubebuilder\.io/version: \S+$`),
+				"upstream annotations should be kept without the keep policy")
+		})
+
+		It("adds commonLabels and commonAnnotations", func() {
+			rendered := HelmTemplate(releaseName, chartPath,
+				"--show-only", httpProxyCRD,
+				"--set", "commonLabels.team=networking",
+				"--set", "commonAnnotations.owner=platform",
+			)
+
+			Expect(rendered).To(MatchRegexp(`(?m)^    team: networking$`))
+			Expect(rendered).To(MatchRegexp(`(?m)^    owner: platform$`))
+		})
+
+		It("keeps the Gateway API CRDs when gatewayAPI.manageCRDs=true", func() {
+			rendered := HelmTemplate(releaseName, chartPath,
+				"--show-only", "templates/crds/gateway-api/standard/gateways.gateway.networking.k8s.io.yaml",
+				"--set", "gatewayAPI.manageCRDs=true",
+				"--set", "gatewayAPI.channel=standard",
+			)
+
+			Expect(rendered).To(MatchRegexp(`(?m)^    helm\.sh/resource-policy: keep$`))
+			Expect(rendered).To(MatchRegexp(`(?m)^    gateway\.networking\.k8s\.io/channel: standard$`),
+				"upstream annotations should be kept")
+			Expect(rendered).To(MatchRegexp(`(?m)^    app\.kubernetes\.io/managed-by: Helm$`))
+		})
+	})
+
+	// Each CRD template is guarded by its manage flag, by the Gateway API channel for the Gateway API CRDs,
+	// and by its per-CRD toggle.
+	Describe("CRD selection", func() {
+		// renderedCRDs returns the CRD templates that a rendered chart contains.
+		renderedCRDs := func(rendered string) []string {
+			var crds []string
+			for _, m := range regexp.MustCompile(`(?m)^# Source: contour/(templates/crds/\S+)$`).FindAllStringSubmatch(rendered, -1) {
+				crds = append(crds, m[1])
+			}
+			return crds
+		}
+
+		contourCRDs := []string{
+			"templates/crds/contour/contourconfigurations.projectcontour.io.yaml",
+			"templates/crds/contour/contourdeployments.projectcontour.io.yaml",
+			"templates/crds/contour/extensionservices.projectcontour.io.yaml",
+			"templates/crds/contour/httpproxies.projectcontour.io.yaml",
+			"templates/crds/contour/tlscertificatedelegations.projectcontour.io.yaml",
+		}
+
+		It("renders all the Contour CRDs by default", func() {
+			crds := renderedCRDs(HelmTemplate(releaseName, chartPath))
+
+			Expect(crds).To(ConsistOf(contourCRDs),
+				"Gateway API CRDs should not be rendered unless gatewayAPI.manageCRDs=true")
+		})
+
+		It("drops a single CRD when contour.crds.<plural>=false", func() {
+			crds := renderedCRDs(HelmTemplate(releaseName, chartPath,
+				"--set", "contour.crds.httpproxies=false",
+			))
+
+			Expect(crds).NotTo(ContainElement("templates/crds/contour/httpproxies.projectcontour.io.yaml"))
+			Expect(crds).To(ConsistOf(
+				"templates/crds/contour/contourconfigurations.projectcontour.io.yaml",
+				"templates/crds/contour/contourdeployments.projectcontour.io.yaml",
+				"templates/crds/contour/extensionservices.projectcontour.io.yaml",
+				"templates/crds/contour/tlscertificatedelegations.projectcontour.io.yaml",
+			), "the other CRDs should still be rendered")
+		})
+
+		It("drops a single Gateway API CRD when gatewayAPI.crds.<plural>=false", func() {
+			crds := renderedCRDs(HelmTemplate(releaseName, chartPath,
+				"--set", "gatewayAPI.manageCRDs=true",
+				"--set", "gatewayAPI.channel=experimental",
+				"--set", "gatewayAPI.crds.udproutes=false",
+			))
+
+			Expect(crds).NotTo(ContainElement(ContainSubstring("udproutes")))
+			Expect(crds).To(ContainElement("templates/crds/gateway-api/experimental/tcproutes.gateway.networking.k8s.io.yaml"))
+		})
+
+		It("renders the standard Gateway API CRDs when gatewayAPI.channel=standard", func() {
+			crds := renderedCRDs(HelmTemplate(releaseName, chartPath,
+				"--set", "gatewayAPI.manageCRDs=true",
+				"--set", "gatewayAPI.channel=standard",
+			))
+
+			Expect(crds).To(ContainElements(
+				"templates/crds/gateway-api/standard/gateways.gateway.networking.k8s.io.yaml",
+				"templates/crds/gateway-api/standard/httproutes.gateway.networking.k8s.io.yaml",
+			))
+			Expect(crds).NotTo(ContainElement(HavePrefix("templates/crds/gateway-api/experimental/")))
+			Expect(crds).NotTo(ContainElement(ContainSubstring("tcproutes")),
+				"TCPRoute is only part of the experimental channel")
+		})
+
+		It("renders the experimental Gateway API CRDs when gatewayAPI.channel=experimental", func() {
+			crds := renderedCRDs(HelmTemplate(releaseName, chartPath,
+				"--set", "gatewayAPI.manageCRDs=true",
+				"--set", "gatewayAPI.channel=experimental",
+			))
+
+			Expect(crds).To(ContainElements(
+				"templates/crds/gateway-api/experimental/gateways.gateway.networking.k8s.io.yaml",
+				"templates/crds/gateway-api/experimental/tcproutes.gateway.networking.k8s.io.yaml",
+				"templates/crds/gateway-api/experimental/udproutes.gateway.networking.k8s.io.yaml",
+			))
+			Expect(crds).NotTo(ContainElement(HavePrefix("templates/crds/gateway-api/standard/")))
+		})
+
+		It("rejects an invalid gatewayAPI.channel", func() {
+			stderr := HelmTemplateError(releaseName, chartPath,
+				"--set", "gatewayAPI.manageCRDs=true",
+				"--set", "gatewayAPI.channel=beta",
+			)
+
+			Expect(stderr).To(ContainSubstring("Invalid gatewayAPI.channel selected"))
+		})
+	})
+
+	// synchronize-rbac keeps the ClusterRole rules in sync with the upstream Contour role.
+	Describe("RBAC rules", func() {
+		It("grants the rules of the upstream Contour ClusterRole", func() {
+			rendered := HelmTemplate(releaseName, chartPath, "--show-only", "templates/contour/rbac.yaml")
+
+			Expect(rendered).To(ContainSubstring("      - backendtlspolicies/status\n"),
+				"Contour updates the status of BackendTLSPolicies")
+			Expect(rendered).To(ContainSubstring("      - endpointslices\n"))
+		})
+	})
+
+	// synchronize-config generates the values schema of configInline from the Contour configuration types.
+	Describe("configInline schema", func() {
+		It("rejects unknown configuration keys", func() {
+			stderr := HelmTemplateError(releaseName, chartPath,
+				"--show-only", "templates/contour/configmap.yaml",
+				"--set", "configInline.accesslog-fromat=json",
+			)
+
+			Expect(stderr).To(ContainSubstring("accesslog-fromat"),
+				"misspelled keys should fail the values schema validation")
+		})
+
+		It("accepts the keys of the Contour configuration", func() {
+			rendered := HelmTemplate(releaseName, chartPath,
+				"--show-only", "templates/contour/configmap.yaml",
+				"--set", "configInline.accesslog-format=json",
+				"--set", "configInline.timeouts.request-timeout=30s",
+				"--set", "configInline.network.num-trusted-hops=1",
+			)
+
+			Expect(rendered).To(MatchRegexp(`(?m)^\s+accesslog-format: json$`))
+			Expect(rendered).To(MatchRegexp(`(?m)^\s+request-timeout: 30s$`))
+		})
+	})
+
 	f.NamespacedTest("test-helm-installation", func(namespace string) {
 		It("should deploy contour using helm", func() {
 			helmRelease := HelmInstall(releaseName, chartPath, namespace, mandatoryInstallArgs...)
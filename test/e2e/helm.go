@@ -82,6 +82,14 @@ func HelmTemplate(releaseName, chartPath string, additionalArgs ...string) strin
 	return stdout.String()
 }
 
+// HelmTemplateError renders a chart with helm template, expects it to fail, and returns its error output.
+func HelmTemplateError(releaseName, chartPath string, additionalArgs ...string) string {
+	cmdArgs := append([]string{"template", releaseName, chartPath}, additionalArgs...)
+	session := runCommand("helm", helmTemplateTimeout, true, nil, cmdArgs...)
+	gomega.Expect(session.ExitCode()).NotTo(gomega.Equal(0), "helm template should fail")
+	return string(session.Err.Contents())
+}
+
 // HelmRepoAdd adds a Helm repository and updates its index.
 func HelmRepoAdd(repoName, repoURL string) {
 	runCommand("helm", helmRepoTimeout, false, nil, "repo", "add", repoName, repoURL)